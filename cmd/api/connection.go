@@ -14,6 +14,7 @@ import (
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	connectionmanager "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/connectionManager"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
 )
 
@@ -73,7 +74,7 @@ func (app *application) GetConnections(context *gin.Context) {
 			}
 		}
 
-		doc, _ := app.mediator.DidResolver.ResolveDidAsJson(x.RemoteDid)
+		doc, _ := mediator.ResolveDidAsJsonContext(context.Request.Context(), app.mediator.DidResolver, x.RemoteDid)
 
 		response := ConnectionResponse{
 			Mediatee: x,
@@ -111,7 +112,7 @@ func (app *application) GetConnection(context *gin.Context) {
 		return
 	}
 
-	doc, _ := app.mediator.DidResolver.ResolveDidAsJson(did)
+	doc, _ := mediator.ResolveDidAsJsonContext(context.Request.Context(), app.mediator.DidResolver, did)
 
 	response := ConnectionResponse{
 		Mediatee: *connection,
@@ -269,6 +270,14 @@ func (app *application) UpdateConnection(context *gin.Context) {
 
 	mediatee.RemoteDid = did
 
+	err = connectionmanager.ValidateMapping(mediatee.Properties, mediatee.DataSchema)
+
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		context.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	err = app.mediator.Database.UpdateMediatee(mediatee)
 	if err != nil {
 		config.Logger.Error(logTag, "Error", err)
@@ -392,6 +401,14 @@ func (app *application) AcceptConnection(context *gin.Context) {
 		return
 	}
 
+	err = connectionmanager.ValidateMapping(inv.Properties, inv.DataSchema)
+
+	if err != nil {
+		_ = app.SendPr(context, protocol.PR_INVALID_REQUEST, err)
+		context.Status(http.StatusBadRequest)
+		return
+	}
+
 	uri, err := url.Parse(inv.Invitation)
 
 	if err != nil {
@@ -417,7 +434,7 @@ func (app *application) AcceptConnection(context *gin.Context) {
 		return
 	}
 
-	msg, err := app.mediator.UnpackMessage(string(b))
+	msg, metadata, err := app.mediator.UnpackMessageWithMetadata(string(b))
 
 	if err != nil {
 		config.Logger.Error(logTag, "Error", err)
@@ -425,6 +442,13 @@ func (app *application) AcceptConnection(context *gin.Context) {
 		return
 	}
 
+	err = protocol.VerifyInvitation(msg, metadata)
+
+	if err != nil {
+		_ = app.SendPr(context, protocol.PR_INVITATION_NOT_TRUSTED, err)
+		return
+	}
+
 	var bodyJson map[string]interface{}
 
 	err = json.Unmarshal([]byte(msg.Body), &bodyJson)
@@ -461,7 +485,7 @@ func (app *application) AcceptConnection(context *gin.Context) {
 		return
 	}
 
-	err = app.mediator.ConnectionManager.StoreConnection(inv.Protocol, *msg.From, inv.Topic, inv.Properties, inv.EventType, []string{peerdid}, inv.Group)
+	err = app.mediator.ConnectionManager.StoreConnection(inv.Protocol, *msg.From, inv.Topic, inv.Properties, inv.EventType, []string{peerdid}, inv.Group, inv.DataSchema)
 
 	if err != nil {
 		config.Logger.Error(logTag, "Error", err)
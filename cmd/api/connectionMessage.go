@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/protocol"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageResponse struct {
+	// id of the DIDComm message, the delivery status events carry it as event id
+	Id string `json:"id"`
+}
+
+// @Summary	Send a message to a connection
+// @Schemes
+// @Description	Packs a DIDComm message for a recipient DID of the connection and delivers it to the service endpoint of the DID or parks it for the pickup. The message is either given as full plaintext message or as type and body. Only served with server.tls.adminClientAuth.
+// @Tags			Connections
+// @Accept			json
+// @Produce		json
+// @Param			did		path	string						true	"DID"
+// @Param			message	body	protocol.OutboundMessage	true	"Message"
+// @Success		202	{object}	MessageResponse
+// @Failure		400	"Bad Request"
+// @Failure		404	"Connection not found"
+// @Failure		409	"Connection is blocked"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/connections/{did}/messages [post]
+func (app *application) SendConnectionMessage(context *gin.Context) {
+	logTag := "/admin/connections/{did}/messages [post]"
+	did := context.Param("did")
+	var outbound protocol.OutboundMessage
+	if err := context.ShouldBindJSON(&outbound); err != nil {
+		context.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := protocol.SendToConnection(context.Request.Context(), app.mediator, did, outbound)
+	if err != nil {
+		switch {
+		case errors.Is(err, protocol.ErrInvalidOutboundMessage):
+			context.String(http.StatusBadRequest, err.Error())
+		case errors.Is(err, protocol.ErrConnectionNotFound):
+			context.String(http.StatusNotFound, err.Error())
+		case errors.Is(err, protocol.ErrConnectionBlocked):
+			context.String(http.StatusConflict, err.Error())
+		default:
+			config.Logger.Error(logTag, "Error", err)
+			context.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+	config.Logger.Info(logTag, "did", did, "id", id)
+	context.JSON(http.StatusAccepted, MessageResponse{Id: id})
+}
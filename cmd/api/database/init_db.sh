@@ -34,7 +34,7 @@ INSERT INTO secret_types(id, description) VALUES (6,'SecretTypeEd25519Verificati
 INSERT INTO secret_types(id, description) VALUES (7,'SecretTypeOther'); "
 
 CREATE_SECRETS_TABLE="CREATE TABLE IF NOT EXISTS ${CASSANDRA_KEYSPACE}.secrets 
-  (id text, type int, key text,added TIMESTAMP , PRIMARY KEY (id)); "
+  (id text, type int, key text, material text,added TIMESTAMP , retired TIMESTAMP, PRIMARY KEY (id)); "
 
 
 CREATE_MESSAGES_TABLE="CREATE TABLE IF NOT EXISTS ${CASSANDRA_KEYSPACE}.messages 
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"errors"
+	"time"
+
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 
 	"github.com/gocql/gocql"
@@ -13,6 +16,9 @@ type Migration struct {
 	session *gocql.Session
 }
 
+// outboxQueueVersion is the migration which queues the outbox events in partitions of their status
+const outboxQueueVersion = 13
+
 func NewMigration() {
 	if config.CurrentConfiguration.Database.InMemory {
 		config.Logger.Info("No migration necessary")
@@ -45,6 +51,11 @@ func (mig *Migration) migrate() {
 		config.Logger.Error("NewCassandra", "Error creating instance:", err)
 		panic("Error creating cassandra instance")
 	}
+	version, _, err := instance.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		config.Logger.Error("NewCassandra", "Error reading version:", err)
+		panic("Error reading cassandra migration version")
+	}
 	err = instance.Up()
 	if err != nil && err != migrate.ErrNoChange {
 		config.Logger.Error("NewCassandra", "Error migrating:", err)
@@ -52,6 +63,37 @@ func (mig *Migration) migrate() {
 	} else {
 		config.Logger.Info("Cassandra migration finished")
 	}
+	if version < outboxQueueVersion {
+		if err = mig.queueOutboxEvents(); err != nil {
+			config.Logger.Error("NewCassandra", "Error queueing outbox events:", err)
+			panic("Error queueing outbox events")
+		}
+	}
+}
+
+// queueOutboxEvents adds the events which were stored before the outbox queue existed to the queue,
+// in the partition of the current minute
+func (mig *Migration) queueOutboxEvents() error {
+	bucket := time.Now().UTC().Truncate(time.Minute)
+	iter := mig.session.Query("SELECT id, status FROM outbox").Iter()
+	var id, status string
+	queued := 0
+	for iter.Scan(&id, &status) {
+		batch := mig.session.NewBatch(gocql.LoggedBatch)
+		batch.Query("UPDATE outbox SET bucket = ? WHERE id = ?", bucket, id)
+		batch.Query("INSERT INTO outbox_queue (status, bucket, id) VALUES (?, ?, ?)", status, bucket, id)
+		batch.Query("INSERT INTO outbox_buckets (status, bucket) VALUES (?, ?)", status, bucket)
+		if err := mig.session.ExecuteBatch(batch); err != nil {
+			iter.Close()
+			return err
+		}
+		queued++
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	config.Logger.Info("Outbox events queued", "count", queued)
+	return nil
 }
 
 func newCassandraSession() (*gocql.Session, error) {
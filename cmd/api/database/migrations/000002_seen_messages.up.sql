@@ -0,0 +1,8 @@
+-- Replay protection, rows expire with the TTL given on insert
+
+CREATE TABLE IF NOT EXISTS seen_messages (
+  sender TEXT,
+  id TEXT,
+  added TIMESTAMP,
+  PRIMARY KEY ((sender, id))
+);
@@ -0,0 +1,4 @@
+-- Format of the key column, multibase for X25519 and Ed25519 keys and jwk for P-256 and secp256k1 keys.
+-- Secrets without material are multibase.
+
+ALTER TABLE secrets ADD material TEXT;
@@ -0,0 +1,3 @@
+-- Retired secrets are no longer published but still used to decrypt and verify
+
+ALTER TABLE secrets ADD retired TIMESTAMP;
@@ -0,0 +1,20 @@
+-- Cloud events are stored before they are published and deleted once the broker accepted them.
+-- The lease keeps other connector instances from publishing the same event at the same time.
+
+CREATE TABLE IF NOT EXISTS outbox (
+  id TEXT,
+  status TEXT,
+  remote_did TEXT,
+  recipient_did TEXT,
+  topic TEXT,
+  event_type TEXT,
+  payload TEXT,
+  attempts INT,
+  last_error TEXT,
+  dead_lettered BOOLEAN,
+  next_attempt TIMESTAMP,
+  lease TIMESTAMP,
+  added TIMESTAMP,
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS outbox_status_idx ON outbox (status);
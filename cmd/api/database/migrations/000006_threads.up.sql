@@ -0,0 +1,12 @@
+-- Threads of cloud requests, replies of the device on a thread are published to the reply topic.
+-- Rows expire with the TTL given on insert.
+
+CREATE TABLE IF NOT EXISTS threads (
+  recipient_did TEXT,
+  thid TEXT,
+  pthid TEXT,
+  reply_to TEXT,
+  PRIMARY KEY ((recipient_did, thid))
+);
+
+ALTER TABLE outbox ADD (thid TEXT, pthid TEXT);
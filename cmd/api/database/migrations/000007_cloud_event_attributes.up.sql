@@ -0,0 +1,5 @@
+-- Attributes of the DIDComm message and the connection which are published with cloud events
+
+ALTER TABLE mediatees ADD data_schema TEXT;
+
+ALTER TABLE outbox ADD (message_id TEXT, message_type TEXT, created TIMESTAMP, group TEXT, data_schema TEXT);
@@ -0,0 +1,5 @@
+-- Delivery status of messages which were forwarded from cloud events
+
+ALTER TABLE messages ADD (event_id TEXT, expires TIMESTAMP);
+
+ALTER TABLE outbox ADD event_id TEXT;
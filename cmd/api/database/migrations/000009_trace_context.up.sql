@@ -0,0 +1,3 @@
+-- W3C trace context of the messages in the outbox, published as distributed tracing extension
+
+ALTER TABLE outbox ADD (traceparent TEXT, tracestate TEXT);
@@ -0,0 +1,3 @@
+-- Messages whose delivered status was published, a message which is picked up again is not reported twice
+
+ALTER TABLE messages ADD delivered BOOLEAN;
@@ -0,0 +1,8 @@
+-- Lookup of the secret ids of a DID, so that the keys of a DID are listed without reading all secrets.
+-- Secrets which were stored before are added by the connector at its start.
+
+CREATE TABLE IF NOT EXISTS secrets_by_did (
+  did TEXT,
+  id TEXT,
+  PRIMARY KEY (did, id)
+);
@@ -0,0 +1,3 @@
+-- Dead events whose cloud event could not be created keep the DIDComm message as payload, they can not be replayed
+
+ALTER TABLE outbox ADD unmapped BOOLEAN;
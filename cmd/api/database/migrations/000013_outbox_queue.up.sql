@@ -0,0 +1,22 @@
+-- The outbox was polled through an index on its status, which collected the tombstones of all published events.
+-- Events are queued in partitions of their status and the minute in which they entered it instead. The partitions of
+-- a status are listed in outbox_buckets, empty partitions are dropped from the list and not read again.
+-- Queue entries which outlive their event are skipped, so the tombstones of both tables are purged after an hour.
+-- The events which are stored at the time of the migration are queued by the connector after the migration.
+
+CREATE TABLE IF NOT EXISTS outbox_queue (
+  status TEXT,
+  bucket TIMESTAMP,
+  id TEXT,
+  PRIMARY KEY ((status, bucket), id)
+) WITH gc_grace_seconds = 3600;
+
+CREATE TABLE IF NOT EXISTS outbox_buckets (
+  status TEXT,
+  bucket TIMESTAMP,
+  PRIMARY KEY (status, bucket)
+) WITH gc_grace_seconds = 3600;
+
+ALTER TABLE outbox ADD bucket TIMESTAMP;
+
+DROP INDEX IF EXISTS outbox_status_idx;
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary		DID document of the mediator
+// @Schemes
+// @Description	Returns the DID document if the mediator runs as did:web
+// @Tags			DID
+// @Produce		json
+// @Success		200	"OK"
+// @Failure		404	"Not Found"
+// @Router			/.well-known/did.json [get]
+func (app *application) DidDocument(context *gin.Context) {
+	doc := app.mediator.Document()
+	if doc == nil {
+		context.Status(http.StatusNotFound)
+		return
+	}
+	context.Header("Content-Type", "application/did+json")
+	context.JSON(http.StatusOK, doc)
+}
@@ -1,12 +1,91 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/health"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/protocol"
+	"github.com/google/uuid"
 
 	"github.com/gin-gonic/gin"
 )
 
-func (app *application) HealthCheck(context *gin.Context) {
+// @Summary	Liveness of the connector
+// @Schemes
+// @Description	Checks that the didcomm library packs messages. A failed liveness check means the process has to be restarted.
+// @Tags			Health
+// @Produce		json
+// @Success		200	{object}	health.Report
+// @Failure		503	{object}	health.Report
+// @Router			/health/live [get]
+func (app *application) HealthLive(context *gin.Context) {
+	app.healthReport(context, []health.Check{app.didcommCheck()})
+}
+
+// @Summary	Readiness of the connector
+// @Schemes
+// @Description	Checks the dependencies which are needed to process messages: database, didcomm library, subscription of the inbound cloud events and the Universal Resolvers. The connector is degraded but ready if only a resolver fails since the resolver chain falls back to the next resolver.
+// @Tags			Health
+// @Produce		json
+// @Success		200	{object}	health.Report
+// @Failure		503	{object}	health.Report
+// @Router			/health/ready [get]
+func (app *application) HealthReady(context *gin.Context) {
+	checks := []health.Check{
+		{Name: "database", Critical: true, Run: app.mediator.Database.Ping},
+		app.didcommCheck(),
+		{Name: "resolver", Run: checkResolvers},
+	}
+	if receivesCloudEvents() {
+		checks = append(checks, health.Check{Name: "messaging", Critical: true, Run: checkReceiving})
+	}
+	app.healthReport(context, checks)
+}
+
+func (app *application) healthReport(context *gin.Context, checks []health.Check) {
+	logTag := "Health"
+	timeout := time.Duration(config.CurrentConfiguration.Health.Timeout) * time.Second
+	report := health.Run(context.Request.Context(), timeout, checks)
+	if !report.Up() {
+		config.Logger.Warn(logTag, "path", context.FullPath(), "report", report)
+		context.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+// didcommCheck packs a plaintext message with the didcomm library
+func (app *application) didcommCheck() health.Check {
+	return health.Check{Name: "didcomm", Critical: true, Run: func(context.Context) error {
+		_, err := app.mediator.PackPlainMessage(didcomm.Message{
+			Id:   uuid.NewString(),
+			Type: "https://didcomm.org/trust-ping/2.0/ping",
+			Body: "{}",
+		})
+		return err
+	}}
+}
+
+func checkReceiving(context.Context) error {
+	return protocol.CheckReceiving()
+}
 
-	context.Status(http.StatusOK)
+// checkResolvers fails if a Universal Resolver of the chain is not available
+func checkResolvers(ctx context.Context) error {
+	var errs []error
+	for _, resolver := range config.DidResolvers() {
+		if resolver.Type != config.RESOLVER_UNIVERSAL {
+			continue
+		}
+		if err := config.CheckResolver(ctx, resolver.Url); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", resolver.Url, err))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary	Get keys
+// @Schemes
+// @Description	Returns the public parts of the stored secrets, optionally of a single DID
+// @Tags			Keys
+// @Produce		json
+// @Param			did	query string	false	"DID"
+// @Success		200	{array}	mediator.Key
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/keys [get]
+func (app *application) GetKeys(context *gin.Context) {
+	logTag := "/admin/keys [get]"
+	keys, err := app.mediator.Keys(context.Query("did"))
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+		return
+	}
+	context.JSON(http.StatusOK, keys)
+}
+
+// @Summary	Generate keys
+// @Schemes
+// @Description	Adds a key agreement and an authentication key to the did:web of the mediator. The new keys are used for signing, the former keys stay valid.
+// @Tags			Keys
+// @Produce		json
+// @Success		201	{array}	mediator.Key
+// @Failure		409	"Mediator does not run as did:web"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/keys [post]
+func (app *application) GenerateKeys(context *gin.Context) {
+	logTag := "/admin/keys [post]"
+	keys, err := app.mediator.GenerateKeys()
+	if err != nil {
+		keyError(context, logTag, err)
+		return
+	}
+	config.Logger.Info(logTag, "Generated", len(keys))
+	context.JSON(http.StatusCreated, keys)
+}
+
+// @Summary	Rotate keys
+// @Schemes
+// @Description	Generates new keys for the did:web of the mediator and retires all former keys
+// @Tags			Keys
+// @Produce		json
+// @Success		200	{array}	mediator.Key
+// @Failure		409	"Mediator does not run as did:web"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/keys/rotate [post]
+func (app *application) RotateKeys(context *gin.Context) {
+	logTag := "/admin/keys/rotate [post]"
+	keys, err := app.mediator.RotateKeys()
+	if err != nil {
+		keyError(context, logTag, err)
+		return
+	}
+	config.Logger.Info(logTag, "Rotated", true)
+	context.JSON(http.StatusOK, keys)
+}
+
+// @Summary	Retire a key
+// @Schemes
+// @Description	Removes a key from the DID document of the mediator. The secret is kept to decrypt older messages and to verify tokens until they expire.
+// @Tags			Keys
+// @Param			kid	path string	true	"Key ID, the # has to be encoded as %23"
+// @Success		200	"OK"
+// @Failure		404	"Key not found"
+// @Failure		409	"Key is not a key of the did:web mediator or the last active key of its purpose"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/keys/retire/{kid} [post]
+func (app *application) RetireKey(context *gin.Context) {
+	logTag := "/admin/keys/retire/{kid} [post]"
+	kid := context.Param("kid")
+	if err := app.mediator.RetireKey(kid); err != nil {
+		keyError(context, logTag, err)
+		return
+	}
+	config.Logger.Info(logTag, "kid", kid, "Retired", true)
+	context.Status(http.StatusOK)
+}
+
+// @Summary	JSON Web Key Set
+// @Schemes
+// @Description	Returns the public keys which sign the invitation tokens of the mediator, tokens name their key in the kid header
+// @Tags			Keys
+// @Produce		json
+// @Success		200	{object}	mediator.Jwks
+// @Failure		500	"Internal Server Error"
+// @Router			/.well-known/jwks.json [get]
+func (app *application) Jwks(context *gin.Context) {
+	logTag := "/.well-known/jwks.json [get]"
+	jwks, err := app.mediator.Jwks()
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+		return
+	}
+	context.JSON(http.StatusOK, jwks)
+}
+
+func keyError(context *gin.Context, logTag string, err error) {
+	switch {
+	case errors.Is(err, mediator.ErrKeyNotFound):
+		context.String(http.StatusNotFound, err.Error())
+	case errors.Is(err, mediator.ErrKeysNotChangeable), errors.Is(err, mediator.ErrLastKey):
+		context.String(http.StatusConflict, err.Error())
+	default:
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+	}
+}
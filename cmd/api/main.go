@@ -11,8 +11,12 @@ import (
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/cmd/api/database"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/protocol"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 //	@title			DIDComm Connector API
@@ -71,24 +75,80 @@ func main() {
 		panic(err)
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
 	database.NewMigration()
 	app := application{
 		mediator: mediator.NewMediator(config.Logger),
 	}
 
-	if config.IsForwardTypeNats() || config.IsForwardTypeHybrid() {
-		// subscribe to nats
-		go protocol.ReceiveMessage(app.mediator)
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	var metricsSrv *http.Server
+	if metricsConfig := config.CurrentConfiguration.Metrics; metricsConfig.Enabled {
+		collector := metrics.NewDatabaseCollector(app.mediator.Database, config.CurrentConfiguration.Metrics.TopRecipients)
+		prometheus.MustRegister(collector)
+		go collector.Run(metricsCtx, time.Duration(metricsConfig.RefreshInterval)*time.Second)
+		metricsSrv = &http.Server{
+			Addr:              ":" + fmt.Sprint(metricsConfig.Port),
+			Handler:           metrics.Handler(),
+			ReadHeaderTimeout: time.Duration(config.CurrentConfiguration.Server.ReadHeaderTimeout) * time.Second,
+		}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil {
+				config.Logger.Error("Metrics ListenAndServe", "Error", err)
+			}
+		}()
+	}
+
+	go protocol.PublishOutbox(app.mediator)
+
+	keysCtx, stopKeys := context.WithCancel(context.Background())
+	if interval := config.CurrentConfiguration.DidComm.KeyReloadInterval; interval > 0 {
+		go app.mediator.WatchKeys(keysCtx, time.Duration(interval)*time.Second)
+	}
+
+	receiveCtx, stopReceiving := context.WithCancel(context.Background())
+	receiving := make(chan struct{})
+	if receivesCloudEvents() {
+		// subscribe to nats, kafka or mqtt
+		go func() {
+			protocol.ReceiveMessage(receiveCtx, app.mediator)
+			close(receiving)
+		}()
+	} else {
+		close(receiving)
 	}
 
 	router := app.NewRouter()
+	serverConfig := config.CurrentConfiguration.Server
 	srv := &http.Server{
-		Addr:    ":" + fmt.Sprint(config.CurrentConfiguration.Port),
-		Handler: router,
+		Addr:              ":" + fmt.Sprint(config.CurrentConfiguration.Port),
+		Handler:           router,
+		ReadTimeout:       time.Duration(serverConfig.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(serverConfig.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(serverConfig.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(serverConfig.IdleTimeout) * time.Second,
+	}
+	if serverConfig.Tls.Enabled {
+		tlsConfig, err := transport.NewServerTLSConfig(serverConfig.Tls.CertFile, serverConfig.Tls.KeyFile, serverConfig.Tls.ClientCaFile)
+		if err != nil {
+			panic(err)
+		}
+		srv.TLSConfig = tlsConfig
 	}
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			config.Logger.Error("ListenAndServe", "Error", err)
+		if srv.TLSConfig != nil {
+			// certificates are provided by the TLS config
+			if err := srv.ListenAndServeTLS("", ""); err != nil {
+				config.Logger.Error("ListenAndServeTLS", "Error", err)
+			}
+		} else {
+			if err := srv.ListenAndServe(); err != nil {
+				config.Logger.Error("ListenAndServe", "Error", err)
+			}
 		}
 	}()
 
@@ -98,7 +158,7 @@ func main() {
 	config.Logger.Info("Server Started")
 	<-quit
 	config.Logger.Info("Shutting down server in 5 seconds...")
-	app.mediator.Database.Close()
+	// the running requests are finished first, they still need the database and the outbox
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
@@ -110,6 +170,28 @@ func main() {
 	default:
 		config.Logger.Info("Server shutdown completed")
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			config.Logger.Error("Metrics Server Shutdown:", "msg", err)
+		}
+	}
+	stopMetrics()
+	// the event which is being forwarded is acknowledged before the mediator is closed
+	stopReceiving()
+	<-receiving
+	stopKeys()
+	// stops the outbox, then closes the publishers and the database
+	if err := app.mediator.Close(); err != nil {
+		config.Logger.Error("Mediator Close:", "msg", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		config.Logger.Error("Tracing Shutdown:", "msg", err)
+	}
 	config.Logger.Info("Server exiting")
 	config.CurrentConfiguration.LoggerFile.Close()
 }
+
+// receivesCloudEvents is true if the inbound messages of the cloud are consumed
+func receivesCloudEvents() bool {
+	return config.IsForwardTypeNats() || config.IsForwardTypeKafka() || config.IsForwardTypeMqtt() || config.IsForwardTypeHybrid()
+}
@@ -30,28 +30,46 @@ import (
 // @Param			message	body		didcomm.Message	true	"Message"
 // @Success		200	"OK"
 // @Failure		400	"Bad Request"
+// @Failure		413	"Request Entity Too Large"
 // @Failure		500	"Internal Server Error"
 // @Router			/message/receive  [post]
 func (app *application) ReceiveMessage(context *gin.Context) {
 	bearer := context.Request.Header.Get("Authorization")
-	// get body of request
-	bodyBytes, err := io.ReadAll(context.Request.Body)
+	// get body of request, stop reading as soon as the envelope size limit is exceeded
+	body := context.Request.Body
+	if limit := config.CurrentConfiguration.DidComm.Limits.MaxEnvelopeSize; limit > 0 {
+		body = http.MaxBytesReader(context.Writer, body, limit)
+	}
+	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			config.Logger.Warn("Received message exceeds envelope size limit", "limit", maxBytesErr.Limit)
+			packMsg, err := protocol.PackProblemReport(protocol.PR_MESSAGE_TOO_LARGE, app.mediator)
+			if err != nil {
+				context.Status(http.StatusRequestEntityTooLarge)
+				return
+			}
+			context.Data(http.StatusRequestEntityTooLarge, "application/json", []byte(packMsg))
+			return
+		}
 		context.String(http.StatusBadRequest, "Error reading request body")
 		return
 	}
 	bodyString := string(bodyBytes)
 
 	// handle message
-	packMsg, err := protocol.HandleMessage(bodyString, app.mediator, bearer)
+	packMsg, err := protocol.HandleMessage(context.Request.Context(), bodyString, app.mediator, bearer)
 	if err != nil {
-		if errors.Is(err, intErr.ErrUnpackingMessage) {
+		switch {
+		case errors.Is(err, intErr.ErrUnpackingMessage):
 			context.Status(http.StatusBadRequest)
-			return
-		} else {
+		case errors.Is(err, intErr.ErrMessageTooLarge):
+			context.Data(http.StatusRequestEntityTooLarge, "application/json", []byte(packMsg))
+		default:
 			context.Status(http.StatusInternalServerError)
-			return
 		}
+		return
 	}
 
 	// answer request
@@ -107,7 +125,7 @@ func (app *application) InvitationMessage(context *gin.Context) {
 		return
 	}
 
-	err = app.mediator.ConnectionManager.StoreConnection(mediateeBase.Protocol, mediateeBase.RemoteDid, mediateeBase.Topic, mediateeBase.Properties, mediateeBase.EventType, []string{}, mediateeBase.Group)
+	err = app.mediator.ConnectionManager.StoreConnection(mediateeBase.Protocol, mediateeBase.RemoteDid, mediateeBase.Topic, mediateeBase.Properties, mediateeBase.EventType, []string{}, mediateeBase.Group, mediateeBase.DataSchema)
 	if err != nil {
 		if err == connectionmanager.ERROR_PROTOCOL_NOT_SUPPORTED {
 			_ = app.SendPr(context, protocol.PR_PROTOCOL_NOT_SUPPORTED, err)
@@ -118,6 +136,9 @@ func (app *application) InvitationMessage(context *gin.Context) {
 		} else if err == connectionmanager.ERROR_CONNECTION_ALREADY_EXISTS {
 			_ = app.SendPr(context, protocol.PR_ALREADY_CONNECTED, err)
 			context.Status(http.StatusInternalServerError)
+		} else if errors.Is(err, connectionmanager.ERROR_INVALID_PROPERTIES) {
+			_ = app.SendPr(context, protocol.PR_INVALID_REQUEST, err)
+			context.Status(http.StatusBadRequest)
 		} else {
 			_ = app.SendPr(context, protocol.PR_INTERNAL_SERVER_ERROR, err)
 			context.Status(http.StatusInternalServerError)
@@ -130,6 +151,7 @@ func (app *application) InvitationMessage(context *gin.Context) {
 
 	payload := jwt.MapClaims{
 		"exp":          time.Now().Add(time.Minute * time.Duration(config.CurrentConfiguration.TokenExpiration)).Unix(),
+		"iss":          m.Did,
 		"invitationId": mediateeBase.RemoteDid,
 	}
 
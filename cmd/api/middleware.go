@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireClientCertificate rejects requests without a client certificate that was verified against server.tls.clientCaFile
+func (app *application) RequireClientCertificate() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if context.Request.TLS == nil || len(context.Request.TLS.VerifiedChains) == 0 {
+			config.Logger.Warn("Rejected admin request without verified client certificate", "path", context.Request.URL.Path)
+			context.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		context.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary	Get outbox events
+// @Schemes
+// @Description	Returns the cloud events of the outbox which are waiting to be published or dead, in the order they were added
+// @Tags			Outbox
+// @Produce		json
+// @Param			status	query string	false	"pending or dead (default)"
+// @Success		200	{array}	database.OutboxEvent
+// @Failure		400	"Unknown status"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/outbox [get]
+func (app *application) GetOutboxEvents(context *gin.Context) {
+	logTag := "/admin/outbox [get]"
+	status := context.DefaultQuery("status", database.OUTBOX_STATUS_DEAD)
+	if status != database.OUTBOX_STATUS_DEAD && status != database.OUTBOX_STATUS_PENDING {
+		context.String(http.StatusBadRequest, "status must be pending or dead")
+		return
+	}
+	events, err := app.mediator.Outbox.Events(status)
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+		return
+	}
+	context.JSON(http.StatusOK, events)
+}
+
+// @Summary	Replay a dead event
+// @Schemes
+// @Description	Publishes a dead cloud event again to its topic with a fresh number of attempts
+// @Tags			Outbox
+// @Produce		json
+// @Param			id	path string	true	"Event ID"
+// @Success		200	{object}	database.OutboxEvent
+// @Failure		404	"Event not found"
+// @Failure		409	"Event is not dead or its cloud event could not be created"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/outbox/{id}/replay [post]
+func (app *application) ReplayOutboxEvent(context *gin.Context) {
+	logTag := "/admin/outbox/{id}/replay [post]"
+	id := context.Param("id")
+	event, err := app.mediator.Outbox.Replay(id)
+	if err != nil {
+		outboxError(context, logTag, err)
+		return
+	}
+	config.Logger.Info(logTag, "id", id, "Replayed", true)
+	context.JSON(http.StatusOK, event)
+}
+
+// @Summary	Replay all dead events
+// @Schemes
+// @Description	Publishes all dead cloud events again to their topics, events whose cloud event could not be created are skipped
+// @Tags			Outbox
+// @Produce		json
+// @Success		200	{array}	database.OutboxEvent
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/outbox/replay [post]
+func (app *application) ReplayOutboxEvents(context *gin.Context) {
+	logTag := "/admin/outbox/replay [post]"
+	events, err := app.mediator.Outbox.Events(database.OUTBOX_STATUS_DEAD)
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+		return
+	}
+	replayed := []database.OutboxEvent{}
+	for _, event := range events {
+		event, err := app.mediator.Outbox.Replay(event.Id)
+		// a concurrent replay or delete is no error
+		if errors.Is(err, outbox.ErrEventNotFound) || errors.Is(err, outbox.ErrEventNotDead) || errors.Is(err, outbox.ErrEventNotReplayable) {
+			continue
+		}
+		if err != nil {
+			config.Logger.Error(logTag, "Error", err)
+			context.Status(http.StatusInternalServerError)
+			return
+		}
+		replayed = append(replayed, event)
+	}
+	config.Logger.Info(logTag, "Replayed", len(replayed))
+	context.JSON(http.StatusOK, replayed)
+}
+
+// @Summary	Delete an outbox event
+// @Schemes
+// @Description	Removes a cloud event from the outbox without publishing it
+// @Tags			Outbox
+// @Param			id	path string	true	"Event ID"
+// @Success		200	"OK"
+// @Failure		404	"Event not found"
+// @Failure		500	"Internal Server Error"
+// @Router			/admin/outbox/{id} [delete]
+func (app *application) DeleteOutboxEvent(context *gin.Context) {
+	logTag := "/admin/outbox/{id} [delete]"
+	id := context.Param("id")
+	if err := app.mediator.Outbox.Delete(id); err != nil {
+		outboxError(context, logTag, err)
+		return
+	}
+	config.Logger.Info(logTag, "id", id, "Deleted", true)
+	context.Status(http.StatusOK)
+}
+
+func outboxError(context *gin.Context, logTag string, err error) {
+	switch {
+	case errors.Is(err, outbox.ErrEventNotFound):
+		context.String(http.StatusNotFound, err.Error())
+	case errors.Is(err, outbox.ErrEventNotDead), errors.Is(err, outbox.ErrEventNotReplayable):
+		context.String(http.StatusConflict, err.Error())
+	default:
+		config.Logger.Error(logTag, "Error", err)
+		context.Status(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary	Resolver cache statistics
+// @Schemes
+// @Description	Returns the number of entries, hits, misses and evictions of the DID resolver cache
+// @Tags			Resolver
+// @Produce		json
+// @Success		200	{object}	mediator.CacheStats
+// @Failure		404	"Cache is disabled"
+// @Router			/admin/resolver/cache [get]
+func (app *application) GetResolverCache(context *gin.Context) {
+	if app.mediator.ResolverCache == nil {
+		context.Status(http.StatusNotFound)
+		return
+	}
+	context.JSON(http.StatusOK, app.mediator.ResolverCache.Stats())
+}
+
+// @Summary	Flush the resolver cache
+// @Schemes
+// @Description	Removes all entries of the DID resolver cache
+// @Tags			Resolver
+// @Success		200	"OK"
+// @Failure		404	"Cache is disabled"
+// @Router			/admin/resolver/cache [delete]
+func (app *application) FlushResolverCache(context *gin.Context) {
+	logTag := "/admin/resolver/cache [delete]"
+	if app.mediator.ResolverCache == nil {
+		context.Status(http.StatusNotFound)
+		return
+	}
+	app.mediator.ResolverCache.Flush()
+	config.Logger.Info(logTag, "Flushed", true)
+	context.Status(http.StatusOK)
+}
+
+// @Summary	Flush a DID from the resolver cache
+// @Schemes
+// @Description	Removes the entry of a DID from the DID resolver cache, the next resolution fetches the document again
+// @Tags			Resolver
+// @Param			did	path string	true	"DID"
+// @Success		200	"OK"
+// @Failure		404	"Cache is disabled or DID is not cached"
+// @Router			/admin/resolver/cache/{did} [delete]
+func (app *application) FlushResolverCacheDid(context *gin.Context) {
+	logTag := "/admin/resolver/cache/{did} [delete]"
+	did := context.Param("did")
+	if app.mediator.ResolverCache == nil || !app.mediator.ResolverCache.FlushDid(did) {
+		context.Status(http.StatusNotFound)
+		return
+	}
+	config.Logger.Info(logTag, "did", did, "Flushed", true)
+	context.Status(http.StatusOK)
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
 
 	"github.com/gin-gonic/gin"
 	sloggin "github.com/samber/slog-gin"
@@ -17,14 +20,27 @@ func (app *application) NewRouter() *gin.Engine {
 
 	router := gin.New()
 	router.Use(sloggin.New(config.Logger))
+	router.Use(tracing.Middleware())
+	if config.CurrentConfiguration.Metrics.Enabled {
+		router.Use(metrics.Middleware())
+	}
 
 	// Connections (Mediatees)
 	adminGroup := router.Group("admin")
+	if config.CurrentConfiguration.Server.Tls.AdminClientAuth {
+		adminGroup.Use(app.RequireClientCertificate())
+	}
 	connectionsGroup := adminGroup.Group("connections")
 	connectionsGroup.GET("", app.GetConnections)
 	connectionsGroup.GET(":did", app.GetConnection)
 	connectionsGroup.PUT(":did", app.UpdateConnection)
 	connectionsGroup.DELETE(":did", app.DeleteConnection)
+	// sends messages in the name of the mediator, only served to clients with a verified certificate
+	if config.CurrentConfiguration.Server.Tls.AdminClientAuth {
+		connectionsGroup.POST(":did/messages", app.SendConnectionMessage)
+	} else {
+		config.Logger.Warn("Sending messages to connections is disabled, it requires server.tls.adminClientAuth")
+	}
 	// Block Connections (Mediatees)
 	connectionsGroup.POST("block/:did", app.BlockConnection)
 	connectionsGroup.POST("unblock/:did", app.UnblockConnection)
@@ -33,12 +49,46 @@ func (app *application) NewRouter() *gin.Engine {
 
 	adminGroup.POST("invitation", app.InvitationMessage)
 
+	// DID resolver cache
+	resolverGroup := adminGroup.Group("resolver")
+	resolverGroup.GET("cache", app.GetResolverCache)
+	resolverGroup.DELETE("cache", app.FlushResolverCache)
+	resolverGroup.DELETE("cache/:did", app.FlushResolverCacheDid)
+
+	// keys of the secrets resolver
+	keysGroup := adminGroup.Group("keys")
+	keysGroup.GET("", app.GetKeys)
+	keysGroup.POST("", app.GenerateKeys)
+	keysGroup.POST("rotate", app.RotateKeys)
+	keysGroup.POST("retire/:kid", app.RetireKey)
+
+	// outbox of cloud events
+	outboxGroup := adminGroup.Group("outbox")
+	outboxGroup.GET("", app.GetOutboxEvents)
+	outboxGroup.POST("replay", app.ReplayOutboxEvents)
+	outboxGroup.POST(":id/replay", app.ReplayOutboxEvent)
+	outboxGroup.DELETE(":id", app.DeleteOutboxEvent)
+
 	// messages
 	messagesGroup := router.Group("message")
 	messagesGroup.POST("receive", app.ReceiveMessage)
 
-	// healthcheck
-	router.GET("health", app.HealthCheck)
+	// DID document of a did:web mediator
+	router.GET(".well-known/did.json", app.DidDocument)
+	if app.mediator.Document() != nil {
+		path, err := mediator.WebDidDocumentPath(app.mediator.Did)
+		if err == nil && path != "/.well-known/did.json" {
+			router.GET(path, app.DidDocument)
+		}
+	}
+
+	// signing keys of invitation tokens
+	router.GET(".well-known/jwks.json", app.Jwks)
+
+	// healthcheck, /health is kept for the liveness
+	router.GET("health", app.HealthLive)
+	router.GET("health/live", app.HealthLive)
+	router.GET("health/ready", app.HealthReady)
 
 	// swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,94 @@
+// Package cloudevent creates the cloud event clients of the configured messaging protocol
+package cloudevent
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cloudeventprovider "github.com/eclipse-xfsc/cloud-event-provider"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/kafka"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/mqtt"
+)
+
+// Client is implemented by the clients of the cloud event provider, Kafka and MQTT
+type Client interface {
+	Pub(event event.Event) error
+	Sub(fn func(event event.Event)) error
+	Close() error
+}
+
+// NewClient connects a publisher or subscriber of the topic
+func NewClient(connectionType cloudeventprovider.ConnectionType, topic string) (Client, error) {
+	switch {
+	case config.IsForwardTypeKafka():
+		opts := kafkaOptions()
+		if connectionType == cloudeventprovider.Sub {
+			return kafka.NewSubscriber(opts, topic)
+		}
+		return kafka.NewPublisher(opts, topic)
+	case config.IsForwardTypeMqtt():
+		opts := mqttOptions()
+		if connectionType == cloudeventprovider.Sub {
+			return mqtt.NewSubscriber(opts, topic)
+		}
+		return mqtt.NewPublisher(opts, topic)
+	}
+	return cloudeventprovider.New(cloudeventprovider.Config{
+		Protocol: cloudeventprovider.ProtocolTypeNats,
+		Settings: cloudeventprovider.NatsConfig{
+			Url:        config.CurrentConfiguration.CloudForwarding.Nats.Url,
+			QueueGroup: config.CurrentConfiguration.CloudForwarding.Nats.QueueGroup,
+		},
+	}, connectionType, topic)
+}
+
+// Source is the source attribute of the published cloud events
+func Source() string {
+	switch {
+	case config.IsForwardTypeKafka():
+		return "kafka://" + strings.Join(config.CurrentConfiguration.CloudForwarding.Kafka.Brokers, ",")
+	case config.IsForwardTypeMqtt():
+		return config.CurrentConfiguration.CloudForwarding.Mqtt.Url
+	}
+	return config.CurrentConfiguration.CloudForwarding.Nats.Url
+}
+
+func kafkaOptions() kafka.Options {
+	kafkaConfig := config.CurrentConfiguration.CloudForwarding.Kafka
+	return kafka.Options{
+		Brokers:       kafkaConfig.Brokers,
+		GroupId:       kafkaConfig.GroupId,
+		ClientId:      kafkaConfig.ClientId,
+		Version:       kafkaConfig.Version,
+		SaslMechanism: kafkaConfig.Sasl.Mechanism,
+		SaslUser:      kafkaConfig.Sasl.User,
+		SaslPassword:  kafkaConfig.Sasl.Password,
+		Tls:           kafkaConfig.Tls.Enabled,
+		CaFile:        kafkaConfig.Tls.CaFile,
+		CertFile:      kafkaConfig.Tls.CertFile,
+		KeyFile:       kafkaConfig.Tls.KeyFile,
+	}
+}
+
+func mqttOptions() mqtt.Options {
+	mqttConfig := config.CurrentConfiguration.CloudForwarding.Mqtt
+	return mqtt.Options{
+		Url:            mqttConfig.Url,
+		ClientId:       mqttConfig.ClientId,
+		SharedGroup:    mqttConfig.SharedGroup,
+		Qos:            byte(mqttConfig.Qos),
+		Retain:         mqttConfig.Retain,
+		RetainHandling: byte(mqttConfig.RetainHandling),
+		KeepAlive:      time.Duration(mqttConfig.KeepAlive) * time.Second,
+		ReconnectDelay: time.Duration(mqttConfig.ReconnectDelay) * time.Second,
+		ConnectTimeout: time.Duration(mqttConfig.ConnectTimeout) * time.Second,
+		User:           mqttConfig.User,
+		Password:       mqttConfig.Password,
+		CaFile:         mqttConfig.Tls.CaFile,
+		CertFile:       mqttConfig.Tls.CertFile,
+		KeyFile:        mqttConfig.Tls.KeyFile,
+		Logger:         config.Logger,
+	}
+}
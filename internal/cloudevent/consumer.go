@@ -0,0 +1,153 @@
+package cloudevent
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/jetstream"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/kafka"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/mqtt"
+)
+
+// ErrInvalidEvent is returned by a handler for events which can never be handled
+var ErrInvalidEvent = jetstream.ErrInvalidEvent
+
+// Handler handles an inbound event, last is set if the event is not delivered again when the handler fails
+type Handler = jetstream.Handler
+
+// Consumer handles the inbound events of a topic until it is closed
+type Consumer interface {
+	Consume(handler Handler) error
+	// Connected reports whether the consumer is subscribed at the moment
+	Connected() bool
+	Close() error
+}
+
+// NewConsumer creates a JetStream consumer if it is enabled for NATS, events of a failed handler are delivered again.
+// The other protocols subscribe with a client and only log failed events.
+func NewConsumer(topic string) Consumer {
+	if config.IsForwardTypeNats() && config.CurrentConfiguration.CloudForwarding.Nats.JetStream.Enabled {
+		return &jetStreamConsumer{jetstream.NewConsumer(jetStreamOptions(), topic)}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subscriber{topic: topic, ctx: ctx, cancel: cancel}
+}
+
+type jetStreamConsumer struct {
+	consumer *jetstream.Consumer
+}
+
+func (c *jetStreamConsumer) Consume(handler Handler) error {
+	return c.consumer.Consume(handler)
+}
+
+func (c *jetStreamConsumer) Connected() bool {
+	return c.consumer.Connected()
+}
+
+func (c *jetStreamConsumer) Close() error {
+	return c.consumer.Close()
+}
+
+// subscriber subscribes again with a growing delay when the client fails
+type subscriber struct {
+	topic  string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// client which is subscribed at the moment
+	client atomic.Pointer[subscriberClient]
+}
+
+// subscriberClient reports the state of its connection, it reconnects on its own
+type subscriberClient interface {
+	Client
+	Connected() bool
+}
+
+func newSubscriberClient(topic string) (subscriberClient, error) {
+	switch {
+	case config.IsForwardTypeKafka():
+		return kafka.NewSubscriber(kafkaOptions(), topic)
+	case config.IsForwardTypeMqtt():
+		return mqtt.NewSubscriber(mqttOptions(), topic)
+	}
+	return newNatsSubscriber(topic)
+}
+
+func (s *subscriber) Consume(handler Handler) error {
+	inbound := config.CurrentConfiguration.CloudForwarding.Inbound
+	attempt := 0
+	for {
+		err := s.subscribe(handler, func() { attempt = 0 })
+		if s.ctx.Err() != nil {
+			return nil
+		}
+		delay := jetstream.Backoff(attempt, time.Duration(inbound.ReconnectDelay)*time.Second, time.Duration(inbound.MaxReconnectDelay)*time.Second)
+		attempt++
+		config.Logger.Warn("Subscription of cloud events failed, subscribing again", "topic", s.topic, "delay", delay, "err", err)
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *subscriber) subscribe(handler Handler, connected func()) error {
+	client, err := newSubscriberClient(s.topic)
+	if err != nil {
+		return err
+	}
+	connected()
+	s.client.Store(&client)
+	defer s.client.Store(nil)
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- client.Sub(func(event event.Event) {
+			// failed events are only logged, every delivery is the last
+			if err := handler(event, true); err != nil {
+				config.Logger.Error("Unable to handle cloud event", "id", event.ID(), "err", err)
+			}
+		})
+	}()
+	select {
+	case <-s.ctx.Done():
+		client.Close()
+		return nil
+	case err = <-subscribed:
+		client.Close()
+		return err
+	}
+}
+
+func (s *subscriber) Connected() bool {
+	client := s.client.Load()
+	return client != nil && (*client).Connected()
+}
+
+func (s *subscriber) Close() error {
+	s.cancel()
+	return nil
+}
+
+func jetStreamOptions() jetstream.Options {
+	natsConfig := config.CurrentConfiguration.CloudForwarding.Nats
+	inbound := config.CurrentConfiguration.CloudForwarding.Inbound
+	return jetstream.Options{
+		Url:                natsConfig.Url,
+		Stream:             natsConfig.JetStream.Stream,
+		Durable:            natsConfig.JetStream.Durable,
+		AckWait:            time.Duration(natsConfig.JetStream.AckWait) * time.Second,
+		MaxDeliver:         natsConfig.JetStream.MaxDeliver,
+		DeadLetterSubject:  natsConfig.JetStream.DeadLetterSubject,
+		RedeliveryDelay:    time.Duration(natsConfig.JetStream.RedeliveryDelay) * time.Second,
+		MaxRedeliveryDelay: time.Duration(natsConfig.JetStream.MaxRedeliveryDelay) * time.Second,
+		ReconnectDelay:     time.Duration(inbound.ReconnectDelay) * time.Second,
+		MaxReconnectDelay:  time.Duration(inbound.MaxReconnectDelay) * time.Second,
+		Logger:             config.Logger,
+	}
+}
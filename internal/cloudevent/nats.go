@@ -0,0 +1,83 @@
+package cloudevent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	cenats "github.com/cloudevents/sdk-go/protocol/nats/v2"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/nats-io/nats.go"
+)
+
+// natsSubscriber subscribes like the client of the cloud event provider, the state of its connection is
+// taken from the handlers of the NATS client which reconnects on its own
+type natsSubscriber struct {
+	consumer *cenats.Consumer
+	client   cloudevents.Client
+
+	connected atomic.Bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func newNatsSubscriber(topic string) (*natsSubscriber, error) {
+	natsConfig := config.CurrentConfiguration.CloudForwarding.Nats
+	s := &natsSubscriber{}
+	var options []cenats.ConsumerOption
+	if natsConfig.QueueGroup != "" {
+		options = append(options, cenats.WithQueueSubscriber(natsConfig.QueueGroup))
+	}
+	consumer, err := cenats.NewConsumer(natsConfig.Url, topic, []nats.Option{
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			s.connected.Store(false)
+			config.Logger.Warn("NATS connection lost", "url", natsConfig.Url, "err", err)
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			s.connected.Store(true)
+			config.Logger.Info("NATS connection reestablished", "url", natsConfig.Url)
+		}),
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cloudevents.NewClient(consumer)
+	if err != nil {
+		_ = consumer.Close(context.Background())
+		return nil, err
+	}
+	s.consumer = consumer
+	s.client = client
+	s.connected.Store(consumer.Conn.IsConnected())
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+func (s *natsSubscriber) Pub(event.Event) error {
+	return errors.New("nats subscriber does not publish")
+}
+
+// Sub handles the events of the subject until the subscriber is closed
+func (s *natsSubscriber) Sub(fn func(event event.Event)) error {
+	return s.client.StartReceiver(s.ctx, fn)
+}
+
+// Connected reports whether the NATS client is connected, the subscription is renewed by the client after a reconnect
+func (s *natsSubscriber) Connected() bool {
+	return s.connected.Load()
+}
+
+func (s *natsSubscriber) Close() (err error) {
+	s.once.Do(func() {
+		s.connected.Store(false)
+		s.cancel()
+		err = s.consumer.Close(context.Background())
+	})
+	return
+}
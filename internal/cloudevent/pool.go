@@ -0,0 +1,171 @@
+package cloudevent
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cloudeventprovider "github.com/eclipse-xfsc/cloud-event-provider"
+)
+
+var ErrPoolClosed = errors.New("publisher pool is closed")
+
+// Pool keeps one publisher per topic connected and reuses it for all events of the topic.
+// A publisher which failed is connected again with the next event, idle publishers are closed.
+type Pool struct {
+	newPublisher func(topic string) (Client, error)
+	idleTimeout  time.Duration
+
+	mu         sync.Mutex
+	publishers map[string]*publisher
+	closed     bool
+}
+
+type publisher struct {
+	topic    string
+	client   Client
+	lastUsed time.Time
+	// publishes which are using the client, a removed publisher is closed by the last of them
+	inFlight int
+	removed  bool
+}
+
+// NewPool creates a pool of publishers of the configured protocol, an idle timeout of 0 keeps them open
+func NewPool(idleTimeout time.Duration) *Pool {
+	return newPool(func(topic string) (Client, error) {
+		return NewClient(cloudeventprovider.Pub, topic)
+	}, idleTimeout)
+}
+
+func newPool(newPublisher func(topic string) (Client, error), idleTimeout time.Duration) *Pool {
+	return &Pool{
+		newPublisher: newPublisher,
+		idleTimeout:  idleTimeout,
+		publishers:   map[string]*publisher{},
+	}
+}
+
+// Publish sends the event with the publisher of the topic and waits for the confirmation of the broker
+func (p *Pool) Publish(topic string, event event.Event) error {
+	publisher, err := p.get(topic)
+	if err != nil {
+		return err
+	}
+	err = publisher.client.Pub(event)
+	if err != nil {
+		// the connection may be broken, the next event connects again
+		p.remove(publisher)
+	}
+	p.release(publisher)
+	return err
+}
+
+// Close closes all publishers, events can not be published afterwards. Publishers which are
+// in use are closed once their publish returns.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var unused []Client
+	for _, publisher := range p.publishers {
+		if p.removeLocked(publisher) {
+			unused = append(unused, publisher.client)
+		}
+	}
+	p.mu.Unlock()
+	return closeAll(unused)
+}
+
+// get returns the publisher of the topic, it must be released after the publish
+func (p *Pool) get(topic string) (*publisher, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	idle := p.removeIdle()
+	existing, ok := p.publishers[topic]
+	if ok {
+		existing.lastUsed = time.Now()
+		existing.inFlight++
+	}
+	p.mu.Unlock()
+	closeAll(idle)
+	if ok {
+		return existing, nil
+	}
+
+	// connect without the lock, so that other topics are not held up
+	client, err := p.newPublisher(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		client.Close()
+		return nil, ErrPoolClosed
+	}
+	// another worker connected to the topic meanwhile
+	if existing, ok := p.publishers[topic]; ok {
+		client.Close()
+		existing.lastUsed = time.Now()
+		existing.inFlight++
+		return existing, nil
+	}
+	created := &publisher{topic: topic, client: client, lastUsed: time.Now(), inFlight: 1}
+	p.publishers[topic] = created
+	return created, nil
+}
+
+// release ends a publish, the client of a removed publisher is closed after the last publish
+func (p *Pool) release(publisher *publisher) {
+	p.mu.Lock()
+	publisher.inFlight--
+	unused := publisher.removed && publisher.inFlight == 0
+	p.mu.Unlock()
+	if unused {
+		publisher.client.Close()
+	}
+}
+
+// remove takes a failed publisher out of the pool, it is closed when it is released
+func (p *Pool) remove(publisher *publisher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(publisher)
+}
+
+// removeLocked takes the publisher out of the pool and returns true if it is not in use and can be closed at once
+func (p *Pool) removeLocked(publisher *publisher) bool {
+	if current, ok := p.publishers[publisher.topic]; ok && current == publisher {
+		delete(p.publishers, publisher.topic)
+	}
+	publisher.removed = true
+	return publisher.inFlight == 0
+}
+
+// removeIdle takes the publishers which were not used within the idle timeout out of the pool,
+// publishers with a running publish are kept
+func (p *Pool) removeIdle() []Client {
+	if p.idleTimeout <= 0 {
+		return nil
+	}
+	var idle []Client
+	for _, publisher := range p.publishers {
+		if publisher.inFlight == 0 && time.Since(publisher.lastUsed) > p.idleTimeout {
+			p.removeLocked(publisher)
+			idle = append(idle, publisher.client)
+		}
+	}
+	return idle
+}
+
+func closeAll(clients []Client) error {
+	var errs []error
+	for _, client := range clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package cloudevent
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClient struct {
+	mu     sync.Mutex
+	fail   bool
+	sent   int
+	closed bool
+	// Pub waits for the channel if it is set
+	block chan struct{}
+}
+
+func (c *testClient) Pub(event event.Event) error {
+	if c.block != nil {
+		<-c.block
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return errors.New("connection lost")
+	}
+	c.sent++
+	return nil
+}
+
+func (c *testClient) Sub(fn func(event event.Event)) error {
+	return nil
+}
+
+func (c *testClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func newTestPool(idleTimeout time.Duration) (*Pool, *[]*testClient) {
+	var mu sync.Mutex
+	clients := []*testClient{}
+	pool := newPool(func(topic string) (Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		client := &testClient{}
+		clients = append(clients, client)
+		return client, nil
+	}, idleTimeout)
+	return pool, &clients
+}
+
+func TestPool_Reuse(t *testing.T) {
+	pool, clients := newTestPool(0)
+	event := cloudevents.NewEvent()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, pool.Publish("devices", event))
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, pool.Publish("replies", event))
+
+	// concurrent connects to a topic keep one of the publishers
+	open := 0
+	sent := 0
+	for _, client := range *clients {
+		if !client.closed {
+			open++
+			sent += client.sent
+		}
+	}
+	assert.Equal(t, 2, open)
+	assert.Equal(t, 11, sent)
+
+	assert.Nil(t, pool.Close())
+	for _, client := range *clients {
+		assert.True(t, client.closed)
+	}
+	assert.ErrorIs(t, pool.Publish("devices", event), ErrPoolClosed)
+}
+
+func TestPool_Reconnect(t *testing.T) {
+	pool, clients := newTestPool(0)
+	event := cloudevents.NewEvent()
+
+	assert.Nil(t, pool.Publish("devices", event))
+	(*clients)[0].fail = true
+	assert.NotNil(t, pool.Publish("devices", event))
+	assert.True(t, (*clients)[0].closed)
+
+	assert.Nil(t, pool.Publish("devices", event))
+	assert.Len(t, *clients, 2)
+	assert.Equal(t, 1, (*clients)[1].sent)
+}
+
+func TestPool_Idle(t *testing.T) {
+	pool, clients := newTestPool(10 * time.Millisecond)
+	event := cloudevents.NewEvent()
+
+	assert.Nil(t, pool.Publish("devices", event))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, pool.Publish("replies", event))
+	assert.True(t, (*clients)[0].closed)
+	assert.False(t, (*clients)[1].closed)
+}
+
+func TestPool_InFlight(t *testing.T) {
+	block := make(chan struct{})
+	var clients []*testClient
+	pool := newPool(func(topic string) (Client, error) {
+		client := &testClient{}
+		if topic == "devices" {
+			client.block = block
+		}
+		clients = append(clients, client)
+		return client, nil
+	}, 10*time.Millisecond)
+	event := cloudevents.NewEvent()
+
+	published := make(chan error)
+	go func() { published <- pool.Publish("devices", event) }()
+	time.Sleep(20 * time.Millisecond)
+	// the idle timeout passed, but the publisher of devices is still in use
+	assert.Nil(t, pool.Publish("replies", event))
+	assert.Nil(t, pool.Close())
+	assert.False(t, clients[0].closed)
+	assert.True(t, clients[1].closed)
+
+	close(block)
+	assert.Nil(t, <-published)
+	assert.Equal(t, 1, clients[0].sent)
+	assert.True(t, clients[0].closed)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,8 +12,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/spf13/viper"
 )
@@ -24,7 +27,18 @@ const (
 
 	HTTP   = "http"
 	NATS   = "nats"
+	KAFKA  = "kafka"
+	MQTT   = "mqtt"
 	HYBRID = "hybrid"
+
+	// P-384 keys are only used for key agreement, the authentication key of the suite is P-256
+	KEY_SUITE_ED25519   = "ed25519"
+	KEY_SUITE_P256      = "p256"
+	KEY_SUITE_P384      = "p384"
+	KEY_SUITE_SECP256K1 = "secp256k1"
+
+	TRACING_EXPORTER_NONE = "none"
+	TRACING_EXPORTER_OTLP = "otlp"
 )
 
 type TemplateConfiguration struct {
@@ -34,9 +48,60 @@ type TemplateConfiguration struct {
 	Url             string `mapstructure:"url" envconfig:"DIDCOMMCONNECTOR_URL"`
 	Label           string `mapstructure:"label" envconfig:"DIDCOMMCONNECTOR_LABEL"`
 	TokenExpiration int    `mapstructure:"tokenExpiration" envconfig:"DIDCOMMCONNECTOR_TOKENEXPIRATION" default:"1"`
-	DidComm         struct {
+	// timeouts are given in seconds
+	Server struct {
+		ReadTimeout       int `mapstructure:"readTimeout" envconfig:"DIDCOMMCONNECTOR_SERVER_READTIMEOUT"`
+		ReadHeaderTimeout int `mapstructure:"readHeaderTimeout" envconfig:"DIDCOMMCONNECTOR_SERVER_READHEADERTIMEOUT"`
+		WriteTimeout      int `mapstructure:"writeTimeout" envconfig:"DIDCOMMCONNECTOR_SERVER_WRITETIMEOUT"`
+		IdleTimeout       int `mapstructure:"idleTimeout" envconfig:"DIDCOMMCONNECTOR_SERVER_IDLETIMEOUT"`
+		Tls               struct {
+			Enabled         bool   `mapstructure:"enabled" envconfig:"DIDCOMMCONNECTOR_SERVER_TLS_ENABLED"`
+			CertFile        string `mapstructure:"certFile" envconfig:"DIDCOMMCONNECTOR_SERVER_TLS_CERTFILE"`
+			KeyFile         string `mapstructure:"keyFile" envconfig:"DIDCOMMCONNECTOR_SERVER_TLS_KEYFILE"`
+			ClientCaFile    string `mapstructure:"clientCaFile" envconfig:"DIDCOMMCONNECTOR_SERVER_TLS_CLIENTCAFILE"`
+			AdminClientAuth bool   `mapstructure:"adminClientAuth" envconfig:"DIDCOMMCONNECTOR_SERVER_TLS_ADMINCLIENTAUTH"`
+		} `mapstructure:"tls"`
+	} `mapstructure:"server"`
+	Outbound struct {
+		Timeout int `mapstructure:"timeout" envconfig:"DIDCOMMCONNECTOR_OUTBOUND_TIMEOUT"`
+		Tls     struct {
+			CaFile   string `mapstructure:"caFile" envconfig:"DIDCOMMCONNECTOR_OUTBOUND_TLS_CAFILE"`
+			CertFile string `mapstructure:"certFile" envconfig:"DIDCOMMCONNECTOR_OUTBOUND_TLS_CERTFILE"`
+			KeyFile  string `mapstructure:"keyFile" envconfig:"DIDCOMMCONNECTOR_OUTBOUND_TLS_KEYFILE"`
+		} `mapstructure:"tls"`
+	} `mapstructure:"outbound"`
+	DidComm struct {
 		ResolverUrl        string `mapstructure:"resolverUrl" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_RESOLVERURL"`
 		IsMessageEncrypted bool   `mapstructure:"messageEncrypted" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_ISMESSAGEENCRYPTED"`
+		// chain of resolvers which are tried in order, replaces the default chain with resolverUrl
+		Resolvers Resolvers `mapstructure:"resolvers" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_RESOLVERS"`
+		// run the mediator under this did:web instead of a generated did:peer
+		WebDid string `mapstructure:"webDid" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_WEBDID"`
+		// curves of the keys generated for mediator and routing DIDs, p384 signs with P-256 since the didcomm library has no ES384 signing
+		KeySuite string `mapstructure:"keySuite" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_KEYSUITE"`
+		// seconds between the checks of a did:web mediator for keys changed by another instance, 0 disables the check
+		KeyReloadInterval int `mapstructure:"keyReloadInterval" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_KEYRELOADINTERVAL"`
+		// sign own invitations with the authentication key of the mediator DID
+		SignInvitations bool `mapstructure:"signInvitations" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_SIGNINVITATIONS"`
+		// reject invitations of other connectors which are not signed by their sender
+		RequireSignedInvitations bool `mapstructure:"requireSignedInvitations" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_REQUIRESIGNEDINVITATIONS"`
+		// limits are given in bytes, a value of 0 disables the limit
+		Limits struct {
+			MaxEnvelopeSize   int64 `mapstructure:"maxEnvelopeSize" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_LIMITS_MAXENVELOPESIZE"`
+			MaxAttachments    int   `mapstructure:"maxAttachments" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_LIMITS_MAXATTACHMENTS"`
+			MaxAttachmentSize int64 `mapstructure:"maxAttachmentSize" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_LIMITS_MAXATTACHMENTSIZE"`
+		} `mapstructure:"limits"`
+		// durations are given in seconds, a value of 0 disables the check
+		Replay struct {
+			Retention     int `mapstructure:"retention" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_REPLAY_RETENTION"`
+			MaxMessageAge int `mapstructure:"maxMessageAge" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_REPLAY_MAXMESSAGEAGE"`
+		} `mapstructure:"replay"`
+		// durations are given in seconds, a maxEntries of 0 disables the cache
+		ResolverCache struct {
+			MaxEntries  int `mapstructure:"maxEntries" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_RESOLVERCACHE_MAXENTRIES"`
+			Ttl         int `mapstructure:"ttl" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_RESOLVERCACHE_TTL"`
+			NegativeTtl int `mapstructure:"negativeTtl" envconfig:"DIDCOMMCONNECTOR_DIDCOMM_RESOLVERCACHE_NEGATIVETTL"`
+		} `mapstructure:"resolverCache"`
 	} `mapstructure:"didcomm"`
 
 	CloudForwarding struct {
@@ -45,12 +110,131 @@ type TemplateConfiguration struct {
 			Url        string `mapstructure:"url" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_URL"`
 			Topic      string `mapstructure:"topic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_TOPIC"`
 			QueueGroup string `mapstructure:"topic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_QUEUEGROUP"`
+			// inbound events are consumed by a durable consumer of a stream and acknowledged once they are forwarded
+			JetStream struct {
+				Enabled bool `mapstructure:"enabled" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_ENABLED"`
+				// created for the topic if it does not exist
+				Stream  string `mapstructure:"stream" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_STREAM"`
+				Durable string `mapstructure:"durable" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_DURABLE"`
+				// seconds until an event without acknowledgement is delivered again
+				AckWait int `mapstructure:"ackWait" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_ACKWAIT"`
+				// failed deliveries until an event is published to the dead-letter subject
+				MaxDeliver int `mapstructure:"maxDeliver" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_MAXDELIVER"`
+				// seconds until a failed event is delivered again, doubled for every further delivery up to the max
+				RedeliveryDelay    int `mapstructure:"redeliveryDelay" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_REDELIVERYDELAY"`
+				MaxRedeliveryDelay int `mapstructure:"maxRedeliveryDelay" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_MAXREDELIVERYDELAY"`
+				// empty drops dead events
+				DeadLetterSubject string `mapstructure:"deadLetterSubject" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_NATS_JETSTREAM_DEADLETTERSUBJECT"`
+			} `mapstructure:"jetstream"`
 		} `mapstructure:"nats"`
 		Http struct {
 			Url string `mapstructure:"url" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_HTTP_URL"`
 		} `mapstructure:"http"`
+		Kafka struct {
+			Brokers []string `mapstructure:"brokers" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_BROKERS"`
+			// topic of the ConnectorMessage events which are forwarded to devices
+			Topic    string `mapstructure:"topic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_TOPIC"`
+			GroupId  string `mapstructure:"groupId" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_GROUPID"`
+			ClientId string `mapstructure:"clientId" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_CLIENTID"`
+			Version  string `mapstructure:"version" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_VERSION"`
+			Sasl     struct {
+				// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, empty disables SASL
+				Mechanism string `mapstructure:"mechanism" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_SASL_MECHANISM"`
+				User      string `mapstructure:"user" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_SASL_USER"`
+				// left out of the configuration which is logged at startup
+				Password string `mapstructure:"password" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_SASL_PASSWORD" json:"-"`
+			} `mapstructure:"sasl"`
+			Tls struct {
+				Enabled  bool   `mapstructure:"enabled" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_TLS_ENABLED"`
+				CaFile   string `mapstructure:"caFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_TLS_CAFILE"`
+				CertFile string `mapstructure:"certFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_TLS_CERTFILE"`
+				KeyFile  string `mapstructure:"keyFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_KAFKA_TLS_KEYFILE"`
+			} `mapstructure:"tls"`
+		} `mapstructure:"kafka"`
+		Mqtt struct {
+			// mqtt://, tls:// or ws:// URL of the broker
+			Url string `mapstructure:"url" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_URL"`
+			// topic of the ConnectorMessage events which are forwarded to devices
+			Topic string `mapstructure:"topic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_TOPIC"`
+			// topic of device messages, rendered with the Topic, Group and RemoteDid of the connection
+			TopicTemplate string `mapstructure:"topicTemplate" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_TOPICTEMPLATE"`
+			// shared subscription group of the connector instances, empty delivers every event to every instance
+			SharedGroup    string `mapstructure:"sharedGroup" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_SHAREDGROUP"`
+			ClientId       string `mapstructure:"clientId" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_CLIENTID"`
+			Qos            int    `mapstructure:"qos" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_QOS"`
+			Retain         bool   `mapstructure:"retain" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_RETAIN"`
+			RetainHandling int    `mapstructure:"retainHandling" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_RETAINHANDLING"`
+			KeepAlive      int    `mapstructure:"keepAlive" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_KEEPALIVE"`
+			ReconnectDelay int    `mapstructure:"reconnectDelay" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_RECONNECTDELAY"`
+			ConnectTimeout int    `mapstructure:"connectTimeout" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_CONNECTTIMEOUT"`
+			User           string `mapstructure:"user" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_USER"`
+			// left out of the configuration which is logged at startup
+			Password string `mapstructure:"password" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_PASSWORD" json:"-"`
+			Tls      struct {
+				CaFile   string `mapstructure:"caFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_TLS_CAFILE"`
+				CertFile string `mapstructure:"certFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_TLS_CERTFILE"`
+				KeyFile  string `mapstructure:"keyFile" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_MQTT_TLS_KEYFILE"`
+			} `mapstructure:"tls"`
+		} `mapstructure:"mqtt"`
+		Outbox struct {
+			// failed publishing attempts until an event is dead
+			MaxAttempts int `mapstructure:"maxAttempts" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_MAXATTEMPTS"`
+			// seconds until the first retry, doubled for every further retry up to the max retry interval
+			RetryInterval    int `mapstructure:"retryInterval" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_RETRYINTERVAL"`
+			MaxRetryInterval int `mapstructure:"maxRetryInterval" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_MAXRETRYINTERVAL"`
+			PollInterval     int `mapstructure:"pollInterval" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_POLLINTERVAL"`
+			// recipients whose events are published in parallel, the events of one recipient stay in order
+			Concurrency int `mapstructure:"concurrency" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_CONCURRENCY"`
+			// dead events are published to this topic, empty keeps them in the outbox only
+			DeadLetterTopic string `mapstructure:"deadLetterTopic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_OUTBOX_DEADLETTERTOPIC"`
+		} `mapstructure:"outbox"`
+		// seconds a cloud request is remembered for the replies of the device on its thread
+		Threads struct {
+			Ttl int `mapstructure:"ttl" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_THREADS_TTL"`
+		} `mapstructure:"threads"`
+		// publishers stay connected per topic, seconds until an unused publisher is closed, 0 keeps them open
+		Publishers struct {
+			IdleTimeout int `mapstructure:"idleTimeout" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_PUBLISHERS_IDLETIMEOUT"`
+		} `mapstructure:"publishers"`
+		// seconds until a failed subscription of inbound events is made again, doubled for every further attempt up to the max
+		Inbound struct {
+			ReconnectDelay    int `mapstructure:"reconnectDelay" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_INBOUND_RECONNECTDELAY"`
+			MaxReconnectDelay int `mapstructure:"maxReconnectDelay" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_INBOUND_MAXRECONNECTDELAY"`
+		} `mapstructure:"inbound"`
+		// topic of the delivery status events of forwarded connector messages, empty disables them
+		DeliveryStatus struct {
+			Topic string `mapstructure:"topic" envconfig:"DIDCOMMCONNECTOR_CLOUDFORWARDING_DELIVERYSTATUS_TOPIC"`
+		} `mapstructure:"deliveryStatus"`
 	} `mapstructure:"messaging"`
 
+	// Prometheus metrics under /metrics of a separate listener which is not exposed with the API
+	Metrics struct {
+		Enabled bool `mapstructure:"enabled" envconfig:"DIDCOMMCONNECTOR_METRICS_ENABLED"`
+		Port    int  `mapstructure:"port" envconfig:"DIDCOMMCONNECTOR_METRICS_PORT"`
+		// seconds between the reads of the pickup queues and connections from the database
+		RefreshInterval int `mapstructure:"refreshInterval" envconfig:"DIDCOMMCONNECTOR_METRICS_REFRESHINTERVAL"`
+		// recipients with the deepest and the oldest pickup queues which get gauges of their own, 0 disables them
+		TopRecipients int `mapstructure:"topRecipients" envconfig:"DIDCOMMCONNECTOR_METRICS_TOPRECIPIENTS"`
+	} `mapstructure:"metrics"`
+
+	// OpenTelemetry spans of the message pipeline
+	Tracing struct {
+		// none or otlp
+		Exporter string `mapstructure:"exporter" envconfig:"DIDCOMMCONNECTOR_TRACING_EXPORTER"`
+		// host:port of the OTLP/HTTP receiver, the OTEL_EXPORTER_OTLP_* variables apply if empty
+		Endpoint    string `mapstructure:"endpoint" envconfig:"DIDCOMMCONNECTOR_TRACING_ENDPOINT"`
+		Insecure    bool   `mapstructure:"insecure" envconfig:"DIDCOMMCONNECTOR_TRACING_INSECURE"`
+		ServiceName string `mapstructure:"serviceName" envconfig:"DIDCOMMCONNECTOR_TRACING_SERVICENAME"`
+		// ratio of new traces which are sampled, traces of a sampled parent are always sampled
+		SampleRatio float64 `mapstructure:"sampleRatio" envconfig:"DIDCOMMCONNECTOR_TRACING_SAMPLERATIO"`
+	} `mapstructure:"tracing"`
+
+	// checks of /health/live and /health/ready
+	Health struct {
+		// seconds after which a check of a dependency fails
+		Timeout int `mapstructure:"timeout" envconfig:"DIDCOMMCONNECTOR_HEALTH_TIMEOUT"`
+	} `mapstructure:"health"`
+
 	Database struct {
 		InMemory bool   `mapstructure:"inMemory" envconfig:"DIDCOMMCONNECTOR_DATBASE_INMEMORY" default:"false"`
 		Host     string `mapstructure:"host" envconfig:"DIDCOMMCONNECTOR_DATBASE_HOST"`
@@ -65,6 +249,9 @@ type TemplateConfiguration struct {
 }
 
 var CurrentConfiguration TemplateConfiguration
+
+// parsed messaging.mqtt.topicTemplate
+var mqttTopicTemplate *template.Template
 var Logger *slog.Logger
 var env string
 
@@ -92,13 +279,46 @@ func LoadConfig() error {
 	if err := setLogLevel(); err != nil {
 		return err
 	}
+	slog.Info("Set Outbound Client")
+	if err := setOutboundClient(); err != nil {
+		Logger.Error("Outbound client can not be configured", "msg", err)
+		return err
+	}
+	if err := checkServerTls(); err != nil {
+		return err
+	}
+	if err := checkKeySuite(); err != nil {
+		return err
+	}
+	if err := checkOutbox(); err != nil {
+		return err
+	}
+	if err := checkInbound(); err != nil {
+		return err
+	}
+	if err := checkMetrics(); err != nil {
+		return err
+	}
+	if err := checkTracing(); err != nil {
+		return err
+	}
+	if CurrentConfiguration.Health.Timeout < 1 {
+		return errors.New("health.timeout must be at least one second")
+	}
 	slog.Info("Load Resolver")
-	err = checkResolver(CurrentConfiguration.DidComm.ResolverUrl)
-	if err != nil {
-		Logger.Error("Resolver not available", "msg", err)
+	if err := checkResolvers(); err != nil {
 		return err
-	} else {
-		Logger.Info("Resolver available")
+	}
+	// a resolver which is down does not stop the start, the next resolver of the chain is used instead
+	for _, resolver := range DidResolvers() {
+		if resolver.Type != RESOLVER_UNIVERSAL {
+			continue
+		}
+		if err := CheckResolver(context.Background(), resolver.Url); err != nil {
+			Logger.Warn("Resolver not available", "url", resolver.Url, "methods", resolver.Methods, "msg", err)
+		} else {
+			Logger.Info("Resolver available", "url", resolver.Url)
+		}
 	}
 	slog.Info("Marshal Config")
 	b, err := json.Marshal(CurrentConfiguration)
@@ -125,6 +345,14 @@ func IsForwardTypeNats() bool {
 	return CurrentConfiguration.CloudForwarding.Protocol == NATS
 }
 
+func IsForwardTypeKafka() bool {
+	return CurrentConfiguration.CloudForwarding.Protocol == KAFKA
+}
+
+func IsForwardTypeMqtt() bool {
+	return CurrentConfiguration.CloudForwarding.Protocol == MQTT
+}
+
 func IsForwardTypeHybrid() bool {
 	return CurrentConfiguration.CloudForwarding.Protocol == HYBRID
 }
@@ -151,7 +379,60 @@ func setDefaults() {
 	viper.SetDefault("port", 9090)
 	viper.SetDefault("url", "http://localhost:9090")
 	viper.SetDefault("cloudForwarding.type", "http")
+	viper.SetDefault("messaging.nats.jetstream.stream", "DIDCOMM_CONNECTOR")
+	viper.SetDefault("messaging.nats.jetstream.durable", "didcomm-connector")
+	viper.SetDefault("messaging.nats.jetstream.ackWait", 30)
+	viper.SetDefault("messaging.nats.jetstream.maxDeliver", 5)
+	viper.SetDefault("messaging.nats.jetstream.redeliveryDelay", 5)
+	viper.SetDefault("messaging.nats.jetstream.maxRedeliveryDelay", 300)
+	viper.SetDefault("messaging.nats.jetstream.deadLetterSubject", "didcomm-inbound-dead-letter")
+	viper.SetDefault("messaging.kafka.clientId", "didcomm-connector")
+	viper.SetDefault("messaging.kafka.version", "2.8.0")
+	viper.SetDefault("messaging.mqtt.topicTemplate", "{{.Topic}}")
+	viper.SetDefault("messaging.mqtt.clientId", "didcomm-connector")
+	viper.SetDefault("messaging.mqtt.qos", 1)
+	// retained messages are only delivered on new subscriptions, not again on reconnect
+	viper.SetDefault("messaging.mqtt.retainHandling", 1)
+	viper.SetDefault("messaging.mqtt.keepAlive", 30)
+	viper.SetDefault("messaging.mqtt.reconnectDelay", 10)
+	viper.SetDefault("messaging.mqtt.connectTimeout", 10)
+	viper.SetDefault("messaging.outbox.maxAttempts", 10)
+	viper.SetDefault("messaging.outbox.retryInterval", 5)
+	viper.SetDefault("messaging.outbox.maxRetryInterval", 300)
+	viper.SetDefault("messaging.outbox.pollInterval", 5)
+	viper.SetDefault("messaging.outbox.concurrency", 4)
+	viper.SetDefault("messaging.outbox.deadLetterTopic", "didcomm-outbox-dead-letter")
+	viper.SetDefault("messaging.threads.ttl", 86400)
+	viper.SetDefault("messaging.publishers.idleTimeout", 300)
+	viper.SetDefault("messaging.inbound.reconnectDelay", 1)
+	viper.SetDefault("messaging.inbound.maxReconnectDelay", 60)
 	viper.SetDefault("didcomm.messageEncrypted", false)
+	viper.SetDefault("didcomm.signInvitations", false)
+	viper.SetDefault("didcomm.keySuite", KEY_SUITE_ED25519)
+	viper.SetDefault("didcomm.keyReloadInterval", 60)
+	viper.SetDefault("didcomm.requireSignedInvitations", false)
+	viper.SetDefault("server.readTimeout", 30)
+	viper.SetDefault("server.readHeaderTimeout", 10)
+	viper.SetDefault("server.writeTimeout", 30)
+	viper.SetDefault("server.idleTimeout", 120)
+	viper.SetDefault("outbound.timeout", 30)
+	viper.SetDefault("didcomm.limits.maxEnvelopeSize", 10485760)
+	viper.SetDefault("didcomm.limits.maxAttachments", 10)
+	viper.SetDefault("didcomm.limits.maxAttachmentSize", 5242880)
+	viper.SetDefault("didcomm.replay.retention", 86400)
+	viper.SetDefault("didcomm.replay.maxMessageAge", 3600)
+	viper.SetDefault("didcomm.resolverCache.maxEntries", 10000)
+	viper.SetDefault("didcomm.resolverCache.ttl", 300)
+	viper.SetDefault("didcomm.resolverCache.negativeTtl", 30)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.port", 9091)
+	viper.SetDefault("metrics.refreshInterval", 60)
+	viper.SetDefault("metrics.topRecipients", 10)
+	viper.SetDefault("tracing.exporter", TRACING_EXPORTER_NONE)
+	viper.SetDefault("tracing.insecure", false)
+	viper.SetDefault("tracing.serviceName", "didcomm-connector")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+	viper.SetDefault("health.timeout", 5)
 }
 
 func setEnvironment() {
@@ -212,21 +493,179 @@ func checkMode() error {
 	switch strings.ToLower(selectedTyp) {
 	case HTTP:
 	case NATS:
+	case KAFKA:
+		return checkKafka()
+	case MQTT:
+		return checkMqtt()
 	case HYBRID:
 		return fmt.Errorf("selected mode %s not yet supported", selectedTyp)
 	default:
-		return fmt.Errorf("unknown cloud forwarding type %s. Select one of these types: %s, %s or %s", selectedTyp, HTTP, NATS, HYBRID)
+		return fmt.Errorf("unknown cloud forwarding type %s. Select one of these types: %s, %s, %s, %s or %s", selectedTyp, HTTP, NATS, KAFKA, MQTT, HYBRID)
 	}
 
 	return nil
 }
 
-func checkResolver(resolverUrl string) error {
+func setOutboundClient() error {
+	outbound := CurrentConfiguration.Outbound
+	return transport.ConfigureClient(transport.ClientOptions{
+		CaFile:   outbound.Tls.CaFile,
+		CertFile: outbound.Tls.CertFile,
+		KeyFile:  outbound.Tls.KeyFile,
+		Timeout:  time.Duration(outbound.Timeout) * time.Second,
+	})
+}
+
+func checkKafka() error {
+	kafka := CurrentConfiguration.CloudForwarding.Kafka
+	if len(kafka.Brokers) == 0 {
+		return fmt.Errorf("messaging.kafka.brokers must be set for %s", KAFKA)
+	}
+	if kafka.Topic == "" || kafka.GroupId == "" {
+		return fmt.Errorf("messaging.kafka.topic and messaging.kafka.groupId must be set for %s", KAFKA)
+	}
+	switch kafka.Sasl.Mechanism {
+	case "":
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		if kafka.Sasl.User == "" {
+			return fmt.Errorf("messaging.kafka.sasl.user must be set for SASL")
+		}
+	default:
+		return fmt.Errorf("unknown SASL mechanism %s. Select one of these mechanisms: PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", kafka.Sasl.Mechanism)
+	}
+	return nil
+}
+
+func checkMqtt() error {
+	mqtt := CurrentConfiguration.CloudForwarding.Mqtt
+	if mqtt.Url == "" || mqtt.Topic == "" {
+		return fmt.Errorf("messaging.mqtt.url and messaging.mqtt.topic must be set for %s", MQTT)
+	}
+	if mqtt.Qos < 0 || mqtt.Qos > 2 {
+		return fmt.Errorf("messaging.mqtt.qos must be 0, 1 or 2")
+	}
+	if mqtt.RetainHandling < 0 || mqtt.RetainHandling > 2 {
+		return fmt.Errorf("messaging.mqtt.retainHandling must be 0, 1 or 2")
+	}
+	topicTemplate, err := template.New("topic").Parse(mqtt.TopicTemplate)
+	if err != nil {
+		return fmt.Errorf("messaging.mqtt.topicTemplate is invalid: %w", err)
+	}
+	mqttTopicTemplate = topicTemplate
+	return nil
+}
+
+// MqttTopicTemplate returns the topic template which was parsed when the configuration was loaded
+func MqttTopicTemplate() (*template.Template, error) {
+	if mqttTopicTemplate == nil {
+		return nil, errors.New("messaging.mqtt.topicTemplate is not loaded")
+	}
+	return mqttTopicTemplate, nil
+}
+
+func checkOutbox() error {
+	outbox := CurrentConfiguration.CloudForwarding.Outbox
+	if outbox.MaxAttempts < 1 {
+		return fmt.Errorf("messaging.outbox.maxAttempts must be at least 1")
+	}
+	if outbox.RetryInterval < 1 || outbox.MaxRetryInterval < outbox.RetryInterval || outbox.PollInterval < 1 {
+		return fmt.Errorf("messaging.outbox intervals must be positive and the max retry interval must not be below the retry interval")
+	}
+	if outbox.Concurrency < 1 {
+		return fmt.Errorf("messaging.outbox.concurrency must be at least 1")
+	}
+	return nil
+}
+
+func checkInbound() error {
+	inbound := CurrentConfiguration.CloudForwarding.Inbound
+	if inbound.ReconnectDelay < 1 || inbound.MaxReconnectDelay < inbound.ReconnectDelay {
+		return fmt.Errorf("messaging.inbound delays must be positive and the max reconnect delay must not be below the reconnect delay")
+	}
+	jetStream := CurrentConfiguration.CloudForwarding.Nats.JetStream
+	if !jetStream.Enabled {
+		return nil
+	}
+	if jetStream.Stream == "" || jetStream.Durable == "" {
+		return fmt.Errorf("messaging.nats.jetstream requires a stream and a durable name")
+	}
+	if jetStream.AckWait < 1 || jetStream.MaxDeliver < 1 || jetStream.RedeliveryDelay < 1 || jetStream.MaxRedeliveryDelay < jetStream.RedeliveryDelay {
+		return fmt.Errorf("messaging.nats.jetstream ackWait, maxDeliver and delays must be positive and the max redelivery delay must not be below the redelivery delay")
+	}
+	// dead device messages and dead cloud events have different payloads
+	if jetStream.DeadLetterSubject != "" && jetStream.DeadLetterSubject == CurrentConfiguration.CloudForwarding.Outbox.DeadLetterTopic {
+		return fmt.Errorf("messaging.nats.jetstream.deadLetterSubject must differ from messaging.outbox.deadLetterTopic")
+	}
+	return nil
+}
+
+func checkMetrics() error {
+	metrics := CurrentConfiguration.Metrics
+	if !metrics.Enabled {
+		return nil
+	}
+	if metrics.Port == CurrentConfiguration.Port {
+		return errors.New("metrics.port must differ from port")
+	}
+	if metrics.RefreshInterval < 1 {
+		return errors.New("metrics.refreshInterval must be at least one second")
+	}
+	if metrics.TopRecipients < 0 {
+		return errors.New("metrics.topRecipients must not be negative")
+	}
+	return nil
+}
+
+func checkTracing() error {
+	tracing := CurrentConfiguration.Tracing
+	if tracing.Exporter != TRACING_EXPORTER_NONE && tracing.Exporter != TRACING_EXPORTER_OTLP {
+		return fmt.Errorf("tracing.exporter must be %s or %s", TRACING_EXPORTER_NONE, TRACING_EXPORTER_OTLP)
+	}
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sampleRatio must be between 0 and 1")
+	}
+	return nil
+}
+
+func checkServerTls() error {
+	serverTls := CurrentConfiguration.Server.Tls
+	if !serverTls.Enabled {
+		if serverTls.AdminClientAuth {
+			return fmt.Errorf("client certificate authentication for admin requires server.tls.enabled")
+		}
+		return nil
+	}
+	if serverTls.CertFile == "" || serverTls.KeyFile == "" {
+		return fmt.Errorf("server.tls.certFile and server.tls.keyFile must be set if TLS is enabled")
+	}
+	if serverTls.AdminClientAuth && serverTls.ClientCaFile == "" {
+		return fmt.Errorf("server.tls.clientCaFile must be set for client certificate authentication")
+	}
+	return nil
+}
+
+func checkKeySuite() error {
+	keySuite := strings.ToLower(CurrentConfiguration.DidComm.KeySuite)
+	switch keySuite {
+	case KEY_SUITE_ED25519, KEY_SUITE_P256, KEY_SUITE_P384, KEY_SUITE_SECP256K1:
+		CurrentConfiguration.DidComm.KeySuite = keySuite
+		return nil
+	default:
+		return fmt.Errorf("unknown key suite %s. Select one of these suites: %s, %s, %s or %s", keySuite, KEY_SUITE_ED25519, KEY_SUITE_P256, KEY_SUITE_P384, KEY_SUITE_SECP256K1)
+	}
+}
+
+// CheckResolver checks that the Universal Resolver of resolverUrl is available
+func CheckResolver(ctx context.Context, resolverUrl string) error {
 	queryUrl, err := url.JoinPath(resolverUrl, "/1.0/testIdentifiers")
 	if err != nil {
 		return err
 	}
-	resp, err := http.Get(queryUrl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := transport.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// types of the DID resolver chain
+const (
+	RESOLVER_LOCAL     = "local"
+	RESOLVER_UNIVERSAL = "universal"
+)
+
+// Resolver is an entry of the DID resolver chain
+type Resolver struct {
+	// local resolves did:peer, did:key and did:web, universal calls a Universal Resolver
+	Type string `mapstructure:"type" json:"type"`
+	// DID methods without the did: prefix, * matches all methods
+	Methods []string `mapstructure:"methods" json:"methods"`
+	Url     string   `mapstructure:"url" json:"url"`
+	// timeout in seconds, 0 uses the timeout of the outbound client
+	Timeout int `mapstructure:"timeout" json:"timeout"`
+}
+
+// Resolvers are given as JSON list in the environment variable
+type Resolvers []Resolver
+
+func (r *Resolvers) Decode(value string) error {
+	return json.Unmarshal([]byte(value), (*[]Resolver)(r))
+}
+
+// DidResolvers returns the configured resolver chain. Without configuration did:peer, did:key and did:web
+// are resolved locally and all other methods with the Universal Resolver of resolverUrl.
+func DidResolvers() Resolvers {
+	if len(CurrentConfiguration.DidComm.Resolvers) > 0 {
+		return CurrentConfiguration.DidComm.Resolvers
+	}
+	return Resolvers{
+		{Type: RESOLVER_LOCAL, Methods: []string{"peer", "key", "web"}},
+		{Type: RESOLVER_UNIVERSAL, Methods: []string{"*"}, Url: CurrentConfiguration.DidComm.ResolverUrl},
+	}
+}
+
+func checkResolvers() error {
+	for i, resolver := range CurrentConfiguration.DidComm.Resolvers {
+		if len(resolver.Methods) == 0 {
+			return fmt.Errorf("didcomm.resolvers[%d] has no methods", i)
+		}
+		switch resolver.Type {
+		case RESOLVER_LOCAL:
+		case RESOLVER_UNIVERSAL:
+			if resolver.Url == "" {
+				return fmt.Errorf("didcomm.resolvers[%d] needs an url", i)
+			}
+		default:
+			return fmt.Errorf("unknown resolver type %s. Select one of these types: %s or %s", resolver.Type, RESOLVER_LOCAL, RESOLVER_UNIVERSAL)
+		}
+	}
+	return nil
+}
@@ -3,8 +3,16 @@ package intErr
 import "errors"
 
 var (
-	ErrNoPingResponseRequested = errors.New("no ping response requested")
-	ErrUnknownMessageType      = errors.New("unknown message type")
-	ErrNotImplemented          = errors.New("not implemented")
-	ErrUnpackingMessage        = errors.New("can not unpacking received message")
+	ErrNoPingResponseRequested  = errors.New("no ping response requested")
+	ErrUnknownMessageType       = errors.New("unknown message type")
+	ErrNotImplemented           = errors.New("not implemented")
+	ErrUnpackingMessage         = errors.New("can not unpacking received message")
+	ErrMessageTooLarge          = errors.New("message exceeds the envelope size limit")
+	ErrTooManyAttachments       = errors.New("message exceeds the attachment count limit")
+	ErrAttachmentTooLarge       = errors.New("attachment exceeds the attachment size limit")
+	ErrMessageTooOld            = errors.New("message is older than the maximum message age")
+	ErrDuplicateMessage         = errors.New("message was already received")
+	ErrInvitationNotSigned      = errors.New("invitation is not signed")
+	ErrInvitationSignerMismatch = errors.New("invitation is not signed by its sender")
+	ErrInvalidRotation          = errors.New("DID rotation is not valid")
 )
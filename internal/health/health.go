@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	STATUS_UP       = "up"
+	STATUS_DOWN     = "down"
+	STATUS_DEGRADED = "degraded"
+)
+
+var ErrTimeout = errors.New("check timed out")
+
+// Check is a dependency of the connector. A failed critical check fails the whole report,
+// other failed checks only degrade it.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+type Result struct {
+	Status   string `json:"status" example:"up"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	// duration of the check in milliseconds
+	Duration int64 `json:"duration"`
+}
+
+type Report struct {
+	Status string            `json:"status" example:"up"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Up is false if a critical check failed
+func (r Report) Up() bool {
+	return r.Status != STATUS_DOWN
+}
+
+// Run runs the checks in parallel, each check fails after the timeout even if it ignores its context
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	report := Report{Status: STATUS_UP, Checks: make(map[string]Result, len(checks))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := run(ctx, timeout, check)
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == STATUS_UP {
+				return
+			}
+			if check.Critical {
+				report.Status = STATUS_DOWN
+			} else if report.Status == STATUS_UP {
+				report.Status = STATUS_DEGRADED
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func run(ctx context.Context, timeout time.Duration, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ErrTimeout
+	}
+	result := Result{Status: STATUS_UP, Critical: check.Critical, Duration: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = STATUS_DOWN
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	failed := func(context.Context) error { return errors.New("connection refused") }
+	// ignores its context, the check times out anyway
+	blocked := func(context.Context) error { time.Sleep(time.Second); return nil }
+
+	report := Run(context.Background(), 50*time.Millisecond, []Check{
+		{Name: "database", Critical: true, Run: up},
+		{Name: "resolver", Run: failed},
+	})
+	assert.Equal(t, STATUS_DEGRADED, report.Status)
+	assert.True(t, report.Up())
+	assert.Equal(t, STATUS_UP, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["resolver"].Error)
+
+	start := time.Now()
+	report = Run(context.Background(), 50*time.Millisecond, []Check{
+		{Name: "database", Critical: true, Run: blocked},
+		{Name: "resolver", Run: failed},
+	})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, STATUS_DOWN, report.Status)
+	assert.False(t, report.Up())
+	assert.Equal(t, ErrTimeout.Error(), report.Checks["database"].Error)
+}
@@ -0,0 +1,272 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/nats-io/nats.go"
+	natsjs "github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	HEADER_DEADLETTER_REASON = "Didcomm-Deadletter-Reason"
+	HEADER_ORIGINAL_SUBJECT  = "Didcomm-Original-Subject"
+	HEADER_DELIVERIES        = "Didcomm-Deliveries"
+)
+
+// ErrInvalidEvent is returned by a handler for events which fail with every delivery, they are dead-lettered at once
+var ErrInvalidEvent = errors.New("invalid event")
+
+type Options struct {
+	Url string
+	// Stream is created for the subject if it does not exist
+	Stream  string
+	Durable string
+	// AckWait is the time after which an event without acknowledgement is delivered again
+	AckWait time.Duration
+	// MaxDeliver deliveries of an event fail before it is published to the dead-letter subject
+	MaxDeliver int
+	// DeadLetterSubject is added to a stream named after the stream with the suffix _DEAD_LETTER if no stream stores it
+	DeadLetterSubject string
+
+	// RedeliveryDelay is doubled with every failed delivery of an event up to the max redelivery delay
+	RedeliveryDelay    time.Duration
+	MaxRedeliveryDelay time.Duration
+	// ReconnectDelay is doubled with every failed attempt to set up the consumer up to the max reconnect delay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	Logger *slog.Logger
+}
+
+// Handler processes an event, the event is acknowledged if no error is returned and delivered again otherwise.
+// last is set for the last delivery, the event is dead if the handler fails.
+type Handler func(event event.Event, last bool) error
+
+// Consumer handles the events of a subject with a durable JetStream consumer. Events are acknowledged once
+// they are handled, failed events are delivered again up to a limit and published to a dead-letter subject afterwards.
+type Consumer struct {
+	opts    Options
+	subject string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// held while an event is handled
+	handling sync.Mutex
+	// set while the consumer is set up and the connection is established
+	connected atomic.Bool
+}
+
+func NewConsumer(opts Options, subject string) *Consumer {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.MaxDeliver < 1 {
+		opts.MaxDeliver = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Consumer{opts: opts, subject: subject, ctx: ctx, cancel: cancel}
+}
+
+// Consume handles the events until the consumer is closed. The connection and the consumer are set up again
+// with a growing delay when they fail.
+func (c *Consumer) Consume(handler Handler) error {
+	attempt := 0
+	for {
+		err := c.consume(handler, func() { attempt = 0 })
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		delay := Backoff(attempt, c.opts.ReconnectDelay, c.opts.MaxReconnectDelay)
+		attempt++
+		c.opts.Logger.Warn("JetStream consumer failed, reconnecting", "subject", c.subject, "delay", delay, "err", err)
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Connected reports whether events are consumed at the moment
+func (c *Consumer) Connected() bool {
+	return c.connected.Load()
+}
+
+// Close stops the consumer, Consume returns after the event which is being handled
+func (c *Consumer) Close() error {
+	c.cancel()
+	return nil
+}
+
+func (c *Consumer) consume(handler Handler, connected func()) error {
+	failed := make(chan error, 1)
+	conn, err := nats.Connect(c.opts.Url,
+		nats.Name(c.opts.Durable),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(c.opts.ReconnectDelay),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			c.connected.Store(false)
+			c.opts.Logger.Warn("NATS connection lost", "url", c.opts.Url, "err", err)
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			c.connected.Store(true)
+			c.opts.Logger.Info("NATS connection reestablished", "url", c.opts.Url)
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	js, consumer, err := c.setup(conn)
+	if err != nil {
+		return err
+	}
+	consumeContext, err := consumer.Consume(func(msg natsjs.Msg) {
+		c.handle(js, msg, handler)
+	}, natsjs.ConsumeErrHandler(func(_ natsjs.ConsumeContext, err error) {
+		// the consumer has to be created again, other errors are recovered by the client
+		if errors.Is(err, natsjs.ErrConsumerDeleted) || errors.Is(err, natsjs.ErrConsumerNotFound) {
+			select {
+			case failed <- err:
+			default:
+			}
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	c.opts.Logger.Info("Consuming JetStream events", "stream", c.opts.Stream, "durable", c.opts.Durable, "subject", c.subject)
+	connected()
+	c.connected.Store(true)
+	defer c.connected.Store(false)
+
+	select {
+	case <-c.ctx.Done():
+	case err = <-failed:
+	}
+	consumeContext.Stop()
+	c.handling.Lock()
+	c.handling.Unlock()
+	// send the last acknowledgements before the connection is closed
+	if flushErr := conn.Flush(); flushErr != nil {
+		c.opts.Logger.Warn("Unable to flush NATS connection", "err", flushErr)
+	}
+	return err
+}
+
+func (c *Consumer) setup(conn *nats.Conn) (natsjs.JetStream, natsjs.Consumer, error) {
+	js, err := natsjs.New(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+	stream, err := js.Stream(ctx, c.opts.Stream)
+	if errors.Is(err, natsjs.ErrStreamNotFound) {
+		stream, err = js.CreateStream(ctx, natsjs.StreamConfig{Name: c.opts.Stream, Subjects: []string{c.subject}})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("stream %s: %w", c.opts.Stream, err)
+	}
+	if c.opts.DeadLetterSubject != "" {
+		// dead events are only terminated once a stream stored them
+		_, err = js.StreamNameBySubject(ctx, c.opts.DeadLetterSubject)
+		if errors.Is(err, natsjs.ErrStreamNotFound) {
+			_, err = js.CreateStream(ctx, natsjs.StreamConfig{Name: c.deadLetterStream(), Subjects: []string{c.opts.DeadLetterSubject}})
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("dead-letter stream for %s: %w", c.opts.DeadLetterSubject, err)
+		}
+	}
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, natsjs.ConsumerConfig{
+		Durable:       c.opts.Durable,
+		FilterSubject: c.subject,
+		AckPolicy:     natsjs.AckExplicitPolicy,
+		AckWait:       c.opts.AckWait,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("consumer %s: %w", c.opts.Durable, err)
+	}
+	return js, consumer, nil
+}
+
+func (c *Consumer) deadLetterStream() string {
+	return c.opts.Stream + "_DEAD_LETTER"
+}
+
+func (c *Consumer) handle(js natsjs.JetStream, msg natsjs.Msg, handler Handler) {
+	c.handling.Lock()
+	defer c.handling.Unlock()
+
+	var e event.Event
+	if err := json.Unmarshal(msg.Data(), &e); err != nil {
+		c.deadLetter(js, msg, 1, fmt.Errorf("%w: %w", ErrInvalidEvent, err))
+		return
+	}
+	deliveries := 1
+	if metadata, metadataErr := msg.Metadata(); metadataErr == nil {
+		deliveries = int(metadata.NumDelivered)
+	}
+	err := handler(e, deliveries >= c.opts.MaxDeliver)
+	if err == nil {
+		if err = msg.Ack(); err != nil {
+			c.opts.Logger.Warn("Unable to acknowledge event", "id", e.ID(), "err", err)
+		}
+		return
+	}
+
+	if errors.Is(err, ErrInvalidEvent) || deliveries >= c.opts.MaxDeliver {
+		c.deadLetter(js, msg, deliveries, err)
+		return
+	}
+	delay := Backoff(deliveries-1, c.opts.RedeliveryDelay, c.opts.MaxRedeliveryDelay)
+	c.opts.Logger.Warn("Handling event failed, delivering again", "id", e.ID(), "deliveries", deliveries, "delay", delay, "err", err)
+	if err = msg.NakWithDelay(delay); err != nil {
+		c.opts.Logger.Warn("Unable to request redelivery", "id", e.ID(), "err", err)
+	}
+}
+
+// deadLetter publishes the event to the dead-letter subject and removes it from the consumer once the
+// dead-letter stream acknowledged it
+func (c *Consumer) deadLetter(js natsjs.JetStream, msg natsjs.Msg, deliveries int, reason error) {
+	c.opts.Logger.Error("Event is dead", "subject", msg.Subject(), "deliveries", deliveries, "err", reason)
+	if c.opts.DeadLetterSubject != "" {
+		header := nats.Header{}
+		for key, values := range msg.Headers() {
+			header[key] = values
+		}
+		header.Set(HEADER_DEADLETTER_REASON, reason.Error())
+		header.Set(HEADER_ORIGINAL_SUBJECT, msg.Subject())
+		header.Set(HEADER_DELIVERIES, strconv.Itoa(deliveries))
+		ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+		_, err := js.PublishMsg(ctx, &nats.Msg{Subject: c.opts.DeadLetterSubject, Header: header, Data: msg.Data()})
+		cancel()
+		if err != nil {
+			// keep the event, it is delivered again after the ack wait
+			c.opts.Logger.Error("Unable to publish dead event", "subject", c.opts.DeadLetterSubject, "err", err)
+			return
+		}
+	}
+	if err := msg.TermWithReason(reason.Error()); err != nil {
+		c.opts.Logger.Warn("Unable to terminate dead event", "err", err)
+	}
+}
+
+// Backoff doubles the delay for every attempt up to the max delay
+func Backoff(attempt int, delay time.Duration, maxDelay time.Duration) time.Duration {
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,114 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	natsjs "github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(0, time.Second, time.Minute))
+	assert.Equal(t, 8*time.Second, Backoff(3, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, Backoff(10, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, Backoff(1000, time.Second, time.Minute))
+}
+
+// TestConsumer_LocalServer runs against the NATS server of the docker compose file:
+// DIDCOMMCONNECTOR_TEST_NATS_URL=nats://localhost:4222 go test ./internal/jetstream
+func TestConsumer_LocalServer(t *testing.T) {
+	serverUrl := os.Getenv("DIDCOMMCONNECTOR_TEST_NATS_URL")
+	if serverUrl == "" {
+		t.Skip("DIDCOMMCONNECTOR_TEST_NATS_URL not set")
+	}
+	id := uuid.NewString()
+	subject := "test." + id
+	opts := Options{
+		Url:                serverUrl,
+		Stream:             "TEST_" + id,
+		Durable:            "test",
+		AckWait:            10 * time.Second,
+		MaxDeliver:         2,
+		DeadLetterSubject:  subject + ".dead",
+		RedeliveryDelay:    100 * time.Millisecond,
+		MaxRedeliveryDelay: time.Second,
+		ReconnectDelay:     time.Second,
+		MaxReconnectDelay:  time.Second,
+	}
+
+	conn, err := nats.Connect(serverUrl)
+	assert.Nil(t, err)
+	defer conn.Close()
+	dead, err := conn.SubscribeSync(opts.DeadLetterSubject)
+	assert.Nil(t, err)
+
+	handled := make(chan string, 10)
+	last := make(chan bool, 10)
+	consumer := NewConsumer(opts, subject)
+	done := make(chan struct{})
+	go func() {
+		consumer.Consume(func(e event.Event, lastDelivery bool) error {
+			handled <- e.ID()
+			if e.ID() == "fails" {
+				last <- lastDelivery
+				return errors.New("device unknown")
+			}
+			return nil
+		})
+		close(done)
+	}()
+	// the stream is created once the consumer is connected
+	time.Sleep(time.Second)
+
+	for _, id := range []string{"handled", "fails"} {
+		sent := cloudevents.NewEvent()
+		sent.SetID(id)
+		sent.SetSource("test")
+		sent.SetType("test")
+		data, err := sent.MarshalJSON()
+		assert.Nil(t, err)
+		assert.Nil(t, conn.Publish(subject, data))
+	}
+
+	received := []string{}
+	for len(received) < 3 {
+		select {
+		case id := <-handled:
+			received = append(received, id)
+		case <-time.After(10 * time.Second):
+			t.Fatal("event not delivered", received)
+		}
+	}
+	// the failed event is delivered again and dead afterwards
+	assert.ElementsMatch(t, []string{"handled", "fails", "fails"}, received)
+	assert.False(t, <-last)
+	assert.True(t, <-last)
+	msg, err := dead.NextMsg(10 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "device unknown", msg.Header.Get(HEADER_DEADLETTER_REASON))
+	assert.Equal(t, subject, msg.Header.Get(HEADER_ORIGINAL_SUBJECT))
+
+	consumer.Close()
+	<-done
+
+	// the dead event is stored by the dead-letter stream which the consumer created
+	js, err := natsjs.New(conn)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := js.Stream(ctx, opts.Stream+"_DEAD_LETTER")
+	assert.Nil(t, err)
+	stored, err := stream.GetLastMsgForSubject(ctx, opts.DeadLetterSubject)
+	assert.Nil(t, err)
+	assert.Equal(t, "device unknown", stored.Header.Get(HEADER_DEADLETTER_REASON))
+	assert.Nil(t, js.DeleteStream(ctx, opts.Stream+"_DEAD_LETTER"))
+	assert.Nil(t, js.DeleteStream(ctx, opts.Stream))
+}
@@ -0,0 +1,245 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IBM/sarama"
+	"github.com/cloudevents/sdk-go/protocol/kafka_sarama/v2"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	SASL_PLAIN         = "PLAIN"
+	SASL_SCRAM_SHA_256 = "SCRAM-SHA-256"
+	SASL_SCRAM_SHA_512 = "SCRAM-SHA-512"
+
+	// PARTITION_KEY is the cloud event extension which the binding maps to the message key
+	PARTITION_KEY = "partitionkey"
+)
+
+type Options struct {
+	Brokers  []string
+	GroupId  string
+	ClientId string
+	Version  string
+
+	SaslMechanism string
+	SaslUser      string
+	SaslPassword  string
+
+	Tls      bool
+	CaFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// Client publishes cloud events to or consumes them from one Kafka topic
+type Client struct {
+	client cloudevents.Client
+	closer interface{ Close(context.Context) error }
+
+	// nil for publishers
+	connected *atomic.Bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+// NewPublisher creates a client which publishes to the topic
+func NewPublisher(opts Options, topic string) (*Client, error) {
+	saramaConfig, err := NewSaramaConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := kafka_sarama.NewSender(opts.Brokers, saramaConfig, topic)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(sender, sender, nil)
+}
+
+// NewSubscriber creates a client which consumes the topic as member of the consumer group of the options
+func NewSubscriber(opts Options, topic string) (*Client, error) {
+	saramaConfig, err := NewSaramaConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sarama.NewClient(opts.Brokers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	consumer := &consumer{Receiver: kafka_sarama.NewReceiver(), client: client, groupId: opts.GroupId, topic: topic}
+	return newClient(consumer, consumer, &consumer.connected)
+}
+
+func newClient(protocol interface{}, closer interface{ Close(context.Context) error }, connected *atomic.Bool) (*Client, error) {
+	client, err := cloudevents.NewClient(protocol, cloudevents.WithTimeNow(), cloudevents.WithUUIDs())
+	if err != nil {
+		_ = closer.Close(context.Background())
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{client: client, closer: closer, connected: connected, ctx: ctx, cancel: cancel}, nil
+}
+
+// Pub sends the event and waits for the acknowledgement of the broker
+func (c *Client) Pub(event event.Event) error {
+	if result := c.client.Send(c.ctx, event); !cloudevents.IsACK(result) {
+		return result
+	}
+	return nil
+}
+
+// Sub handles the events of the topic until the client is closed
+func (c *Client) Sub(fn func(event event.Event)) error {
+	return c.client.StartReceiver(c.ctx, fn)
+}
+
+// Connected reports whether a subscriber has a session of its consumer group, a publisher is never connected
+func (c *Client) Connected() bool {
+	return c.connected != nil && c.connected.Load()
+}
+
+func (c *Client) Close() (err error) {
+	c.once.Do(func() {
+		c.cancel()
+		err = c.closer.Close(context.Background())
+	})
+	return
+}
+
+// consumer joins the consumer group like the consumer of the binding, the sessions of the group are tracked.
+// A session ends with a rebalance or when the connection to the group coordinator is lost.
+type consumer struct {
+	*kafka_sarama.Receiver
+
+	client  sarama.Client
+	groupId string
+	topic   string
+
+	connected atomic.Bool
+}
+
+func (c *consumer) Setup(sarama.ConsumerGroupSession) error {
+	c.connected.Store(true)
+	return nil
+}
+
+func (c *consumer) Cleanup(sarama.ConsumerGroupSession) error {
+	c.connected.Store(false)
+	return nil
+}
+
+func (c *consumer) OpenInbound(ctx context.Context) error {
+	group, err := sarama.NewConsumerGroupFromClient(c.groupId, c.client)
+	if err != nil {
+		return err
+	}
+	defer c.Receiver.Close(ctx)
+	defer group.Close()
+	for {
+		err := group.Consume(ctx, []string{c.topic}, c)
+		if ctx.Err() != nil || errors.Is(err, sarama.ErrClosedClient) || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+		// the subscriber joins the group again after a delay
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *consumer) Close(context.Context) error {
+	c.connected.Store(false)
+	return c.client.Close()
+}
+
+// NewSaramaConfig translates the options to the sarama config of producers and consumer groups
+func NewSaramaConfig(opts Options) (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	if opts.ClientId != "" {
+		saramaConfig.ClientID = opts.ClientId
+	}
+	if opts.Version != "" {
+		version, err := sarama.ParseKafkaVersion(opts.Version)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Version = version
+	}
+	// messages of one device must neither get lost nor be reordered by retries, a single request in flight
+	// keeps retried batches in order and the idempotent producer keeps them from being written twice
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	saramaConfig.Net.MaxOpenRequests = 1
+	saramaConfig.Producer.Idempotent = saramaConfig.Version.IsAtLeast(sarama.V0_11_0_0)
+	// a new consumer group starts with the retained events instead of skipping them
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	if opts.Tls {
+		tlsConfig, err := transport.NewClientTLSConfig(opts.CaFile, opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	switch opts.SaslMechanism {
+	case "":
+	case SASL_PLAIN:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case SASL_SCRAM_SHA_256:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: sha256.New}
+		}
+	case SASL_SCRAM_SHA_512:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: sha512.New}
+		}
+	default:
+		return nil, fmt.Errorf("unknown SASL mechanism %s", opts.SaslMechanism)
+	}
+	if opts.SaslMechanism != "" {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.User = opts.SaslUser
+		saramaConfig.Net.SASL.Password = opts.SaslPassword
+	}
+
+	return saramaConfig, saramaConfig.Validate()
+}
+
+// scramClient implements sarama.SCRAMClient
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName string, password string, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
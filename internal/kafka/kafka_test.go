@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSaramaConfig(t *testing.T) {
+	saramaConfig, err := NewSaramaConfig(Options{ClientId: "connector", Version: "3.6.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, "connector", saramaConfig.ClientID)
+	assert.Equal(t, sarama.V3_6_0_0, saramaConfig.Version)
+	assert.False(t, saramaConfig.Net.SASL.Enable)
+	assert.False(t, saramaConfig.Net.TLS.Enable)
+	assert.True(t, saramaConfig.Producer.Idempotent)
+	assert.Equal(t, 1, saramaConfig.Net.MaxOpenRequests)
+	assert.Nil(t, saramaConfig.Validate())
+
+	saramaConfig, err = NewSaramaConfig(Options{SaslMechanism: SASL_SCRAM_SHA_512, SaslUser: "user", SaslPassword: "secret", Tls: true})
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), saramaConfig.Net.SASL.Mechanism)
+	assert.NotNil(t, saramaConfig.Net.SASL.SCRAMClientGeneratorFunc())
+	assert.True(t, saramaConfig.Net.TLS.Enable)
+
+	// brokers before 0.11 do not support idempotent producers
+	saramaConfig, err = NewSaramaConfig(Options{Version: "0.10.2.0"})
+	assert.Nil(t, err)
+	assert.False(t, saramaConfig.Producer.Idempotent)
+	assert.Nil(t, saramaConfig.Validate())
+
+	_, err = NewSaramaConfig(Options{SaslMechanism: "GSSAPI"})
+	assert.NotNil(t, err)
+	_, err = NewSaramaConfig(Options{Version: "latest"})
+	assert.NotNil(t, err)
+}
+
+func TestClient_Connected(t *testing.T) {
+	consumer := &consumer{}
+	client := &Client{connected: &consumer.connected}
+	assert.False(t, client.Connected())
+	assert.Nil(t, consumer.Setup(nil))
+	assert.True(t, client.Connected())
+	// rebalance or lost coordinator
+	assert.Nil(t, consumer.Cleanup(nil))
+	assert.False(t, client.Connected())
+
+	assert.False(t, (&Client{}).Connected())
+}
+
+// TestClient_LocalBroker runs against the broker of the docker compose file:
+// DIDCOMMCONNECTOR_TEST_KAFKA_BROKERS=localhost:9094 go test ./internal/kafka
+func TestClient_LocalBroker(t *testing.T) {
+	brokers := os.Getenv("DIDCOMMCONNECTOR_TEST_KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("DIDCOMMCONNECTOR_TEST_KAFKA_BROKERS not set")
+	}
+	opts := Options{Brokers: strings.Split(brokers, ","), GroupId: "test-" + uuid.NewString(), Version: "2.8.0"}
+	topic := "test-" + uuid.NewString()
+
+	publisher, err := NewPublisher(opts, topic)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	sent := cloudevents.NewEvent()
+	sent.SetID(uuid.NewString())
+	sent.SetSource("test")
+	sent.SetType("test")
+	sent.SetExtension(PARTITION_KEY, "did:peer:2.recipient")
+	assert.Nil(t, sent.SetData(cloudevents.ApplicationJSON, map[string]string{"hello": "kafka"}))
+	assert.Nil(t, publisher.Pub(sent))
+
+	subscriber, err := NewSubscriber(opts, topic)
+	assert.Nil(t, err)
+	received := make(chan event.Event, 1)
+	go subscriber.Sub(func(e event.Event) {
+		received <- e
+	})
+	defer subscriber.Close()
+
+	select {
+	case e := <-received:
+		assert.Equal(t, sent.ID(), e.ID())
+		assert.Equal(t, "did:peer:2.recipient", e.Extensions()[PARTITION_KEY])
+	case <-time.After(30 * time.Second):
+		t.Fatal("event not received")
+	}
+}
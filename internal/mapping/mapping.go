@@ -0,0 +1,180 @@
+// Package mapping renders the payload of cloud events from the properties of a connection.
+//
+// A property value is one of
+//   - a JSON Pointer in URI fragment form, e.g. "#/body/temperature"
+//   - a JSONPath, e.g. "$.attachments[0].data.json.id"
+//   - a text/template with the message as data, e.g. "device {{.from}}", the result is a JSON string
+//
+// Pointers and paths keep the JSON type of the extracted value, values which are not in the message are null.
+package mapping
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/ohler55/ojg/jp"
+)
+
+var ErrInvalidMapping = errors.New("invalid mapping")
+
+type field interface {
+	value(message any, document any) (any, error)
+}
+
+// Mapping is the parsed properties of a connection
+type Mapping struct {
+	fields map[string]field
+}
+
+// Parse validates the properties, the error names every invalid property
+func Parse(properties map[string]string) (mapping *Mapping, err error) {
+	mapping = &Mapping{fields: make(map[string]field, len(properties))}
+	var errs []error
+	for key, expression := range properties {
+		f, err := parseField(expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("property %s: %w", key, err))
+			continue
+		}
+		mapping.fields[key] = f
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMapping, errors.Join(errs...))
+	}
+	return mapping, nil
+}
+
+func parseField(expression string) (f field, err error) {
+	// the parsers must not take the process down with a malformed expression
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	switch {
+	case expression == "#" || strings.HasPrefix(expression, "#/"):
+		pointer, err := jsonpointer.New(strings.TrimPrefix(expression, "#"))
+		if err != nil {
+			return nil, err
+		}
+		return pointerField{pointer: pointer}, nil
+	case expression == "$" || strings.HasPrefix(expression, "$.") || strings.HasPrefix(expression, "$["):
+		path, err := jp.ParseString(expression)
+		if err != nil {
+			return nil, err
+		}
+		return pathField{path: path}, nil
+	}
+	tmpl, err := template.New("property").Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return templateField{template: tmpl}, nil
+}
+
+// Render extracts the values of the properties from the message
+func (m *Mapping) Render(message any) (values map[string]any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rendering the mapping failed: %v", r)
+		}
+	}()
+	document, err := Document(message)
+	if err != nil {
+		return nil, err
+	}
+	values = make(map[string]any, len(m.fields))
+	for key, f := range m.fields {
+		value, err := f.value(message, document)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", key, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Document is the JSON form of the message which pointers and paths are evaluated on.
+// JSON attachments which are base64 encoded are decoded to data.json of the attachment.
+func Document(message any) (any, error) {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var document any
+	if err = json.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	if object, ok := document.(map[string]any); ok {
+		attachments, _ := object["attachments"].([]any)
+		for _, attachment := range attachments {
+			decodeAttachment(attachment)
+		}
+	}
+	return document, nil
+}
+
+func decodeAttachment(attachment any) {
+	object, _ := attachment.(map[string]any)
+	data, _ := object["data"].(map[string]any)
+	encoded, ok := data["base64"].(string)
+	if !ok || data["json"] != nil {
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if decoded, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+			return
+		}
+	}
+	var value any
+	if json.Unmarshal(decoded, &value) == nil {
+		data["json"] = value
+	}
+}
+
+type pointerField struct {
+	pointer jsonpointer.Pointer
+}
+
+func (f pointerField) value(_ any, document any) (any, error) {
+	value, _, err := f.pointer.Get(document)
+	if err != nil {
+		return nil, nil
+	}
+	return value, nil
+}
+
+type pathField struct {
+	path jp.Expr
+}
+
+// value is the single match of the path, an array of all matches or null
+func (f pathField) value(_ any, document any) (any, error) {
+	results := f.path.Get(document)
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	}
+	return results, nil
+}
+
+type templateField struct {
+	template *template.Template
+}
+
+func (f templateField) value(message any, _ any) (any, error) {
+	var result bytes.Buffer
+	if err := f.template.Execute(&result, message); err != nil {
+		return nil, err
+	}
+	return result.String(), nil
+}
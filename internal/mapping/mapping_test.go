@@ -0,0 +1,65 @@
+package mapping
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	_, err := Parse(map[string]string{
+		"literal":  "did:remotedid:example:67890fghijk#key-2",
+		"template": "device {{.from}}",
+		"pointer":  "#/body/temperature",
+		"path":     "$.attachments[*].id",
+	})
+	assert.Nil(t, err)
+
+	_, err = Parse(map[string]string{"broken": "{{.body", "path": "$.body[?(@.x =="})
+	assert.ErrorIs(t, err, ErrInvalidMapping)
+	assert.ErrorContains(t, err, "property broken")
+	assert.ErrorContains(t, err, "property path")
+}
+
+func TestRender(t *testing.T) {
+	attachment, _ := json.Marshal(map[string]any{"reading": 21.5})
+	message := map[string]interface{}{
+		"id":   "1",
+		"from": `did:peer:2."quoted"`,
+		"body": map[string]interface{}{"temperature": 21.5, "unit": "C"},
+		"attachments": []interface{}{
+			map[string]interface{}{"id": "a1", "data": map[string]interface{}{"base64": base64.StdEncoding.EncodeToString(attachment)}},
+			map[string]interface{}{"id": "a2", "data": map[string]interface{}{"json": map[string]interface{}{"reading": 22}}},
+		},
+	}
+	mapping, err := Parse(map[string]string{
+		"from":        "device {{.from}}",
+		"temperature": "#/body/temperature",
+		"missing":     "#/body/humidity",
+		"first":       "$.attachments[0].data.json.reading",
+		"ids":         "$.attachments[*].id",
+	})
+	assert.Nil(t, err)
+
+	values, err := mapping.Render(message)
+	assert.Nil(t, err)
+	assert.Equal(t, `device did:peer:2."quoted"`, values["from"])
+	assert.Equal(t, 21.5, values["temperature"])
+	assert.Nil(t, values["missing"])
+	assert.Equal(t, 21.5, values["first"])
+	assert.Equal(t, []any{"a1", "a2"}, values["ids"])
+
+	// the quotes of the message are escaped
+	raw, err := json.Marshal(values)
+	assert.Nil(t, err)
+	assert.True(t, json.Valid(raw))
+}
+
+func TestRender_TemplateError(t *testing.T) {
+	mapping, err := Parse(map[string]string{"call": "{{call .body}}"})
+	assert.Nil(t, err)
+	_, err = mapping.Render(map[string]interface{}{"body": "not a function"})
+	assert.NotNil(t, err)
+}
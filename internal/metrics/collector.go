@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DatabaseCollector exposes the pickup queues and the connections. They are read from the database by Run
+// in an interval and not on every scrape, since both are full scans of their tables.
+// Only the top recipients get gauges of their own, so the series are bounded however many connections there are.
+type DatabaseCollector struct {
+	database           database.Adapter
+	topRecipients      int
+	queueMessages      *prometheus.Desc
+	queueRecipients    *prometheus.Desc
+	queueOldestAge     *prometheus.Desc
+	recipientMessages  *prometheus.Desc
+	recipientOldestAge *prometheus.Desc
+	connections        *prometheus.Desc
+	lastRefreshAge     *prometheus.Desc
+
+	mu       sync.Mutex
+	snapshot databaseSnapshot
+}
+
+type databaseSnapshot struct {
+	messages   int
+	recipients int
+	// time the oldest parked message was added, zero if no message is parked
+	oldest time.Time
+	// deepest and oldest queues, at most topRecipients each
+	deepest      []database.MessageQueue
+	oldestQueues []database.MessageQueue
+	connections  map[string]int
+	// zero before the first successful refresh
+	refreshed time.Time
+}
+
+func NewDatabaseCollector(database database.Adapter, topRecipients int) *DatabaseCollector {
+	return &DatabaseCollector{
+		database:      database,
+		topRecipients: topRecipients,
+		queueMessages: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "pickup_queue_messages"),
+			"Messages parked for the pickup", nil, nil),
+		queueRecipients: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "pickup_queue_recipients"),
+			"Recipient DIDs with messages parked for the pickup", nil, nil),
+		queueOldestAge: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "pickup_queue_oldest_message_age_seconds"),
+			"Age of the oldest message parked for the pickup", nil, nil),
+		recipientMessages: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "pickup_queue_top_recipient_messages"),
+			"Messages parked for the recipients with the deepest pickup queues", []string{"recipient"}, nil),
+		recipientOldestAge: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "pickup_queue_top_recipient_oldest_message_age_seconds"),
+			"Age of the oldest message parked for the recipients with the oldest pickup queues", []string{"recipient"}, nil),
+		connections: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "connections"),
+			"Connections by group", []string{"group"}, nil),
+		lastRefreshAge: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "database_metrics_age_seconds"),
+			"Time since the pickup queues and connections were read from the database", nil, nil),
+	}
+}
+
+// Run refreshes the metrics in the interval until the context is done
+func (c *DatabaseCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh reads the pickup queues and the connections from the database, the former values are kept if it fails
+func (c *DatabaseCollector) Refresh() {
+	queues, err := c.database.GetMessageQueues()
+	if err != nil {
+		config.Logger.Warn("Unable to read pickup queues for metrics", "err", err)
+		return
+	}
+	mediatees, err := c.database.GetMediatees(nil)
+	if err != nil {
+		config.Logger.Warn("Unable to read connections for metrics", "err", err)
+		return
+	}
+	snapshot := databaseSnapshot{recipients: len(queues), connections: map[string]int{}, refreshed: time.Now()}
+	for _, queue := range queues {
+		snapshot.messages += queue.Count
+		if snapshot.oldest.IsZero() || queue.Oldest.Before(snapshot.oldest) {
+			snapshot.oldest = queue.Oldest
+		}
+	}
+	snapshot.deepest = topQueues(queues, c.topRecipients, func(a, b database.MessageQueue) bool { return a.Count > b.Count })
+	snapshot.oldestQueues = topQueues(queues, c.topRecipients, func(a, b database.MessageQueue) bool { return a.Oldest.Before(b.Oldest) })
+	for _, mediatee := range mediatees {
+		snapshot.connections[mediatee.Group]++
+	}
+	c.mu.Lock()
+	c.snapshot = snapshot
+	c.mu.Unlock()
+}
+
+// topQueues returns the first n queues in the order of less
+func topQueues(queues []database.MessageQueue, n int, less func(a, b database.MessageQueue) bool) []database.MessageQueue {
+	if n <= 0 {
+		return nil
+	}
+	sorted := slices.Clone(queues)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func (c *DatabaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueMessages
+	ch <- c.queueRecipients
+	ch <- c.queueOldestAge
+	ch <- c.recipientMessages
+	ch <- c.recipientOldestAge
+	ch <- c.connections
+	ch <- c.lastRefreshAge
+}
+
+func (c *DatabaseCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	snapshot := c.snapshot
+	c.mu.Unlock()
+	if snapshot.refreshed.IsZero() {
+		return
+	}
+	now := time.Now()
+	oldestAge := 0.0
+	if !snapshot.oldest.IsZero() {
+		oldestAge = now.Sub(snapshot.oldest).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(c.queueMessages, prometheus.GaugeValue, float64(snapshot.messages))
+	ch <- prometheus.MustNewConstMetric(c.queueRecipients, prometheus.GaugeValue, float64(snapshot.recipients))
+	ch <- prometheus.MustNewConstMetric(c.queueOldestAge, prometheus.GaugeValue, oldestAge)
+	// recipients which left the top are dropped with the next refresh, so no stale series are left behind
+	for _, queue := range snapshot.deepest {
+		ch <- prometheus.MustNewConstMetric(c.recipientMessages, prometheus.GaugeValue, float64(queue.Count), queue.RecipientDid)
+	}
+	for _, queue := range snapshot.oldestQueues {
+		ch <- prometheus.MustNewConstMetric(c.recipientOldestAge, prometheus.GaugeValue, now.Sub(queue.Oldest).Seconds(), queue.RecipientDid)
+	}
+	for group, count := range snapshot.connections {
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(count), group)
+	}
+	ch <- prometheus.MustNewConstMetric(c.lastRefreshAge, prometheus.GaugeValue, now.Sub(snapshot.refreshed).Seconds())
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseCollector(t *testing.T) {
+	config.Logger = slog.Default()
+	db := database.NewDemo()
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: "did:peer:2.a", Group: "sensors"}))
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: "did:peer:2.b", Group: "sensors"}))
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: "did:peer:2.c"}))
+	attachment := didcomm.Attachment{Data: didcomm.AttachmentDataBase64{Value: didcomm.Base64AttachmentData{Base64: "e30="}}}
+	// the oldest queue is not the deepest
+	assert.Nil(t, db.AddMessage("did:peer:2.other", attachment, "", time.Time{}))
+	assert.Nil(t, db.AddMessage("did:peer:2.recipient", attachment, "", time.Time{}))
+	assert.Nil(t, db.AddMessage("did:peer:2.recipient", attachment, "", time.Time{}))
+	assert.Nil(t, db.AddMessage("did:peer:2.recipient", attachment, "", time.Time{}))
+
+	registry := prometheus.NewRegistry()
+	collector := NewDatabaseCollector(db, 1)
+	assert.Nil(t, registry.Register(collector))
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Empty(t, families)
+
+	collector.Refresh()
+	families, err = registry.Gather()
+	assert.Nil(t, err)
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += "/" + label.GetValue()
+			}
+			values[name] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, 2.0, values["didcomm_connector_connections/sensors"])
+	assert.Equal(t, 1.0, values["didcomm_connector_connections/"])
+	assert.Equal(t, 4.0, values["didcomm_connector_pickup_queue_messages"])
+	assert.Equal(t, 2.0, values["didcomm_connector_pickup_queue_recipients"])
+	assert.Equal(t, 3.0, values["didcomm_connector_pickup_queue_top_recipient_messages/did:peer:2.recipient"])
+	assert.NotContains(t, values, "didcomm_connector_pickup_queue_top_recipient_messages/did:peer:2.other")
+	assert.Contains(t, values, "didcomm_connector_pickup_queue_top_recipient_oldest_message_age_seconds/did:peer:2.other")
+	assert.NotContains(t, values, "didcomm_connector_pickup_queue_top_recipient_oldest_message_age_seconds/did:peer:2.recipient")
+	assert.Greater(t, values["didcomm_connector_pickup_queue_oldest_message_age_seconds"], 0.0)
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "didcomm_connector"
+
+// results of an operation
+const (
+	RESULT_SUCCESS = "success"
+	RESULT_FAILURE = "failure"
+)
+
+// results of a resolver cache lookup
+const (
+	CACHE_HIT          = "hit"
+	CACHE_NEGATIVE_HIT = "negative_hit"
+	CACHE_MISS         = "miss"
+)
+
+var (
+	InboundMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "inbound_messages_total",
+		Help:      "DIDComm messages received from devices by protocol and message type",
+	}, []string{"protocol", "type"})
+
+	// operation is pack_encrypted, pack_plain, pack_signed or unpack
+	PackDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "pack_duration_seconds",
+		Help:      "Duration of packing and unpacking DIDComm messages",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	ProblemReports = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "problem_reports_total",
+		Help:      "Problem reports sent to devices by code",
+	}, []string{"code"})
+
+	CloudPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "cloud_events_published_total",
+		Help:      "Publishing attempts of cloud events by messaging protocol and result",
+	}, []string{"protocol", "result"})
+
+	ResolveDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "resolver_duration_seconds",
+		Help:      "Duration of DID resolutions by DID method and result, cache hits are not included",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	ResolverCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "resolver_cache_lookups_total",
+		Help:      "Lookups of the DID resolver cache by result",
+	}, []string{"result"})
+
+	HttpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route, method and status code",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+)
+
+// Observe records the duration since start in the histogram with the result of err
+func Observe(histogram *prometheus.HistogramVec, start time.Time, err error, labels ...string) {
+	histogram.WithLabelValues(append(labels, Result(err))...).Observe(time.Since(start).Seconds())
+}
+
+func Result(err error) string {
+	if err != nil {
+		return RESULT_FAILURE
+	}
+	return RESULT_SUCCESS
+}
+
+// Middleware records the duration of the requests per route, requests without a route share the route "unmatched"
+func Middleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		start := time.Now()
+		context.Next()
+		route := context.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HttpDuration.WithLabelValues(route, context.Request.Method, strconv.Itoa(context.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves /metrics on the separate metrics listener
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
@@ -0,0 +1,230 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudevents/sdk-go/protocol/mqtt_paho/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/google/uuid"
+)
+
+type Options struct {
+	Url string
+	// ClientId is extended by a random suffix, every connection needs its own id
+	ClientId string
+	// SharedGroup subscribes to $share/<group>/<topic>, the broker delivers each event to one member
+	SharedGroup string
+
+	Qos            byte
+	Retain         bool
+	RetainHandling byte
+
+	KeepAlive      time.Duration
+	ReconnectDelay time.Duration
+	ConnectTimeout time.Duration
+
+	User     string
+	Password string
+
+	CaFile   string
+	CertFile string
+	KeyFile  string
+
+	Logger *slog.Logger
+}
+
+// Client publishes cloud events to or consumes them from one MQTT topic. The connection is
+// reestablished after a loss and subscriptions are renewed.
+type Client struct {
+	opts  Options
+	topic string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	connection *autopaho.ConnectionManager
+	// a subscriber is connected once its subscription is renewed after the connection is up
+	connected atomic.Bool
+}
+
+// NewPublisher connects to the broker and waits until the connection is up
+func NewPublisher(opts Options, topic string) (*Client, error) {
+	c := newClient(opts, topic)
+	connection, err := c.connect(nil)
+	if err != nil {
+		c.cancel()
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, c.opts.ConnectTimeout)
+	defer cancel()
+	if err = connection.AwaitConnection(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("mqtt broker %s not reachable: %w", opts.Url, err)
+	}
+	return c, nil
+}
+
+// NewSubscriber creates a client which connects with the first call of Sub
+func NewSubscriber(opts Options, topic string) (*Client, error) {
+	if _, err := url.Parse(opts.Url); err != nil {
+		return nil, err
+	}
+	return newClient(opts, topic), nil
+}
+
+func newClient(opts Options, topic string) *Client {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.ConnectTimeout == 0 {
+		opts.ConnectTimeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{opts: opts, topic: topic, ctx: ctx, cancel: cancel}
+}
+
+// Pub sends the event and waits for the acknowledgement of the broker if the QoS asks for one
+func (c *Client) Pub(event event.Event) error {
+	publish := &paho.Publish{
+		Topic:  c.topic,
+		QoS:    c.opts.Qos,
+		Retain: c.opts.Retain,
+	}
+	if err := mqtt_paho.WritePubMessage(c.ctx, binding.ToMessage(&event), publish); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	connection := c.connection
+	c.mu.Unlock()
+	if connection == nil {
+		return errors.New("mqtt client is not connected")
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, c.opts.ConnectTimeout)
+	defer cancel()
+	_, err := connection.Publish(ctx, publish)
+	return err
+}
+
+// Sub handles the events of the topic until the client is closed
+func (c *Client) Sub(fn func(event event.Event)) error {
+	_, err := c.connect(func(p *paho.Publish) {
+		e, err := binding.ToEvent(c.ctx, mqtt_paho.NewMessage(p))
+		if err != nil {
+			c.opts.Logger.Error("Received MQTT message is no cloud event", "topic", p.Topic, "err", err)
+			return
+		}
+		fn(*e)
+	})
+	if err != nil {
+		return err
+	}
+	<-c.ctx.Done()
+	return nil
+}
+
+// Connected reports whether the connection to the broker is up and the topic is subscribed
+func (c *Client) Connected() bool {
+	return c.connected.Load()
+}
+
+func (c *Client) Close() error {
+	c.connected.Store(false)
+	c.mu.Lock()
+	connection := c.connection
+	c.connection = nil
+	c.mu.Unlock()
+	defer c.cancel()
+	if connection == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.ConnectTimeout)
+	defer cancel()
+	return connection.Disconnect(ctx)
+}
+
+func (c *Client) connect(handler paho.MessageHandler) (*autopaho.ConnectionManager, error) {
+	brokerUrl, err := url.Parse(c.opts.Url)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig := autopaho.ClientConfig{
+		BrokerUrls:        []*url.URL{brokerUrl},
+		KeepAlive:         uint16(c.opts.KeepAlive.Seconds()),
+		ConnectRetryDelay: c.opts.ReconnectDelay,
+		ConnectTimeout:    c.opts.ConnectTimeout,
+		OnConnectError: func(err error) {
+			c.connected.Store(false)
+			c.opts.Logger.Warn("MQTT connection failed, retrying", "url", c.opts.Url, "err", err)
+		},
+		OnConnectionUp: func(*autopaho.ConnectionManager, *paho.Connack) {
+			c.connected.Store(true)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: c.opts.ClientId + "-" + strings.Split(uuid.NewString(), "-")[0],
+			OnClientError: func(err error) {
+				c.connected.Store(false)
+				c.opts.Logger.Warn("MQTT connection lost", "url", c.opts.Url, "err", err)
+			},
+			OnServerDisconnect: func(disconnect *paho.Disconnect) {
+				c.connected.Store(false)
+				c.opts.Logger.Warn("MQTT broker closed the connection", "url", c.opts.Url, "reason", disconnect.ReasonCode)
+			},
+		},
+	}
+	switch strings.ToLower(brokerUrl.Scheme) {
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps", "wss":
+		if clientConfig.TlsCfg, err = transport.NewClientTLSConfig(c.opts.CaFile, c.opts.CertFile, c.opts.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts.User != "" {
+		clientConfig.SetUsernamePassword(c.opts.User, []byte(c.opts.Password))
+	}
+	if handler != nil {
+		clientConfig.Router = paho.NewSingleHandlerRouter(handler)
+		// subscriptions of a clean session are gone after a reconnect
+		clientConfig.OnConnectionUp = func(connection *autopaho.ConnectionManager, _ *paho.Connack) {
+			_, err := connection.Subscribe(c.ctx, &paho.Subscribe{
+				Subscriptions: []paho.SubscribeOptions{{
+					Topic:          SubscriptionTopic(c.opts.SharedGroup, c.topic),
+					QoS:            c.opts.Qos,
+					RetainHandling: c.opts.RetainHandling,
+				}},
+			})
+			if err != nil {
+				c.opts.Logger.Error("MQTT subscription failed", "topic", c.topic, "err", err)
+				return
+			}
+			c.connected.Store(true)
+		}
+	}
+	connection, err := autopaho.NewConnection(c.ctx, clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.connection = connection
+	c.mu.Unlock()
+	return connection, nil
+}
+
+// SubscriptionTopic returns the shared subscription of the group or the topic itself without a group
+func SubscriptionTopic(group string, topic string) string {
+	if group == "" {
+		return topic
+	}
+	return "$share/" + group + "/" + topic
+}
@@ -0,0 +1,61 @@
+package mqtt
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionTopic(t *testing.T) {
+	assert.Equal(t, "didcomm/messages", SubscriptionTopic("", "didcomm/messages"))
+	assert.Equal(t, "$share/connector/didcomm/messages", SubscriptionTopic("connector", "didcomm/messages"))
+}
+
+func TestNewPublisher_UnreachableBroker(t *testing.T) {
+	_, err := NewPublisher(Options{Url: "mqtt://127.0.0.1:1", ClientId: "test", ConnectTimeout: 200 * time.Millisecond}, "test")
+	assert.NotNil(t, err)
+}
+
+// TestClient_LocalBroker runs against the broker of the docker compose file:
+// DIDCOMMCONNECTOR_TEST_MQTT_URL=mqtt://localhost:1883 go test ./internal/mqtt
+func TestClient_LocalBroker(t *testing.T) {
+	brokerUrl := os.Getenv("DIDCOMMCONNECTOR_TEST_MQTT_URL")
+	if brokerUrl == "" {
+		t.Skip("DIDCOMMCONNECTOR_TEST_MQTT_URL not set")
+	}
+	opts := Options{Url: brokerUrl, ClientId: "test", Qos: 1, RetainHandling: 1, ReconnectDelay: time.Second}
+	topic := "test/" + uuid.NewString()
+
+	subscriber, err := NewSubscriber(opts, topic)
+	assert.Nil(t, err)
+	received := make(chan event.Event, 1)
+	go subscriber.Sub(func(e event.Event) {
+		received <- e
+	})
+	defer subscriber.Close()
+	// the subscription is made once the connection is up
+	time.Sleep(time.Second)
+
+	publisher, err := NewPublisher(opts, topic)
+	assert.Nil(t, err)
+	defer publisher.Close()
+
+	sent := cloudevents.NewEvent()
+	sent.SetID(uuid.NewString())
+	sent.SetSource("test")
+	sent.SetType("test")
+	assert.Nil(t, sent.SetData(cloudevents.ApplicationJSON, map[string]string{"hello": "mqtt"}))
+	assert.Nil(t, publisher.Pub(sent))
+
+	select {
+	case e := <-received:
+		assert.Equal(t, sent.ID(), e.ID())
+	case <-time.After(30 * time.Second):
+		t.Fatal("event not received")
+	}
+}
@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/extensions"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER = "github.com/eclipse-xfsc/didcomm-v2-connector"
+
+// Init installs the W3C trace context propagator and the tracer provider of the configuration.
+// Without exporter the global no-op provider stays in place. The returned function flushes the spans on shutdown.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	tracing := config.CurrentConfiguration.Tracing
+	if tracing.Exporter != config.TRACING_EXPORTER_OTLP {
+		return func(context.Context) error { return nil }, nil
+	}
+	options := []otlptracehttp.Option{}
+	if tracing.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(tracing.Endpoint))
+	}
+	if tracing.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", tracing.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(TRACER).Start(ctx, name, options...)
+}
+
+// End marks the span as failed if err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Database runs a query of the database adapter in a child span of ctx, the adapter itself has no context
+func Database(ctx context.Context, operation string, query func() error) error {
+	_, span := Start(ctx, "db "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.operation", operation)))
+	err := query()
+	End(span, err)
+	return err
+}
+
+// Query is Database for queries with a result
+func Query[T any](ctx context.Context, operation string, query func() (T, error)) (result T, err error) {
+	err = Database(ctx, operation, func() (err error) {
+		result, err = query()
+		return err
+	})
+	return result, err
+}
+
+// awaiting holds the spans which wait for callbacks of the didcomm library by key, e.g. by the DIDs it resolves.
+// The library calls back without context, so the callbacks only find their message through the key.
+var awaiting = struct {
+	sync.Mutex
+	spans map[string][]*trace.SpanContext
+}{spans: map[string][]*trace.SpanContext{}}
+
+// Await registers the span of ctx for the callbacks about the keys, the returned function removes it
+// once the call of the library returned
+func Await(ctx context.Context, keys ...string) func() {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || len(keys) == 0 {
+		return func() {}
+	}
+	// a span is linked once per key
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	awaiting.Lock()
+	defer awaiting.Unlock()
+	for _, key := range keys {
+		awaiting.spans[key] = append(awaiting.spans[key], &spanContext)
+	}
+	return func() {
+		awaiting.Lock()
+		defer awaiting.Unlock()
+		for _, key := range keys {
+			spans := awaiting.spans[key]
+			for i, span := range spans {
+				if span == &spanContext {
+					spans = append(spans[:i:i], spans[i+1:]...)
+					break
+				}
+			}
+			if len(spans) == 0 {
+				delete(awaiting.spans, key)
+			} else {
+				awaiting.spans[key] = spans
+			}
+		}
+	}
+}
+
+// Links returns links to the spans which wait for a callback about the key. Concurrent messages with the same
+// key are all linked, the callback does not tell which of them it serves.
+func Links(key string) []trace.Link {
+	awaiting.Lock()
+	defer awaiting.Unlock()
+	links := []trace.Link{}
+	for _, span := range awaiting.spans[key] {
+		links = append(links, trace.Link{SpanContext: *span})
+	}
+	return links
+}
+
+// TraceContext returns the W3C traceparent and tracestate of the span of ctx, empty without span
+func TraceContext(ctx context.Context) (traceParent string, traceState string) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.Get(extensions.TraceParentExtension), carrier.Get(extensions.TraceStateExtension)
+}
+
+// WithTraceContext returns ctx with the remote span of the W3C traceparent and tracestate
+func WithTraceContext(ctx context.Context, traceParent string, traceState string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{extensions.TraceParentExtension: traceParent}
+	if traceState != "" {
+		carrier[extensions.TraceStateExtension] = traceState
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Inject sets the trace context of ctx as distributed tracing extension of the cloud event
+func Inject(ctx context.Context, e *event.Event) {
+	traceParent, traceState := TraceContext(ctx)
+	extensions.DistributedTracingExtension{TraceParent: traceParent, TraceState: traceState}.AddTracingAttributes(e)
+}
+
+// Extract returns ctx with the trace context of the distributed tracing extension of the cloud event
+func Extract(ctx context.Context, e event.Event) context.Context {
+	tracingExtension, ok := extensions.GetDistributedTracingExtension(e)
+	if !ok {
+		return ctx
+	}
+	return WithTraceContext(ctx, tracingExtension.TraceParent, tracingExtension.TraceState)
+}
+
+// Middleware starts a server span per request which continues the trace of the traceparent header
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := Start(ctx, c.Request.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("http.method", c.Request.Method), attribute.String("http.route", route)))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCloudEventPropagation(t *testing.T) {
+	config.CurrentConfiguration.Tracing.Exporter = config.TRACING_EXPORTER_NONE
+	shutdown, err := Init(context.Background())
+	assert.Nil(t, err)
+	defer shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// events without extension keep the context
+	event := cloudevents.NewEvent()
+	assert.False(t, trace.SpanContextFromContext(Extract(context.Background(), event)).IsValid())
+
+	ctx, span := Start(context.Background(), "HandleMessage")
+	traceParent, _ := TraceContext(ctx)
+	assert.Contains(t, traceParent, span.SpanContext().TraceID().String())
+
+	// the trace context survives the outbox and the cloud event
+	stored := WithTraceContext(context.Background(), traceParent, "")
+	Inject(stored, &event)
+	assert.Equal(t, traceParent, event.Extensions()["traceparent"])
+
+	_, consumer := Start(Extract(context.Background(), event), "forwardCloudEvent")
+	End(consumer, nil)
+	End(span, nil)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, span.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestDatabase(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, span := Start(context.Background(), "HandleMessage")
+	isMediated, err := Query(ctx, "IsMediated", func() (bool, error) { return true, nil })
+	assert.Nil(t, err)
+	assert.True(t, isMediated)
+	err = Database(ctx, "AddMessage", func() error { return errors.New("unavailable") })
+	assert.EqualError(t, err, "unavailable")
+	End(span, nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "db IsMediated", spans[0].Name())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, "db AddMessage", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestAwait(t *testing.T) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	// without span there is nothing to link
+	Await(context.Background(), "did:peer:2.a")()
+	assert.Empty(t, Links("did:peer:2.a"))
+
+	first, firstSpan := Start(context.Background(), "UnpackMessage")
+	second, secondSpan := Start(context.Background(), "UnpackMessage")
+	releaseFirst := Await(first, "did:peer:2.a", "did:peer:2.b", "did:peer:2.a")
+	releaseSecond := Await(second, "did:peer:2.a")
+
+	links := Links("did:peer:2.a")
+	assert.Len(t, links, 2)
+	assert.Equal(t, firstSpan.SpanContext(), links[0].SpanContext)
+	assert.Equal(t, secondSpan.SpanContext(), links[1].SpanContext)
+	assert.Len(t, Links("did:peer:2.b"), 1)
+
+	releaseFirst()
+	links = Links("did:peer:2.a")
+	assert.Len(t, links, 1)
+	assert.Equal(t, secondSpan.SpanContext(), links[0].SpanContext)
+	assert.Empty(t, Links("did:peer:2.b"))
+	releaseSecond()
+	assert.Empty(t, Links("did:peer:2.a"))
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type ClientOptions struct {
+	CaFile   string
+	CertFile string
+	KeyFile  string
+	Timeout  time.Duration
+}
+
+var (
+	clientMu sync.RWMutex
+	client   = &http.Client{}
+)
+
+// ConfigureClient replaces the shared client for outbound calls to other connectors and the resolver
+func ConfigureClient(opts ClientOptions) error {
+	c, err := NewClient(opts)
+	if err != nil {
+		return err
+	}
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	client = c
+	return nil
+}
+
+// Client returns the shared client for outbound calls
+func Client() *http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return client
+}
+
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	tlsConfig, err := NewClientTLSConfig(opts.CaFile, opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}, nil
+}
+
+// NewClientTLSConfig creates a TLS config which trusts the CA file in addition to the system pool
+// and presents a client certificate, reloaded on change, if a key pair is given
+func NewClientTLSConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile, true)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		reloader, err := newCertificateReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return tlsConfig, nil
+}
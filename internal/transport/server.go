@@ -0,0 +1,28 @@
+package transport
+
+import (
+	"crypto/tls"
+)
+
+// NewServerTLSConfig creates a TLS config which reloads the server certificate on change.
+// If a client CA file is given, client certificates are requested and verified against it
+// but not required, the decision is left to the handlers (see VerifiedChains).
+func NewServerTLSConfig(certFile string, keyFile string, clientCaFile string) (*tls.Config, error) {
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCaFile != "" {
+		pool, err := loadCertPool(clientCaFile, false)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
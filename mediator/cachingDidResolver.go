@@ -0,0 +1,203 @@
+package mediator
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+)
+
+// CachingDidResolver caches the results of another resolver.
+// Entries expire after the ttl, did:peer documents never change and are only removed by the size limit.
+// Failed resolutions are cached for the negative ttl, except for did:peer which is resolved locally.
+type CachingDidResolver struct {
+	resolver    DidResolver
+	maxEntries  int
+	ttl         time.Duration
+	negativeTtl time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// least recently used entries are at the back
+	lru   *list.List
+	stats CacheStats
+}
+
+type CacheStats struct {
+	Entries      int     `json:"entries"`
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negativeHits"`
+	Misses       uint64  `json:"misses"`
+	Evictions    uint64  `json:"evictions"`
+	HitRate      float64 `json:"hitRate"`
+}
+
+type cacheEntry struct {
+	did     string
+	doc     *didcomm.DidDoc
+	docJson *DidDocumentJSON
+	err     error
+	// zero for entries which do not expire
+	expires time.Time
+}
+
+func NewCachingDidResolver(resolver DidResolver, maxEntries int, ttl time.Duration, negativeTtl time.Duration) *CachingDidResolver {
+	return &CachingDidResolver{
+		resolver:    resolver,
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		negativeTtl: negativeTtl,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+func (c *CachingDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	didDoc, err := c.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+func (c *CachingDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	return c.ResolveDidContext(context.Background(), did)
+}
+
+// ResolveDidContext passes ctx to the underlying resolver on a cache miss
+func (c *CachingDidResolver) ResolveDidContext(ctx context.Context, did string) (*didcomm.DidDoc, error) {
+	if entry, ok := c.lookup(did, func(e *cacheEntry) bool { return e.doc != nil }); ok {
+		return entry.doc, entry.err
+	}
+	doc, err := ResolveDidContext(ctx, c.resolver, did)
+	c.store(did, err, func(e *cacheEntry) { e.doc = doc })
+	return doc, err
+}
+
+func (c *CachingDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	return c.ResolveDidAsJsonContext(context.Background(), did)
+}
+
+// ResolveDidAsJsonContext passes ctx to the underlying resolver on a cache miss
+func (c *CachingDidResolver) ResolveDidAsJsonContext(ctx context.Context, did string) (*DidDocumentJSON, error) {
+	if entry, ok := c.lookup(did, func(e *cacheEntry) bool { return e.docJson != nil }); ok {
+		return entry.docJson, entry.err
+	}
+	docJson, err := ResolveDidAsJsonContext(ctx, c.resolver, did)
+	c.store(did, err, func(e *cacheEntry) { e.docJson = docJson })
+	return docJson, err
+}
+
+// AddLocalDocument passes the document to the underlying resolver and drops a cached entry of the DID
+func (c *CachingDidResolver) AddLocalDocument(doc DidDocument) {
+	if local, ok := c.resolver.(interface{ AddLocalDocument(DidDocument) }); ok {
+		local.AddLocalDocument(doc)
+	}
+	c.FlushDid(doc.ID)
+}
+
+// Flush removes all entries
+func (c *CachingDidResolver) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// FlushDid removes the entry of the DID and reports whether it was cached
+func (c *CachingDidResolver) FlushDid(did string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[did]
+	if ok {
+		c.remove(element)
+	}
+	return ok
+}
+
+func (c *CachingDidResolver) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+	return stats
+}
+
+// lookup returns a copy of the entry if it is a failed resolution or contains the requested representation
+func (c *CachingDidResolver) lookup(did string, has func(e *cacheEntry) bool) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[did]
+	if !ok {
+		c.stats.Misses++
+		metrics.ResolverCache.WithLabelValues(metrics.CACHE_MISS).Inc()
+		return cacheEntry{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.remove(element)
+		c.stats.Misses++
+		metrics.ResolverCache.WithLabelValues(metrics.CACHE_MISS).Inc()
+		return cacheEntry{}, false
+	}
+	if entry.err != nil {
+		c.lru.MoveToFront(element)
+		c.stats.NegativeHits++
+		metrics.ResolverCache.WithLabelValues(metrics.CACHE_NEGATIVE_HIT).Inc()
+		return *entry, true
+	}
+	if !has(entry) {
+		c.stats.Misses++
+		metrics.ResolverCache.WithLabelValues(metrics.CACHE_MISS).Inc()
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(element)
+	c.stats.Hits++
+	metrics.ResolverCache.WithLabelValues(metrics.CACHE_HIT).Inc()
+	return *entry, true
+}
+
+func (c *CachingDidResolver) store(did string, err error, fill func(e *cacheEntry)) {
+	isPeer := strings.HasPrefix(did, "did:peer:")
+	if err != nil && (isPeer || c.negativeTtl <= 0) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[did]
+	// a successful resolution is added to the valid entry of the other representation
+	if ok && err == nil && element.Value.(*cacheEntry).err == nil {
+		fill(element.Value.(*cacheEntry))
+		c.lru.MoveToFront(element)
+		return
+	}
+	if ok {
+		c.remove(element)
+	}
+	entry := &cacheEntry{did: did, err: err}
+	switch {
+	case err != nil:
+		entry.expires = c.now().Add(c.negativeTtl)
+	case !isPeer:
+		entry.expires = c.now().Add(c.ttl)
+	}
+	if err == nil {
+		fill(entry)
+	}
+	c.entries[did] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *CachingDidResolver) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).did)
+}
@@ -0,0 +1,119 @@
+package mediator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingResolver struct {
+	calls map[string]int
+	fail  bool
+}
+
+func (r *countingResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	return didcomm.ErrorCodeSuccess
+}
+
+func (r *countingResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	r.calls[did]++
+	if r.fail {
+		return nil, errors.New("not found")
+	}
+	return &didcomm.DidDoc{Id: did}, nil
+}
+
+func (r *countingResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	r.calls[did]++
+	if r.fail {
+		return nil, errors.New("not found")
+	}
+	return &DidDocumentJSON{DidDocument: DidDocument{ID: did}}, nil
+}
+
+func TestCachingDidResolver_Ttl(t *testing.T) {
+	inner := &countingResolver{calls: map[string]int{}}
+	cache := NewCachingDidResolver(inner, 10, time.Minute, time.Second)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	webDid := "did:web:example.com"
+	peerDid := "did:peer:0z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	for i := 0; i < 3; i++ {
+		_, err := cache.ResolveDid(webDid)
+		assert.Nil(t, err)
+		_, err = cache.ResolveDid(peerDid)
+		assert.Nil(t, err)
+	}
+	// the other representation is resolved once as well
+	_, err := cache.ResolveDidAsJson(webDid)
+	assert.Nil(t, err)
+	_, err = cache.ResolveDidAsJson(webDid)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.calls[webDid])
+	assert.Equal(t, 1, inner.calls[peerDid])
+
+	now = now.Add(2 * time.Minute)
+	cache.ResolveDid(webDid)
+	cache.ResolveDid(peerDid)
+	assert.Equal(t, 3, inner.calls[webDid])
+	assert.Equal(t, 1, inner.calls[peerDid])
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(6), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+
+	assert.True(t, cache.FlushDid(peerDid))
+	assert.False(t, cache.FlushDid(peerDid))
+	cache.ResolveDid(peerDid)
+	assert.Equal(t, 2, inner.calls[peerDid])
+}
+
+func TestCachingDidResolver_NegativeCaching(t *testing.T) {
+	inner := &countingResolver{calls: map[string]int{}, fail: true}
+	cache := NewCachingDidResolver(inner, 10, time.Minute, time.Second)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	did := "did:web:unknown.example.com"
+	_, err := cache.ResolveDid(did)
+	assert.NotNil(t, err)
+	_, err = cache.ResolveDidAsJson(did)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, inner.calls[did])
+	assert.Equal(t, uint64(1), cache.Stats().NegativeHits)
+
+	inner.fail = false
+	now = now.Add(2 * time.Second)
+	_, err = cache.ResolveDid(did)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.calls[did])
+
+	// failed did:peer resolutions are not cached
+	inner.fail = true
+	cache.ResolveDid("did:peer:4unknown")
+	cache.ResolveDid("did:peer:4unknown")
+	assert.Equal(t, 2, inner.calls["did:peer:4unknown"])
+}
+
+func TestCachingDidResolver_SizeLimit(t *testing.T) {
+	inner := &countingResolver{calls: map[string]int{}}
+	cache := NewCachingDidResolver(inner, 2, time.Minute, time.Second)
+
+	cache.ResolveDid("did:web:a")
+	cache.ResolveDid("did:web:b")
+	cache.ResolveDid("did:web:a")
+	cache.ResolveDid("did:web:c")
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	// b was least recently used
+	assert.False(t, cache.FlushDid("did:web:b"))
+	assert.True(t, cache.FlushDid("did:web:a"))
+}
@@ -0,0 +1,37 @@
+package callback
+
+import "github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+
+type PackSignedErrorPair struct {
+	Err *didcomm.ErrorKind
+	Msg string
+}
+
+type PackSignedSuccessPair struct {
+	Result   string
+	Metadata didcomm.PackSignedMetadata
+}
+
+type PackSignedResultCallback struct {
+	sucCh chan<- PackSignedSuccessPair
+	errCh chan<- PackSignedErrorPair
+}
+
+func NewPackSignedResultCallback(sucCh chan<- PackSignedSuccessPair, errCh chan<- PackSignedErrorPair) *PackSignedResultCallback {
+	return &PackSignedResultCallback{
+		sucCh: sucCh,
+		errCh: errCh,
+	}
+}
+
+func (m *PackSignedResultCallback) Success(result string, metadata didcomm.PackSignedMetadata) {
+	m.sucCh <- PackSignedSuccessPair{result, metadata}
+	close(m.sucCh)
+	close(m.errCh)
+}
+
+func (m *PackSignedResultCallback) Error(err *didcomm.ErrorKind, msg string) {
+	m.errCh <- PackSignedErrorPair{err, msg}
+	close(m.errCh)
+	close(m.sucCh)
+}
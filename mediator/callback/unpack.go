@@ -7,12 +7,17 @@ type UnpackErrorPair struct {
 	Msg string
 }
 
+type UnpackSuccessPair struct {
+	Message  didcomm.Message
+	Metadata didcomm.UnpackMetadata
+}
+
 type UnpackResultCallback struct {
-	msgCh chan<- didcomm.Message
+	msgCh chan<- UnpackSuccessPair
 	errCh chan<- UnpackErrorPair
 }
 
-func NewUnpackResultCallback(msgCh chan<- didcomm.Message, errCh chan<- UnpackErrorPair) *UnpackResultCallback {
+func NewUnpackResultCallback(msgCh chan<- UnpackSuccessPair, errCh chan<- UnpackErrorPair) *UnpackResultCallback {
 	return &UnpackResultCallback{
 		msgCh: msgCh,
 		errCh: errCh,
@@ -20,7 +25,7 @@ func NewUnpackResultCallback(msgCh chan<- didcomm.Message, errCh chan<- UnpackEr
 }
 
 func (m *UnpackResultCallback) Success(result didcomm.Message, metadata didcomm.UnpackMetadata) {
-	m.msgCh <- result
+	m.msgCh <- UnpackSuccessPair{result, metadata}
 	close(m.msgCh)
 	close(m.errCh)
 }
@@ -0,0 +1,37 @@
+package callback
+
+import "github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+
+type UnpackFromPriorErrorPair struct {
+	Err *didcomm.ErrorKind
+	Msg string
+}
+
+type UnpackFromPriorSuccessPair struct {
+	FromPrior didcomm.FromPrior
+	Kid       string
+}
+
+type UnpackFromPriorResultCallback struct {
+	sucCh chan<- UnpackFromPriorSuccessPair
+	errCh chan<- UnpackFromPriorErrorPair
+}
+
+func NewUnpackFromPriorResultCallback(sucCh chan<- UnpackFromPriorSuccessPair, errCh chan<- UnpackFromPriorErrorPair) *UnpackFromPriorResultCallback {
+	return &UnpackFromPriorResultCallback{
+		sucCh: sucCh,
+		errCh: errCh,
+	}
+}
+
+func (m *UnpackFromPriorResultCallback) Success(fromPrior didcomm.FromPrior, kid string) {
+	m.sucCh <- UnpackFromPriorSuccessPair{fromPrior, kid}
+	close(m.sucCh)
+	close(m.errCh)
+}
+
+func (m *UnpackFromPriorResultCallback) Error(err *didcomm.ErrorKind, msg string) {
+	m.errCh <- UnpackFromPriorErrorPair{err, msg}
+	close(m.errCh)
+	close(m.sucCh)
+}
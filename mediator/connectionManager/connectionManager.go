@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/mapping"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/constants"
 )
@@ -28,8 +31,9 @@ func NewConnectionManager(database database.Adapter) *ConnectionManager {
 var ERROR_PROTOCOL_NOT_SUPPORTED = errors.New("protocol not supported")
 var ERROR_INTERNAL = errors.New("internal error")
 var ERROR_CONNECTION_ALREADY_EXISTS = errors.New("connection already exists")
+var ERROR_INVALID_PROPERTIES = errors.New("invalid properties")
 
-func (c *ConnectionManager) StoreConnection(protocol string, remoteDid string, topic string, properties map[string]string, eventType string, recipients []string, group string) (err error) {
+func (c *ConnectionManager) StoreConnection(protocol string, remoteDid string, topic string, properties map[string]string, eventType string, recipients []string, group string, dataSchema string) (err error) {
 	switch config.CurrentConfiguration.CloudForwarding.Protocol {
 	case config.HTTP:
 		if protocol != config.HTTP {
@@ -39,6 +43,14 @@ func (c *ConnectionManager) StoreConnection(protocol string, remoteDid string, t
 		if protocol != config.NATS {
 			return ERROR_PROTOCOL_NOT_SUPPORTED
 		}
+	case config.KAFKA:
+		if protocol != config.KAFKA {
+			return ERROR_PROTOCOL_NOT_SUPPORTED
+		}
+	case config.MQTT:
+		if protocol != config.MQTT {
+			return ERROR_PROTOCOL_NOT_SUPPORTED
+		}
 	case "hybrid":
 		if protocol != config.HTTP && protocol != config.NATS {
 			return ERROR_PROTOCOL_NOT_SUPPORTED
@@ -46,6 +58,9 @@ func (c *ConnectionManager) StoreConnection(protocol string, remoteDid string, t
 	default:
 		return ERROR_PROTOCOL_NOT_SUPPORTED
 	}
+	if err = ValidateMapping(properties, dataSchema); err != nil {
+		return err
+	}
 	isMediated, err := c.database.IsMediated(remoteDid)
 	if err != nil {
 		return ERROR_INTERNAL
@@ -53,13 +68,27 @@ func (c *ConnectionManager) StoreConnection(protocol string, remoteDid string, t
 	if isMediated {
 		return ERROR_CONNECTION_ALREADY_EXISTS
 	}
-	err = c.database.AddMediatee(database.Mediatee{RemoteDid: remoteDid, Protocol: protocol, Topic: topic, EventType: eventType, Properties: properties, RecipientDids: recipients, Group: group})
+	err = c.database.AddMediatee(database.Mediatee{RemoteDid: remoteDid, Protocol: protocol, Topic: topic, EventType: eventType, Properties: properties, RecipientDids: recipients, Group: group, DataSchema: dataSchema})
 	if err != nil {
 		return ERROR_INTERNAL
 	}
 	return nil
 }
 
+// ValidateMapping checks that the properties are a valid mapping of messages to cloud events
+// and that the schema of the mapped payload is an absolute URI
+func ValidateMapping(properties map[string]string, dataSchema string) error {
+	if _, err := mapping.Parse(properties); err != nil {
+		return fmt.Errorf("%w: %w", ERROR_INVALID_PROPERTIES, err)
+	}
+	if dataSchema != "" {
+		if uri, err := url.Parse(dataSchema); err != nil || !uri.IsAbs() {
+			return fmt.Errorf("%w: dataSchema %s is no absolute URI", ERROR_INVALID_PROPERTIES, dataSchema)
+		}
+	}
+	return nil
+}
+
 func (c *ConnectionManager) Connect(host string, mediatorPeerDid string, peerdid string, bearer string) (string, error) {
 
 	var mediatonRequest = make(map[string]interface{})
@@ -121,8 +150,7 @@ func GetHttpResult(input interface{}, url string, bearer string) (map[string]int
 		return nil, err
 	}
 
-	client := &http.Client{}
-	res, err := client.Do(r)
+	res, err := transport.Client().Do(r)
 	if err != nil {
 		return nil, err
 	}
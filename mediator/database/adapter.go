@@ -1,6 +1,11 @@
 package database
 
-import "github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+import (
+	"context"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+)
 
 type Adapter interface {
 	// Mediator Did
@@ -13,6 +18,8 @@ type Adapter interface {
 	AddMediatee(mediatee Mediatee) (err error)
 	DeleteMediatee(remoteDid string) (err error)
 	IsMediated(remoteDid string) (isMediated bool, err error)
+	// RotateMediatee moves the mediatee with its recipient DIDs, routing key and blocked status to the new remote DID
+	RotateMediatee(oldRemoteDid string, newRemoteDid string) error
 	// Block Connections (Mediatees)
 	BlockMediatee(remoteDid string) error
 	UnblockMediatee(remoteDid string) error
@@ -33,9 +40,39 @@ type Adapter interface {
 	GetMessage(id string) (*Message, error)
 	GetMessagesForRecipient(recipientDid string, limit int) ([]didcomm.Attachment, error)
 	GetMessagesCountForRecipient(recipientDid string) (count int, err error)
-	AddMessage(recipientDid string, message didcomm.Attachment) error
+	// AddMessage parks the message for the pickup, eventId is the cloud event it was forwarded from if any
+	AddMessage(recipientDid string, message didcomm.Attachment, eventId string, expires time.Time) error
 	DeleteMessagesByIds(messageIds []string) (int, error)
+	// MarkMessageDelivered returns true if the message was not marked as delivered before
+	MarkMessageDelivered(messageId string, recipientDid string) (bool, error)
+	// DeleteExpiredMessages deletes the expired messages of the recipient and returns them
+	DeleteExpiredMessages(recipientDid string) ([]Message, error)
+	// GetMessageQueues returns the number of parked messages and the oldest one per recipient DID
+	GetMessageQueues() ([]MessageQueue, error)
 	RemoteDidBelongsToMessage(remoteDid string, messageId string) (bool, error)
 
+	// Replay protection
+	// StoreMessageId returns false if the message id of the sender was already stored within the retention
+	StoreMessageId(sender string, messageId string, retention time.Duration) (isNew bool, err error)
+	// DeleteMessageId forgets the message id, so that the sender can retry a message which was not handled
+	DeleteMessageId(sender string, messageId string) error
+
+	// Outbox of cloud events
+	AddOutboxEvent(event OutboxEvent) error
+	GetOutboxEvents(status string) ([]OutboxEvent, error)
+	GetOutboxEvent(id string) (*OutboxEvent, error)
+	// ClaimOutboxEvent leases the event until the given time, false if another instance holds the lease
+	ClaimOutboxEvent(id string, until time.Time) (bool, error)
+	// UpdateOutboxEvent releases the lease until the next attempt of the event
+	UpdateOutboxEvent(event OutboxEvent) error
+	DeleteOutboxEvent(id string) error
+
+	// Threads of cloud requests, they expire after the ttl
+	AddThread(thread Thread, ttl time.Duration) error
+	// GetThread returns nil if the thread is unknown or expired
+	GetThread(recipientDid string, thid string) (*Thread, error)
+
+	// Ping checks that the database answers queries
+	Ping(ctx context.Context) error
 	Close() error
 }
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -139,6 +140,11 @@ func (db *Cassandra) UpdateMediatee(mediatee Mediatee) error {
 		values = append(values, mediatee.Group)
 	}
 
+	if mediatee.DataSchema != "" {
+		query = query + "data_schema=?,"
+		values = append(values, mediatee.DataSchema)
+	}
+
 	values = append(values, mediatee.RemoteDid)
 	query = strings.Trim(query, ",") + " WHERE remote_did=?"
 	if err := db.session.Query(query, values...).Exec(); err != nil {
@@ -155,8 +161,8 @@ func (db *Cassandra) AddMediatee(mediatee Mediatee) error {
 	logTag := "AddMediatee"
 	config.Logger.Info(logTag, "Start", true, "mediatee", mediatee)
 
-	query := "INSERT INTO mediatees (remote_did, routing_key, protocol, recipient_dids, topic, properties, added, eventtype,group) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ; "
-	if err := db.session.Query(query, mediatee.RemoteDid, mediatee.RoutingKey, mediatee.Protocol, mediatee.RecipientDids, mediatee.Topic, mediatee.Properties, time.Now(), mediatee.EventType, mediatee.Group).Exec(); err != nil {
+	query := "INSERT INTO mediatees (remote_did, routing_key, protocol, recipient_dids, topic, properties, added, eventtype,group, data_schema) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ; "
+	if err := db.session.Query(query, mediatee.RemoteDid, mediatee.RoutingKey, mediatee.Protocol, mediatee.RecipientDids, mediatee.Topic, mediatee.Properties, time.Now(), mediatee.EventType, mediatee.Group, mediatee.DataSchema).Exec(); err != nil {
 		config.Logger.Error(logTag, "Error while executing the query", err)
 		return errors.New(logTag + ". Error while executing the query: " + query + ". " + err.Error())
 	}
@@ -193,6 +199,42 @@ func (db *Cassandra) IsMediated(remoteDid string) (bool, error) {
 	return m != nil, nil
 }
 
+func (db *Cassandra) RotateMediatee(oldRemoteDid string, newRemoteDid string) error {
+	logTag := "RotateMediatee"
+	config.Logger.Info(logTag, "Start", true, "oldRemoteDid", oldRemoteDid, "newRemoteDid", newRemoteDid)
+
+	mediatee, err := db.getMediatee(oldRemoteDid)
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		return errors.New(logTag + ". Error: " + err.Error())
+	}
+	if mediatee == nil {
+		return errors.New(logTag + ". Error: mediatee not found")
+	}
+	isBlocked, err := db.IsBlocked(oldRemoteDid)
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		return errors.New(logTag + ". Error: " + err.Error())
+	}
+
+	// logged batch, so that the mediatee is never lost or duplicated
+	batch := db.session.NewBatch(gocql.LoggedBatch)
+	batch.Query("INSERT INTO mediatees (remote_did, routing_key, protocol, recipient_dids, topic, properties, added, eventtype, group, data_schema) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ;",
+		newRemoteDid, mediatee.RoutingKey, mediatee.Protocol, mediatee.RecipientDids, mediatee.Topic, mediatee.Properties, mediatee.Added, mediatee.EventType, mediatee.Group, mediatee.DataSchema)
+	batch.Query("DELETE FROM mediatees WHERE remote_did = ? ;", oldRemoteDid)
+	if isBlocked {
+		batch.Query("INSERT INTO blocked_dids (remote_did, added) VALUES (?, ?) ;", newRemoteDid, time.Now())
+		batch.Query("DELETE FROM blocked_dids WHERE remote_did = ? ;", oldRemoteDid)
+	}
+	if err := db.session.ExecuteBatch(batch); err != nil {
+		config.Logger.Error(logTag, "Error while executing the batch", err)
+		return errors.New(logTag + ". Error while executing the batch: " + err.Error())
+	}
+
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
 // Block Connections (Mediatees)
 
 func (db *Cassandra) BlockMediatee(remoteDid string) error {
@@ -411,6 +453,21 @@ func (db *Cassandra) GetMessage(id string) (*Message, error) {
 	return message, nil
 }
 
+func (db *Cassandra) MarkMessageDelivered(messageId string, recipientDid string) (bool, error) {
+	logTag := "MarkMessageDelivered"
+	config.Logger.Info(logTag, "Start", true, "messageId", messageId)
+
+	// the condition on the recipient DID keeps the update from creating a row for a deleted message
+	query := "UPDATE messages SET delivered = true WHERE id = ? IF recipient_did = ? AND delivered = null ;"
+	applied, err := db.session.Query(query, messageId, recipientDid).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return false, errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return applied, nil
+}
+
 func (db *Cassandra) GetMessagesForRecipient(recipientDid string, limit int) (messages []didcomm.Attachment, err error) {
 	logTag := "GetMessagesForRecipient"
 	config.Logger.Info(logTag, "Start", true, "recipientDid", recipientDid, "limit", limit)
@@ -450,14 +507,14 @@ func (db *Cassandra) GetMessagesCountForRecipient(recipientDid string) (count in
 	return rows, nil
 }
 
-func (db *Cassandra) AddMessage(recipientDid string, message didcomm.Attachment) (err error) {
+func (db *Cassandra) AddMessage(recipientDid string, message didcomm.Attachment, eventId string, expires time.Time) (err error) {
 	logTag := "AddMessage"
 	config.Logger.Info(logTag, "Start", message)
 
 	query := "INSERT INTO messages " +
-		"(id, recipient_did, description, filename, media_type, format, lastmod_time, byte_count, attachment_data, added) VALUES (now(), ?, ?, ?, ?, ?, ?, ?, ?, ?) ;"
+		"(id, recipient_did, description, filename, media_type, format, lastmod_time, byte_count, attachment_data, added, event_id, expires) VALUES (now(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ;"
 	if err := db.session.Query(query, recipientDid, message.Description, message.Filename, message.MediaType,
-		message.Format, message.LastmodTime, message.ByteCount, message.Data.(didcomm.AttachmentDataBase64).Value.Base64, time.Now()).Exec(); err != nil {
+		message.Format, message.LastmodTime, message.ByteCount, message.Data.(didcomm.AttachmentDataBase64).Value.Base64, time.Now(), eventId, expires).Exec(); err != nil {
 		config.Logger.Error(logTag, "Error while executing the query", err)
 		return errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
 	}
@@ -465,6 +522,37 @@ func (db *Cassandra) AddMessage(recipientDid string, message didcomm.Attachment)
 	return nil
 }
 
+func (db *Cassandra) GetMessageQueues() (queues []MessageQueue, err error) {
+	logTag := "GetMessageQueues"
+	config.Logger.Info(logTag, "Start", true)
+
+	var recipientDid string
+	var added time.Time
+	byRecipient := map[string]*MessageQueue{}
+	query := "SELECT recipient_did, added FROM messages ;"
+	iter := db.session.Query(query).Iter()
+	for iter.Scan(&recipientDid, &added) {
+		queue, ok := byRecipient[recipientDid]
+		if !ok {
+			queue = &MessageQueue{RecipientDid: recipientDid, Oldest: added}
+			byRecipient[recipientDid] = queue
+		}
+		queue.Count++
+		if added.Before(queue.Oldest) {
+			queue.Oldest = added
+		}
+	}
+	if err := iter.Close(); err != nil {
+		config.Logger.Error(logTag, "Error while closing iter", err)
+		return nil, errors.New(logTag + ": Error while closing iter:" + err.Error())
+	}
+	for _, queue := range byRecipient {
+		queues = append(queues, *queue)
+	}
+	config.Logger.Info(logTag, "End", true)
+	return queues, nil
+}
+
 func (db *Cassandra) DeleteMessagesByIds(messageIds []string) (deletedCount int, err error) {
 	logTag := "DeleteMessagesByIds"
 	config.Logger.Info(logTag, "Start", messageIds)
@@ -487,6 +575,36 @@ func (db *Cassandra) DeleteMessagesByIds(messageIds []string) (deletedCount int,
 	return foundDatasetsCount, nil
 }
 
+func (db *Cassandra) DeleteExpiredMessages(recipientDid string) (expired []Message, err error) {
+	logTag := "DeleteExpiredMessages"
+	config.Logger.Info(logTag, "Start", true, "recipientDid", recipientDid)
+
+	now := time.Now()
+	var message Message
+	ids := []string{}
+	query := "SELECT id, recipient_did, event_id, expires FROM messages WHERE recipient_did = ? ;"
+	iter := db.session.Query(query, recipientDid).Iter()
+	for iter.Scan(&message.Id, &message.RecipientDid, &message.EventId, &message.Expires) {
+		if !message.Expires.IsZero() && message.Expires.Before(now) {
+			expired = append(expired, message)
+			ids = append(ids, message.Id.String())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		config.Logger.Error(logTag, "Error while closing iter", err)
+		return nil, errors.New(logTag + ": Error while closing iter:" + err.Error())
+	}
+	if len(ids) > 0 {
+		query = "DELETE FROM messages WHERE id in ? ;"
+		if err = db.session.Query(query, ids).Exec(); err != nil {
+			config.Logger.Error(logTag, "Error while executing the query", err)
+			return nil, errors.New(logTag + ". Error while executing the query: " + query + ". " + err.Error())
+		}
+	}
+	config.Logger.Info(logTag, "End", true, "expired", len(expired))
+	return expired, nil
+}
+
 func (db *Cassandra) RemoteDidBelongsToMessage(remoteDid string, messageId string) (b bool, err error) {
 	logTag := "RemoteDidBelongsToMessage"
 	config.Logger.Info(logTag, "Start", true, "remoteDid", remoteDid, "messageId", messageId)
@@ -515,6 +633,303 @@ func (db *Cassandra) RemoteDidBelongsToMessage(remoteDid string, messageId strin
 
 // Help Functions
 
+// Replay protection
+func (db *Cassandra) StoreMessageId(sender string, messageId string, retention time.Duration) (isNew bool, err error) {
+	logTag := "StoreMessageId"
+	config.Logger.Info(logTag, "Start", true, "sender", sender, "messageId", messageId)
+
+	// lightweight transaction, so that replicas can not accept the same message twice
+	query := "INSERT INTO seen_messages (sender, id, added) VALUES (?, ?, ?) IF NOT EXISTS USING TTL ? ;"
+	applied, err := db.session.Query(query, sender, messageId, time.Now(), int(retention.Seconds())).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return false, errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return applied, nil
+}
+
+func (db *Cassandra) DeleteMessageId(sender string, messageId string) error {
+	logTag := "DeleteMessageId"
+	config.Logger.Info(logTag, "Start", true, "sender", sender, "messageId", messageId)
+
+	// lightweight transaction like the insert, plain and conditional writes of a row must not be mixed
+	query := "DELETE FROM seen_messages WHERE sender = ? AND id = ? IF EXISTS ;"
+	if _, err := db.session.Query(query, sender, messageId).MapScanCAS(map[string]interface{}{}); err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
+// Outbox
+const outboxColumns = "id, status, remote_did, recipient_did, topic, event_type, payload, thid, pthid, " +
+	"message_id, message_type, created, group, data_schema, attempts, last_error, dead_lettered, unmapped, next_attempt, added, event_id, traceparent, tracestate"
+
+// Events are queued in partitions of their status and the time span in which they entered it, instead of being read
+// through an index on the status. A partition is read while it holds events. Once it is empty and no event can enter it
+// anymore, it is dropped from the list of partitions, so the tombstones of published events are not read again.
+const (
+	outboxBucketSize = time.Minute
+	// no event enters a bucket which is older, a larger clock skew between the instances could lose queue entries
+	outboxBucketGrace = 10 * time.Minute
+	// ids per query of the events of the queue
+	outboxReadChunk = 100
+)
+
+// outboxBucket returns the queue partition of an event which enters its status at the given time
+func outboxBucket(t time.Time) time.Time {
+	return t.UTC().Truncate(outboxBucketSize)
+}
+
+func (db *Cassandra) AddOutboxEvent(event OutboxEvent) error {
+	logTag := "AddOutboxEvent"
+	config.Logger.Info(logTag, "Start", true, "id", event.Id)
+
+	bucket := outboxBucket(time.Now())
+	// logged batch, so that a stored event is always queued
+	batch := db.session.NewBatch(gocql.LoggedBatch)
+	// the lease starts expired, so that the event can be claimed right away
+	batch.Query("INSERT INTO outbox ("+outboxColumns+", bucket, lease) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ;",
+		event.Id, event.Status, event.RemoteDid, event.RecipientDid, event.Topic, event.EventType, event.Payload,
+		event.Thid, event.Pthid, event.MessageId, event.MessageType, event.Created, event.Group, event.DataSchema,
+		event.Attempts, event.LastError, event.DeadLettered, event.Unmapped, event.NextAttempt, event.Added, event.EventId, event.TraceParent, event.TraceState,
+		bucket, event.Added)
+	db.queueOutboxEvent(batch, event.Status, bucket, event.Id)
+	if err := db.session.ExecuteBatch(batch); err != nil {
+		config.Logger.Error(logTag, "Error while executing the batch", err)
+		return errors.New(logTag + ". Error while executing the batch: " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
+// queueOutboxEvent adds the queue entry of the event and lists its partition
+func (db *Cassandra) queueOutboxEvent(batch *gocql.Batch, status string, bucket time.Time, id string) {
+	batch.Query("INSERT INTO outbox_queue (status, bucket, id) VALUES (?, ?, ?) ;", status, bucket, id)
+	batch.Query("INSERT INTO outbox_buckets (status, bucket) VALUES (?, ?) ;", status, bucket)
+}
+
+func (db *Cassandra) GetOutboxEvents(status string) ([]OutboxEvent, error) {
+	logTag := "GetOutboxEvents"
+	config.Logger.Info(logTag, "Start", true, "status", status)
+
+	queued, err := db.queuedOutboxEvents(status)
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		return nil, errors.New(logTag + ". Error: " + err.Error())
+	}
+	events := []OutboxEvent{}
+	stale := time.Now().Add(-outboxBucketGrace)
+	for i := 0; i < len(queued); i += outboxReadChunk {
+		chunk := queued[i:min(i+outboxReadChunk, len(queued))]
+		ids := make([]string, 0, len(chunk))
+		for _, entry := range chunk {
+			ids = append(ids, entry.id)
+		}
+		rows, err := readQueuedOutboxRows(db.session.Query("SELECT "+outboxColumns+", bucket FROM outbox WHERE id IN ? ;", ids).Iter())
+		if err != nil {
+			config.Logger.Error(logTag, "Error", err)
+			return nil, errors.New(logTag + ". Error: " + err.Error())
+		}
+		for _, entry := range chunk {
+			row, ok := rows[entry.id]
+			if ok && row.event.Status == status && row.bucket.Equal(entry.bucket) {
+				events = append(events, row.event)
+				continue
+			}
+			// the entry of a deleted event or of a status change which was interrupted, recent entries may belong to
+			// a status change which is still running
+			if entry.bucket.Before(stale) {
+				if err := db.session.Query("DELETE FROM outbox_queue WHERE status = ? AND bucket = ? AND id = ? ;", status, entry.bucket, entry.id).Exec(); err != nil {
+					config.Logger.Warn(logTag, "Error while removing a stale queue entry", err)
+				}
+			}
+		}
+	}
+	config.Logger.Info(logTag, "End", true)
+	return events, nil
+}
+
+type outboxQueueEntry struct {
+	bucket time.Time
+	id     string
+}
+
+// queuedOutboxEvents reads the queue entries of the status and drops the empty partitions which no event can enter anymore
+func (db *Cassandra) queuedOutboxEvents(status string) ([]outboxQueueEntry, error) {
+	buckets := []time.Time{}
+	iter := db.session.Query("SELECT bucket FROM outbox_buckets WHERE status = ? ;", status).Iter()
+	var bucket time.Time
+	for iter.Scan(&bucket) {
+		buckets = append(buckets, bucket)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	entries := []outboxQueueEntry{}
+	stale := time.Now().Add(-outboxBucketGrace)
+	for _, bucket := range buckets {
+		iter := db.session.Query("SELECT id FROM outbox_queue WHERE status = ? AND bucket = ? ;", status, bucket).Iter()
+		count := 0
+		var id string
+		for iter.Scan(&id) {
+			entries = append(entries, outboxQueueEntry{bucket: bucket, id: id})
+			count++
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+		if count == 0 && bucket.Before(stale) {
+			if err := db.session.Query("DELETE FROM outbox_buckets WHERE status = ? AND bucket = ? ;", status, bucket).Exec(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (db *Cassandra) GetOutboxEvent(id string) (*OutboxEvent, error) {
+	logTag := "GetOutboxEvent"
+	config.Logger.Info(logTag, "Start", true, "id", id)
+
+	query := "SELECT " + outboxColumns + " FROM outbox WHERE id = ? ;"
+	events, err := readOutboxRows(db.session.Query(query, id).Iter())
+	if err != nil {
+		config.Logger.Error(logTag, "Error", err)
+		return nil, errors.New(logTag + ". Error: " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+func (db *Cassandra) ClaimOutboxEvent(id string, until time.Time) (bool, error) {
+	logTag := "ClaimOutboxEvent"
+	config.Logger.Info(logTag, "Start", true, "id", id)
+
+	// lightweight transaction, so that only one instance publishes the event
+	query := "UPDATE outbox SET lease = ? WHERE id = ? IF lease < ? ;"
+	applied, err := db.session.Query(query, until, id, time.Now()).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return false, errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return applied, nil
+}
+
+func (db *Cassandra) UpdateOutboxEvent(event OutboxEvent) error {
+	logTag := "UpdateOutboxEvent"
+	config.Logger.Info(logTag, "Start", true, "id", event.Id)
+
+	var status string
+	var bucket time.Time
+	err := db.session.Query("SELECT status, bucket FROM outbox WHERE id = ? ;", event.Id).Scan(&status, &bucket)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		config.Logger.Error(logTag, "Error while reading the event", err)
+		return errors.New(logTag + ". Error while reading the event: " + err.Error())
+	}
+	newBucket := bucket
+	if status != event.Status {
+		// the event is queued for its new status first, the entry is skipped until the event has the status
+		newBucket = outboxBucket(time.Now())
+		batch := db.session.NewBatch(gocql.LoggedBatch)
+		db.queueOutboxEvent(batch, event.Status, newBucket, event.Id)
+		if err := db.session.ExecuteBatch(batch); err != nil {
+			config.Logger.Error(logTag, "Error while executing the batch", err)
+			return errors.New(logTag + ". Error while executing the batch: " + err.Error())
+		}
+	}
+
+	// lightweight transaction, so that an event which was deleted in the meantime is not written again
+	query := "UPDATE outbox SET status = ?, bucket = ?, topic = ?, attempts = ?, last_error = ?, dead_lettered = ?, next_attempt = ?, lease = ? WHERE id = ? IF EXISTS ;"
+	applied, err := db.session.Query(query, event.Status, newBucket, event.Topic, event.Attempts, event.LastError, event.DeadLettered, event.NextAttempt, event.NextAttempt, event.Id).
+		MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	if status != event.Status {
+		// the former entry is removed, or the new one if the event was deleted in the meantime.
+		// An entry which remains is skipped and removed by GetOutboxEvents.
+		removedStatus, removedBucket := status, bucket
+		if !applied {
+			removedStatus, removedBucket = event.Status, newBucket
+		}
+		if err := db.session.Query("DELETE FROM outbox_queue WHERE status = ? AND bucket = ? AND id = ? ;", removedStatus, removedBucket, event.Id).Exec(); err != nil {
+			config.Logger.Warn(logTag, "Error while removing the queue entry", err)
+		}
+	}
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
+func (db *Cassandra) DeleteOutboxEvent(id string) error {
+	logTag := "DeleteOutboxEvent"
+	config.Logger.Info(logTag, "Start", true, "id", id)
+
+	var status string
+	var bucket time.Time
+	err := db.session.Query("SELECT status, bucket FROM outbox WHERE id = ? ;", id).Scan(&status, &bucket)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		config.Logger.Error(logTag, "Error while reading the event", err)
+		return errors.New(logTag + ". Error while reading the event: " + err.Error())
+	}
+	batch := db.session.NewBatch(gocql.LoggedBatch)
+	batch.Query("DELETE FROM outbox WHERE id = ? ;", id)
+	batch.Query("DELETE FROM outbox_queue WHERE status = ? AND bucket = ? AND id = ? ;", status, bucket, id)
+	if err := db.session.ExecuteBatch(batch); err != nil {
+		config.Logger.Error(logTag, "Error while executing the batch", err)
+		return errors.New(logTag + ". Error while executing the batch: " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
+// Threads
+func (db *Cassandra) AddThread(thread Thread, ttl time.Duration) error {
+	logTag := "AddThread"
+	config.Logger.Info(logTag, "Start", true, "recipientDid", thread.RecipientDid, "thid", thread.Thid)
+
+	query := "INSERT INTO threads (recipient_did, thid, pthid, reply_to) VALUES (?, ?, ?, ?) USING TTL ? ;"
+	if err := db.session.Query(query, thread.RecipientDid, thread.Thid, thread.Pthid, thread.ReplyTo, int(ttl.Seconds())).Exec(); err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return nil
+}
+
+func (db *Cassandra) GetThread(recipientDid string, thid string) (*Thread, error) {
+	logTag := "GetThread"
+	config.Logger.Info(logTag, "Start", true, "recipientDid", recipientDid, "thid", thid)
+
+	thread := Thread{RecipientDid: recipientDid, Thid: thid}
+	query := "SELECT pthid, reply_to FROM threads WHERE recipient_did = ? AND thid = ? ;"
+	err := db.session.Query(query, recipientDid, thid).Scan(&thread.Pthid, &thread.ReplyTo)
+	if errors.Is(err, gocql.ErrNotFound) {
+		config.Logger.Info(logTag, "End", "No thread found")
+		return nil, nil
+	}
+	if err != nil {
+		config.Logger.Error(logTag, "Error while executing the query", err)
+		return nil, errors.New(logTag + ". Error while executing the query: '" + query + "'. " + err.Error())
+	}
+	config.Logger.Info(logTag, "End", true)
+	return &thread, nil
+}
+
 func (db *Cassandra) getMediateeGroup(group string) (*Mediatee, error) {
 	logTag := "getAttachmentById"
 	config.Logger.Info(logTag, "Start", true, "group", group)
@@ -559,6 +974,11 @@ func (db *Cassandra) getMediatee(remoteDid string) (*Mediatee, error) {
 	}
 }
 
+func (db *Cassandra) Ping(ctx context.Context) error {
+	var now gocql.UUID
+	return db.session.Query("SELECT now() FROM system.local").WithContext(ctx).Scan(&now)
+}
+
 func (db *Cassandra) Close() error {
 	logTag := "Database Closing"
 	config.Logger.Info(logTag, "Start", true)
@@ -580,6 +1000,7 @@ func readMediateeRows(iter *gocql.Iter) (datasets []Mediatee, err error) {
 			Topic:         m["topic"].(string),
 			EventType:     m["eventtype"].(string),
 			Group: 		   m["group"].(string),
+			DataSchema:    m["data_schema"].(string),
 		})
 		m = map[string]interface{}{}
 	}
@@ -594,11 +1015,48 @@ func readMediateeRows(iter *gocql.Iter) (datasets []Mediatee, err error) {
 	return datasets, nil
 }
 
+func readOutboxRows(iter *gocql.Iter) ([]OutboxEvent, error) {
+	events := []OutboxEvent{}
+	var event OutboxEvent
+	for iter.Scan(outboxRowDestinations(&event)...) {
+		events = append(events, event)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.New("readOutboxRows: Error while closing iter:" + err.Error())
+	}
+	return events, nil
+}
+
+type queuedOutboxRow struct {
+	event  OutboxEvent
+	bucket time.Time
+}
+
+// readQueuedOutboxRows reads the events with their queue partition by id
+func readQueuedOutboxRows(iter *gocql.Iter) (map[string]queuedOutboxRow, error) {
+	rows := map[string]queuedOutboxRow{}
+	var row queuedOutboxRow
+	for iter.Scan(append(outboxRowDestinations(&row.event), &row.bucket)...) {
+		rows[row.event.Id] = row
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.New("readQueuedOutboxRows: Error while closing iter:" + err.Error())
+	}
+	return rows, nil
+}
+
+// outboxRowDestinations returns the fields of the event in the order of outboxColumns
+func outboxRowDestinations(event *OutboxEvent) []interface{} {
+	return []interface{}{&event.Id, &event.Status, &event.RemoteDid, &event.RecipientDid, &event.Topic, &event.EventType, &event.Payload,
+		&event.Thid, &event.Pthid, &event.MessageId, &event.MessageType, &event.Created, &event.Group, &event.DataSchema,
+		&event.Attempts, &event.LastError, &event.DeadLettered, &event.Unmapped, &event.NextAttempt, &event.Added, &event.EventId, &event.TraceParent, &event.TraceState}
+}
+
 func (db *Cassandra) getMessage(messageId string) (message *Message, err error) {
 	logTag := "getAttachmentById"
 	config.Logger.Info(logTag, "Start", true, "messageId", messageId)
 
-	query := "SELECT recipient_did, id, description, filename, media_type, format, lastmod_time, byte_count, attachment_data " +
+	query := "SELECT recipient_did, id, description, filename, media_type, format, lastmod_time, byte_count, attachment_data, event_id, expires " +
 		"FROM messages WHERE id = ?; "
 
 	iter := db.session.Query(query, messageId).Iter()
@@ -624,7 +1082,7 @@ func readMessageRows(iter *gocql.Iter) (messages []Message, err error) {
 	var message Message
 	for iter.Scan(&message.RecipientDid,
 		&message.Id, &message.Description, &message.Filename, &message.MediaType, &message.Format, &message.LastmodTime, &message.ByteCount,
-		&message.AttachmentData) {
+		&message.AttachmentData, &message.EventId, &message.Expires) {
 		messages = append(messages, message)
 	}
 	if err := iter.Close(); err != nil {
@@ -1,12 +1,15 @@
 package database
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	secretsResolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+	"github.com/google/uuid"
 )
 
 // Table Structure
@@ -30,12 +33,28 @@ import (
 type DemoElement struct {
 	message      didcomm.Attachment
 	recipientDid string
+	eventId      string
+	expires      time.Time
+	added        time.Time
+	delivered    bool
 }
 
 type Demo struct {
 	attachments []DemoElement
 	mediatees   []Mediatee
 	blockedDids []string
+	seenMu      sync.Mutex
+	seenIds     map[string]time.Time
+	outboxMu    sync.Mutex
+	outbox      map[string]OutboxEvent
+	leases      map[string]time.Time
+	threadsMu   sync.Mutex
+	threads     map[string]demoThread
+}
+
+type demoThread struct {
+	thread  Thread
+	expires time.Time
 }
 
 func NewDemo() *Demo {
@@ -43,6 +62,10 @@ func NewDemo() *Demo {
 		attachments: []DemoElement{},
 		mediatees:   []Mediatee{},
 		blockedDids: []string{},
+		seenIds:     map[string]time.Time{},
+		outbox:      map[string]OutboxEvent{},
+		leases:      map[string]time.Time{},
+		threads:     map[string]demoThread{},
 	}
 }
 
@@ -108,6 +131,21 @@ func (d *Demo) IsMediated(remoteDid string) (bool, error) {
 	return false, nil
 }
 
+func (d *Demo) RotateMediatee(oldRemoteDid string, newRemoteDid string) error {
+	for i, mediatee := range d.mediatees {
+		if mediatee.RemoteDid == oldRemoteDid {
+			d.mediatees[i].RemoteDid = newRemoteDid
+			for j, blockedDid := range d.blockedDids {
+				if blockedDid == oldRemoteDid {
+					d.blockedDids[j] = newRemoteDid
+				}
+			}
+			return nil
+		}
+	}
+	return errors.New("could not find mediatee")
+}
+
 // Block Connections (Mediatees)
 func (d *Demo) BlockMediatee(remoteDid string) error {
 	d.blockedDids = append(d.blockedDids, remoteDid)
@@ -228,14 +266,29 @@ func (d *Demo) GetRoutingKey(remoteDid string) (string, error) {
 func (d *Demo) GetMessage(id string) (*Message, error) {
 	for _, message := range d.attachments {
 		if *message.message.Id == id {
-			return &Message{AttachmentId: *message.message.Id, RecipientDid: message.recipientDid, Description: *message.message.Description,
-				Filename: *message.message.Filename, MediaType: *message.message.MediaType, Format: *message.message.Format, LastmodTime: *message.message.LastmodTime,
-				ByteCount: *message.message.ByteCount, AttachmentData: message.message.Data.(didcomm.AttachmentDataBase64).Value.Base64}, nil
+			return &Message{AttachmentId: *message.message.Id, RecipientDid: message.recipientDid, Description: value(message.message.Description),
+				Filename: value(message.message.Filename), MediaType: value(message.message.MediaType), Format: value(message.message.Format), LastmodTime: value(message.message.LastmodTime),
+				ByteCount: value(message.message.ByteCount), AttachmentData: message.message.Data.(didcomm.AttachmentDataBase64).Value.Base64,
+				EventId: message.eventId, Expires: message.expires}, nil
 		}
 	}
 	return nil, errors.New("cannot find the message")
 }
 
+func (d *Demo) MarkMessageDelivered(messageId string, recipientDid string) (bool, error) {
+	for i := range d.attachments {
+		element := &d.attachments[i]
+		if *element.message.Id == messageId && element.recipientDid == recipientDid {
+			if element.delivered {
+				return false, nil
+			}
+			element.delivered = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *Demo) GetMessagesForRecipient(recipientDid string, limit int) ([]didcomm.Attachment, error) {
 	messages := []didcomm.Attachment{}
 	for _, e := range m.attachments {
@@ -256,15 +309,55 @@ func (m *Demo) GetMessagesCountForRecipient(recipientDid string) (count int, err
 	return count, nil
 }
 
-func (m *Demo) AddMessage(recipientDid string, message didcomm.Attachment) error {
+func (m *Demo) AddMessage(recipientDid string, message didcomm.Attachment, eventId string, expires time.Time) error {
+	if message.Id == nil {
+		id := uuid.NewString()
+		message.Id = &id
+	}
 	m.attachments = append(m.attachments, DemoElement{
 		message:      message,
 		recipientDid: recipientDid,
+		eventId:      eventId,
+		expires:      expires,
+		added:        time.Now(),
 	})
 
 	return nil
 }
 
+func (m *Demo) DeleteExpiredMessages(recipientDid string) ([]Message, error) {
+	now := time.Now()
+	expired := []Message{}
+	attachments := []DemoElement{}
+	for _, e := range m.attachments {
+		if e.recipientDid == recipientDid && !e.expires.IsZero() && e.expires.Before(now) {
+			expired = append(expired, Message{AttachmentId: *e.message.Id, RecipientDid: e.recipientDid, EventId: e.eventId, Expires: e.expires})
+			continue
+		}
+		attachments = append(attachments, e)
+	}
+	m.attachments = attachments
+	return expired, nil
+}
+
+func (m *Demo) GetMessageQueues() ([]MessageQueue, error) {
+	queues := []MessageQueue{}
+	index := map[string]int{}
+	for _, e := range m.attachments {
+		i, ok := index[e.recipientDid]
+		if !ok {
+			i = len(queues)
+			index[e.recipientDid] = i
+			queues = append(queues, MessageQueue{RecipientDid: e.recipientDid, Oldest: e.added})
+		}
+		queues[i].Count++
+		if e.added.Before(queues[i].Oldest) {
+			queues[i].Oldest = e.added
+		}
+	}
+	return queues, nil
+}
+
 func (m *Demo) DeleteMessagesByIds(messageIds []string) (deletedCount int, err error) {
 	count := 0
 	for _, id := range messageIds {
@@ -299,6 +392,119 @@ func (d *Demo) RemoteDidBelongsToMessage(remoteDid string, messageId string) (bo
 	return false, nil
 }
 
+// Replay protection
+func (d *Demo) StoreMessageId(sender string, messageId string, retention time.Duration) (bool, error) {
+	d.seenMu.Lock()
+	defer d.seenMu.Unlock()
+	now := time.Now()
+	for key, expires := range d.seenIds {
+		if expires.Before(now) {
+			delete(d.seenIds, key)
+		}
+	}
+	key := sender + " " + messageId
+	if _, ok := d.seenIds[key]; ok {
+		return false, nil
+	}
+	d.seenIds[key] = now.Add(retention)
+	return true, nil
+}
+
+func (d *Demo) DeleteMessageId(sender string, messageId string) error {
+	d.seenMu.Lock()
+	defer d.seenMu.Unlock()
+	delete(d.seenIds, sender+" "+messageId)
+	return nil
+}
+
+// Outbox
+func (d *Demo) AddOutboxEvent(event OutboxEvent) error {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	d.outbox[event.Id] = event
+	d.leases[event.Id] = event.Added
+	return nil
+}
+
+func (d *Demo) GetOutboxEvents(status string) ([]OutboxEvent, error) {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	events := []OutboxEvent{}
+	for _, event := range d.outbox {
+		if event.Status == status {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (d *Demo) GetOutboxEvent(id string) (*OutboxEvent, error) {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	event, ok := d.outbox[id]
+	if !ok {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+func (d *Demo) ClaimOutboxEvent(id string, until time.Time) (bool, error) {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	lease, ok := d.leases[id]
+	if !ok || !lease.Before(time.Now()) {
+		return false, nil
+	}
+	d.leases[id] = until
+	return true, nil
+}
+
+func (d *Demo) UpdateOutboxEvent(event OutboxEvent) error {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	if _, ok := d.outbox[event.Id]; ok {
+		d.outbox[event.Id] = event
+		d.leases[event.Id] = event.NextAttempt
+	}
+	return nil
+}
+
+func (d *Demo) DeleteOutboxEvent(id string) error {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	delete(d.outbox, id)
+	delete(d.leases, id)
+	return nil
+}
+
+// Threads
+func (d *Demo) AddThread(thread Thread, ttl time.Duration) error {
+	d.threadsMu.Lock()
+	defer d.threadsMu.Unlock()
+	now := time.Now()
+	for key, t := range d.threads {
+		if t.expires.Before(now) {
+			delete(d.threads, key)
+		}
+	}
+	d.threads[thread.RecipientDid+" "+thread.Thid] = demoThread{thread: thread, expires: now.Add(ttl)}
+	return nil
+}
+
+func (d *Demo) GetThread(recipientDid string, thid string) (*Thread, error) {
+	d.threadsMu.Lock()
+	defer d.threadsMu.Unlock()
+	t, ok := d.threads[recipientDid+" "+thid]
+	if !ok || t.expires.Before(time.Now()) {
+		return nil, nil
+	}
+	return &t.thread, nil
+}
+
+func (d *Demo) Ping(ctx context.Context) error {
+	return nil
+}
+
 func (d *Demo) Close() error {
 	logTag := "Database Closing"
 	config.Logger.Info(logTag, "Start", true)
@@ -306,6 +512,15 @@ func (d *Demo) Close() error {
 	return nil
 }
 
+// value returns the zero value for optional attachment fields which are not set
+func value[T any](pointer *T) T {
+	var zero T
+	if pointer == nil {
+		return zero
+	}
+	return *pointer
+}
+
 func DeleteIdFromSlice[T any](slice []T, id int) []T {
 	if len := len(slice); len == 1 {
 		return []T{}
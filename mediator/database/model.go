@@ -19,6 +19,8 @@ type MediateeBase struct {
 	Properties map[string]string `json:"properties" example:"did:remotedid:example:67890fghijk#key-2"`
 	EventType  string            `json:"eventType"`
 	Group      string            `json:"group"`
+	// URI of the schema of the cloud event payload which the properties create
+	DataSchema string `json:"dataSchema,omitempty" example:"https://example.com/schemas/temperature.json"`
 }
 
 type Mediatee struct {
@@ -31,6 +33,7 @@ type Mediatee struct {
 	RecipientDids []string          `json:"recipientDids" example:"did:recipientdid:example:12345abcde#key-1,did:recipientdid:example:12345abcde#key-2"`
 	Added         time.Time         `json:"added" example:"2024-01-16 12:23:34.952000+0000"`
 	Group         string            `json:"group"`
+	DataSchema    string            `json:"dataSchema,omitempty" example:"https://example.com/schemas/temperature.json"`
 }
 
 type Message struct {
@@ -45,4 +48,63 @@ type Message struct {
 	ByteCount      uint64
 	AttachmentData string
 	Added          time.Time
+	// id of the cloud event the message was forwarded from, its delivery status is published with this id
+	EventId string
+	// the message is dropped instead of delivered after this time, zero if it does not expire
+	Expires time.Time
+}
+
+type MessageQueue struct {
+	RecipientDid string
+	Count        int
+	// time the oldest message of the queue was added
+	Oldest time.Time
+}
+
+const (
+	OUTBOX_STATUS_PENDING = "pending"
+	// retries are exhausted or the event could not be created, it waits for a replay
+	OUTBOX_STATUS_DEAD = "dead"
+)
+
+// OutboxEvent is a cloud event which is stored before it is published
+type OutboxEvent struct {
+	Id           string `json:"id"`
+	Status       string `json:"status" example:"pending"`
+	RemoteDid    string `json:"remoteDid"`
+	RecipientDid string `json:"recipientDid"`
+	Topic        string `json:"topic"`
+	EventType    string `json:"eventType"`
+	Payload      string `json:"payload"`
+	Attempts     int    `json:"attempts"`
+	LastError    string `json:"lastError,omitempty"`
+	// the dead event was published to the dead-letter topic
+	DeadLettered bool `json:"deadLettered"`
+	// the cloud event could not be created, the payload is the DIDComm message and the event can not be replayed
+	Unmapped    bool      `json:"unmapped,omitempty"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	Added       time.Time `json:"added"`
+	// DIDComm thread of the message, published as cloud event extensions
+	Thid  string `json:"thid,omitempty"`
+	Pthid string `json:"pthid,omitempty"`
+	// attributes of the DIDComm message and the connection which are published with the event
+	MessageId   string    `json:"messageId,omitempty"`
+	MessageType string    `json:"messageType,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	Group       string    `json:"group,omitempty"`
+	DataSchema  string    `json:"dataSchema,omitempty"`
+	// id of the cloud event whose delivery status is published
+	EventId string `json:"eventId,omitempty"`
+	// W3C trace context of the message, published as distributed tracing extension
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+}
+
+// Thread correlates the replies of a device with the cloud service which started the DIDComm thread
+type Thread struct {
+	RecipientDid string
+	Thid         string
+	Pthid        string
+	// topic or subject of the replies, empty for the topic of the mediatee
+	ReplyTo string
 }
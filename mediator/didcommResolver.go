@@ -1,7 +1,9 @@
 package mediator
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
 )
 
 // type DidResolver interface {
@@ -23,7 +26,7 @@ type DidDocumentJSON struct {
 
 // DidDocument represents the nested structure inside the main JSON.
 type DidDocument struct {
-	Context              []string             `json:"@context"`
+	Context              JsonLdContext        `json:"@context"`
 	ID                   string               `json:"id"`
 	VerificationMethod   []VerificationMethod `json:"verificationMethod"`
 	KeyAgreement         []string             `json:"keyAgreement"`
@@ -34,12 +37,47 @@ type DidDocument struct {
 	Service              []Service            `json:"service"`
 }
 
+// JsonLdContext is the @context of a document, given as a single uri or as list
+type JsonLdContext []string
+
+// UnmarshalJSON accepts the context as plain uri or as list, embedded context definitions of the list are skipped
+func (c *JsonLdContext) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = nil
+		return nil
+	}
+	var uri string
+	if err := json.Unmarshal(data, &uri); err == nil {
+		*c = JsonLdContext{uri}
+		return nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("@context is neither a uri nor a list: %w", err)
+	}
+	context := JsonLdContext{}
+	for _, entry := range entries {
+		if err := json.Unmarshal(entry, &uri); err == nil {
+			context = append(context, uri)
+			continue
+		}
+		var definition map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &definition); err != nil {
+			return fmt.Errorf("@context entry is neither a uri nor a definition: %w", err)
+		}
+	}
+	*c = context
+	return nil
+}
+
 // VerificationMethod represents a nested structure inside the DidDocument.
 type VerificationMethod struct {
-	ID                 string `json:"id"`
-	Type               string `json:"type"`
-	Controller         string `json:"controller"`
-	PublicKeyMultibase string `json:"publicKeyMultibase"`
+	ID                 string          `json:"id"`
+	Type               string          `json:"type"`
+	Controller         string          `json:"controller"`
+	PublicKeyMultibase string          `json:"publicKeyMultibase"`
+	PublicKeyBase58    string          `json:"publicKeyBase58,omitempty"`
+	PublicKeyJwk       json.RawMessage `json:"publicKeyJwk,omitempty"`
 }
 
 // Service represents the nested structure inside the DidDocument for the "service" field.
@@ -57,6 +95,29 @@ type ServiceEndpoint struct {
 	RoutingKeys []string `json:"routingKeys"`
 }
 
+// UnmarshalJSON accepts the service endpoint as object, as plain uri or as list of which the first entry is used
+func (s *ServiceEndpoint) UnmarshalJSON(data []byte) error {
+	var uri string
+	if err := json.Unmarshal(data, &uri); err == nil {
+		*s = ServiceEndpoint{Uri: uri, Accept: []string{}, RoutingKeys: []string{}}
+		return nil
+	}
+	var endpoints []json.RawMessage
+	if err := json.Unmarshal(data, &endpoints); err == nil {
+		if len(endpoints) == 0 {
+			return errors.New("empty service endpoint list")
+		}
+		return s.UnmarshalJSON(endpoints[0])
+	}
+	type serviceEndpoint ServiceEndpoint
+	var endpoint serviceEndpoint
+	if err := json.Unmarshal(data, &endpoint); err != nil {
+		return err
+	}
+	*s = ServiceEndpoint(endpoint)
+	return nil
+}
+
 // DidDocumentMetadata represents the nested structure inside the main JSON for "didDocumentMetadata".
 type DidDocumentMetadataJSON struct{}
 
@@ -71,17 +132,48 @@ type DidResolver interface {
 	ResolveDidAsJson(did string) (*DidDocumentJSON, error)
 }
 
+// ContextDidResolver resolves DIDs in the trace of ctx, the didcomm library calls Resolve without context
+type ContextDidResolver interface {
+	ResolveDidContext(ctx context.Context, did string) (*didcomm.DidDoc, error)
+	ResolveDidAsJsonContext(ctx context.Context, did string) (*DidDocumentJSON, error)
+}
+
+// ResolveDidContext resolves the DID in the trace of ctx if the resolver supports it
+func ResolveDidContext(ctx context.Context, resolver DidResolver, did string) (*didcomm.DidDoc, error) {
+	if contextResolver, ok := resolver.(ContextDidResolver); ok {
+		return contextResolver.ResolveDidContext(ctx, did)
+	}
+	return resolver.ResolveDid(did)
+}
+
+// ResolveDidAsJsonContext resolves the DID document in the trace of ctx if the resolver supports it
+func ResolveDidAsJsonContext(ctx context.Context, resolver DidResolver, did string) (*DidDocumentJSON, error) {
+	if contextResolver, ok := resolver.(ContextDidResolver); ok {
+		return contextResolver.ResolveDidAsJsonContext(ctx, did)
+	}
+	return resolver.ResolveDidAsJson(did)
+}
+
+// UniverseDidResolver resolves DIDs with a Universal Resolver deployment
 type UniverseDidResolver struct {
+	url    string
+	client *http.Client
 }
 
-func NewDidResolver() *UniverseDidResolver {
-	return &UniverseDidResolver{}
+// NewUniverseDidResolver creates a resolver for the Universal Resolver at url, the outbound client is used if client is nil
+func NewUniverseDidResolver(url string, client *http.Client) *UniverseDidResolver {
+	return &UniverseDidResolver{
+		url:    url,
+		client: client,
+	}
 }
 
-// Note: Only the Service Endpoint encoding of the official did:peer method is supported.
-// https://identity.foundation/peer-did-method-spec/#generating-a-didpeer2
 func (u *UniverseDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
 	didDoc, err := u.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+func resolveCallback(didDoc *didcomm.DidDoc, err error, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
 	if err != nil {
 		errorKind := didcomm.NewErrorKindSecretNotFound()
 		err = cb.Error(errorKind, err.Error())
@@ -98,17 +190,29 @@ func (u *UniverseDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResul
 	}
 }
 
-func (u *UniverseDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
-	queryUrl, err := url.JoinPath(config.CurrentConfiguration.DidComm.ResolverUrl, "/1.0/identifiers/", did)
+// fetch returns the resolution result of the Universal Resolver
+func (u *UniverseDidResolver) fetch(did string) ([]byte, error) {
+	queryUrl, err := url.JoinPath(u.url, "/1.0/identifiers/", did)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.Get(queryUrl)
+	client := u.client
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Get(queryUrl)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("resolver %s returned status %d for %s", u.url, resp.StatusCode, did)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (u *UniverseDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	body, err := u.fetch(did)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +241,7 @@ func (u *UniverseDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
 				}
 			}
 		}
-		didDoc := u.didDocJsonToDidDoc(didDocJSON)
+		didDoc := didDocJsonToDidDoc(didDocJSON)
 		return didDoc, nil
 	}
 
@@ -149,16 +253,7 @@ func (u *UniverseDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
 }
 
 func (u *UniverseDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
-	queryUrl, err := url.JoinPath(config.CurrentConfiguration.DidComm.ResolverUrl, "/1.0/identifiers/", did)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := http.Get(queryUrl)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	body, err := u.fetch(did)
 	if err != nil {
 		return nil, err
 	}
@@ -196,41 +291,61 @@ func (u *UniverseDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, er
 	return &didDocJSON, err
 }
 
-func (u *UniverseDidResolver) didDocJsonToDidDoc(ddJson DidDocumentJSON) *didcomm.DidDoc {
+func didDocJsonToDidDoc(ddJson DidDocumentJSON) *didcomm.DidDoc {
 	// Convert VerificationMethods
 	var VerificationMethods []didcomm.VerificationMethod = make([]didcomm.VerificationMethod, len(ddJson.DidDocument.VerificationMethod))
 	for i, vm := range ddJson.DidDocument.VerificationMethod {
-		VerificationMethodType, err := getVerificationType(vm.Type)
+		vmType := vm.Type
+		if vmType == "Multikey" {
+			// the concrete type is given by the multicodec prefix of the key
+			if t, err := multikeyType(vm.PublicKeyMultibase); err == nil {
+				vmType = t
+			}
+			if vmType == "JsonWebKey2020" && len(vm.PublicKeyJwk) == 0 {
+				if jwk, err := multikeyJwk(vm.PublicKeyMultibase); err == nil {
+					vm.PublicKeyJwk = jwk
+				}
+			}
+		}
+		VerificationMethodType, err := getVerificationType(vmType)
 		if err != nil {
 			config.Logger.Error("didDocJsonToDidDoc:", "err", err)
 		}
+		var material didcomm.VerificationMaterial
+		switch {
+		case len(vm.PublicKeyJwk) > 0:
+			material = didcomm.VerificationMaterialJwk{PublicKeyJwk: didcomm.JsonValue(vm.PublicKeyJwk)}
+		case vm.PublicKeyBase58 != "":
+			material = didcomm.VerificationMaterialBase58{PublicKeyBase58: vm.PublicKeyBase58}
+		default:
+			material = didcomm.VerificationMaterialMultibase{PublicKeyMultibase: vm.PublicKeyMultibase}
+		}
 		VerificationMethods[i] = didcomm.VerificationMethod{
-			Id:         vm.ID,
-			Type:       VerificationMethodType,
-			Controller: vm.Controller,
-			VerificationMaterial: didcomm.VerificationMaterialMultibase{
-				PublicKeyMultibase: vm.PublicKeyMultibase,
-			},
+			Id:                   vm.ID,
+			Type:                 VerificationMethodType,
+			Controller:           vm.Controller,
+			VerificationMaterial: material,
 		}
 	}
 
 	// Convert Services
-	var Services []didcomm.Service = make([]didcomm.Service, len(ddJson.DidDocument.Service))
-	for i, s := range ddJson.DidDocument.Service {
+	var Services []didcomm.Service = []didcomm.Service{}
+	for _, s := range ddJson.DidDocument.Service {
 		if s.Type != "DIDCommMessaging" {
 			// MUST be DIDCommMessaging see https://identity.foundation/didcomm-messaging/spec/#service-endpoint
 			continue
 		}
-		Services[i] = didcomm.Service{
+		accept := s.ServiceEndpoint.Accept
+		Services = append(Services, didcomm.Service{
 			Id: s.ID,
 			ServiceEndpoint: didcomm.ServiceKindDidCommMessaging{
 				Value: didcomm.DidCommMessagingService{
 					Uri:         s.ServiceEndpoint.Uri,
-					Accept:      &s.ServiceEndpoint.Accept,
+					Accept:      &accept,
 					RoutingKeys: s.ServiceEndpoint.RoutingKeys,
 				},
 			},
-		}
+		})
 	}
 
 	didDoc := didcomm.DidDoc{
@@ -254,7 +369,7 @@ func getVerificationType(stype string) (didcomm.VerificationMethodType, error) {
 	// VerificationMethodTypeOther                             VerificationMethodType = 7
 
 	switch stype {
-	case "JsonWebKey2020":
+	case "JsonWebKey2020", "JsonWebKey":
 		return didcomm.VerificationMethodTypeJsonWebKey2020, nil
 	case "X25519KeyAgreementKey2019":
 		return didcomm.VerificationMethodTypeX25519KeyAgreementKey2019, nil
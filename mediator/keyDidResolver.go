@@ -0,0 +1,51 @@
+package mediator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+)
+
+// https://w3c-ccg.github.io/did-method-key/
+
+var ErrInvalidKeyDid = errors.New("invalid did:key")
+
+// KeyDidResolver resolves did:key without calling an external resolver
+type KeyDidResolver struct{}
+
+func NewKeyDidResolver() *KeyDidResolver {
+	return &KeyDidResolver{}
+}
+
+func (k *KeyDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	didDoc, err := k.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+func (k *KeyDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	didDocJSON, err := k.ResolveDidAsJson(did)
+	if err != nil {
+		return nil, err
+	}
+	return didDocJsonToDidDoc(*didDocJSON), nil
+}
+
+func (k *KeyDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	key := strings.TrimPrefix(did, "did:key:")
+	if key == did || !strings.HasPrefix(key, "z") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyDid, did)
+	}
+	// the verification method ids keep the full multibase value as fragment
+	doc, err := resolveInceptionKey(did, key, func(did string, publicKeyMultibase string) string {
+		return did + "#" + publicKeyMultibase
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyDid, err.Error())
+	}
+	return &DidDocumentJSON{
+		DidDocument:               *doc,
+		DidResolutionMetadataJSON: DidResolutionMetadataJSON{ContentType: "application/did+ld+json"},
+	}, nil
+}
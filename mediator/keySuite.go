@@ -0,0 +1,255 @@
+package mediator
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+	"github.com/google/uuid"
+)
+
+const (
+	multicodecP256Pub      = 0x1200
+	multicodecP384Pub      = 0x1201
+	multicodecSecp256k1Pub = 0xe7
+
+	crvP256      = "P-256"
+	crvP384      = "P-384"
+	crvSecp256k1 = "secp256k1"
+)
+
+// keyPair is a generated public multikey together with the secret of its private key
+type keyPair struct {
+	public string
+	secret didcomm.Secret
+}
+
+// ecJwk is the JWK of a P-256, P-384 or secp256k1 key, D is only set for private keys
+type ecJwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+}
+
+// generateKeyPairs generates the key agreement and the authentication key of a key suite.
+// P-384 is only used for key agreement, the didcomm library can't sign with ES384 and signs with P-256 instead.
+func generateKeyPairs(keySuite string) (encryption keyPair, signing keyPair, err error) {
+	switch keySuite {
+	case config.KEY_SUITE_P256:
+		if encryption, err = generateP256KeyPair(); err != nil {
+			return
+		}
+		signing, err = generateP256KeyPair()
+	case config.KEY_SUITE_P384:
+		if encryption, err = generateP384KeyPair(); err != nil {
+			return
+		}
+		signing, err = generateP256KeyPair()
+	case config.KEY_SUITE_SECP256K1:
+		if encryption, err = generateSecp256k1KeyPair(); err != nil {
+			return
+		}
+		signing, err = generateSecp256k1KeyPair()
+	default:
+		if encryption, err = generateX25519KeyPair(); err != nil {
+			return
+		}
+		signing, err = generateEd25519KeyPair()
+	}
+	return
+}
+
+func generateX25519KeyPair() (keyPair, error) {
+	public, private, err := generateX25519Base58BTC()
+	if err != nil {
+		return keyPair{}, err
+	}
+	material := didcomm.SecretMaterialMultibase{PrivateKeyMultibase: private}
+	return keyPair{public, *createSecretFromKeyPair(public, material, didcomm.SecretTypeX25519KeyAgreementKey2020)}, nil
+}
+
+func generateEd25519KeyPair() (keyPair, error) {
+	public, private, err := generateEd25519Base58BTC()
+	if err != nil {
+		return keyPair{}, err
+	}
+	material := didcomm.SecretMaterialMultibase{PrivateKeyMultibase: private}
+	return keyPair{public, *createSecretFromKeyPair(public, material, didcomm.SecretTypeEd25519VerificationKey2020)}, nil
+}
+
+func generateP256KeyPair() (keyPair, error) {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return keyPair{}, err
+	}
+	// uncompressed point 0x04 || x || y
+	point := private.PublicKey().Bytes()
+	return newEcKeyPair(multicodecP256Pub, crvP256, point[1:33], point[33:], private.Bytes())
+}
+
+func generateP384KeyPair() (keyPair, error) {
+	private, err := ecdh.P384().GenerateKey(rand.Reader)
+	if err != nil {
+		return keyPair{}, err
+	}
+	// uncompressed point 0x04 || x || y
+	point := private.PublicKey().Bytes()
+	return newEcKeyPair(multicodecP384Pub, crvP384, point[1:49], point[49:], private.Bytes())
+}
+
+func generateSecp256k1KeyPair() (keyPair, error) {
+	private, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return keyPair{}, err
+	}
+	// uncompressed point 0x04 || x || y
+	point := private.PubKey().SerializeUncompressed()
+	return newEcKeyPair(multicodecSecp256k1Pub, crvSecp256k1, point[1:33], point[33:], private.Serialize())
+}
+
+func newEcKeyPair(codec uint64, crv string, x []byte, y []byte, d []byte) (keyPair, error) {
+	public, err := encodeMultikey(codec, compressPoint(x, y))
+	if err != nil {
+		return keyPair{}, err
+	}
+	jwk, err := json.Marshal(ecJwk{
+		Kty: "EC",
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(x),
+		Y:   base64.RawURLEncoding.EncodeToString(y),
+		D:   base64.RawURLEncoding.EncodeToString(d),
+	})
+	if err != nil {
+		return keyPair{}, err
+	}
+	material := didcomm.SecretMaterialJwk{PrivateKeyJwk: string(jwk)}
+	return keyPair{public, *createSecretFromKeyPair(public, material, didcomm.SecretTypeJsonWebKey2020)}, nil
+}
+
+// compressPoint encodes a point as 0x02 or 0x03 for the parity of y followed by x
+func compressPoint(x []byte, y []byte) []byte {
+	return append([]byte{0x02 | y[len(y)-1]&1}, x...)
+}
+
+// multikeyJwk converts a compressed P-256, P-384 or secp256k1 multikey to the JWK used by JsonWebKey2020
+func multikeyJwk(publicKeyMultibase string) (json.RawMessage, error) {
+	codec, raw, err := decodeMultikey(publicKeyMultibase)
+	if err != nil {
+		return nil, err
+	}
+	var jwk ecJwk
+	switch codec {
+	case multicodecP256Pub:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), raw)
+		if x == nil {
+			return nil, fmt.Errorf("%w: invalid P-256 key", ErrInvalidPeerDid)
+		}
+		jwk = ecJwk{Kty: "EC", Crv: crvP256, X: encodeCoordinate(x, 32), Y: encodeCoordinate(y, 32)}
+	case multicodecP384Pub:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P384(), raw)
+		if x == nil {
+			return nil, fmt.Errorf("%w: invalid P-384 key", ErrInvalidPeerDid)
+		}
+		jwk = ecJwk{Kty: "EC", Crv: crvP384, X: encodeCoordinate(x, 48), Y: encodeCoordinate(y, 48)}
+	case multicodecSecp256k1Pub:
+		key, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid secp256k1 key", ErrInvalidPeerDid)
+		}
+		jwk = ecJwk{Kty: "EC", Crv: crvSecp256k1, X: encodeCoordinate(key.X(), 32), Y: encodeCoordinate(key.Y(), 32)}
+	default:
+		return nil, fmt.Errorf("%w: key type 0x%x has no JWK representation", ErrInvalidPeerDid, codec)
+	}
+	return json.Marshal(jwk)
+}
+
+// jwkMultikey is the inverse of multikeyJwk and accepts private keys as well
+func jwkMultikey(jwk string) (string, error) {
+	var key ecJwk
+	if err := json.Unmarshal([]byte(jwk), &key); err != nil {
+		return "", err
+	}
+	var codec uint64
+	size := 32
+	switch key.Crv {
+	case crvP256:
+		codec = multicodecP256Pub
+	case crvP384:
+		codec, size = multicodecP384Pub, 48
+	case crvSecp256k1:
+		codec = multicodecSecp256k1Pub
+	default:
+		return "", fmt.Errorf("unsupported curve %s", key.Crv)
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(key.X)
+	y, errY := base64.RawURLEncoding.DecodeString(key.Y)
+	if err := errors.Join(errX, errY); err != nil || len(x) != size || len(y) != size {
+		return "", errors.New("invalid JWK coordinates")
+	}
+	return encodeMultikey(codec, compressPoint(x, y))
+}
+
+// encodeCoordinate encodes a coordinate with the byte size of its curve
+func encodeCoordinate(c *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(c.FillBytes(make([]byte, size)))
+}
+
+// anonCryptAlg selects the content encryption of anoncrypt and forward messages,
+// the NIST suites keep to AES-GCM to stay within NIST approved algorithms
+func anonCryptAlg(keySuite string) didcomm.AnonCryptAlg {
+	if keySuite == config.KEY_SUITE_P256 || keySuite == config.KEY_SUITE_P384 {
+		return didcomm.AnonCryptAlgA256gcmEcdhEsA256kw
+	}
+	return didcomm.AnonCryptAlgA256cbcHs512EcdhEsA256kw
+}
+
+// checkKeySuite packs a message from and to a throwaway DID of the key suite and unpacks it again,
+// so a suite whose curves the linked didcomm library can't agree or sign on fails at the start
+// and not with the first message.
+func checkKeySuite(keySuite string, logger *slog.Logger) error {
+	encryption, signing, err := generateKeyPairs(keySuite)
+	if err != nil {
+		return err
+	}
+	did := fmt.Sprintf("did:peer:2.E%s.V%s", encryption.public, signing.public)
+	secrets := secretsresolver.NewDemo()
+	for _, pair := range []keyPair{encryption, signing} {
+		secret := pair.secret
+		secret.Id = did + "#" + secret.Id
+		if err = secrets.StoreSecret(secret); err != nil {
+			return err
+		}
+	}
+	check := &Mediator{Messages: didcomm.NewDidComm(NewPeerDidResolver(), secrets), Logger: logger}
+	message := didcomm.Message{
+		Id:   uuid.NewString(),
+		Type: "https://didcomm.org/trust-ping/2.0/ping",
+		Body: "{}",
+		From: &did,
+		To:   &[]string{did},
+	}
+	packed, err := check.PackEncryptedMessage(message, did, did)
+	if err != nil {
+		return fmt.Errorf("packing with key suite %s failed: %w", keySuite, err)
+	}
+	unpacked, err := check.UnpackMessage(packed)
+	if err != nil {
+		return fmt.Errorf("unpacking with key suite %s failed: %w", keySuite, err)
+	}
+	if unpacked.Id != message.Id {
+		return fmt.Errorf("key suite %s unpacked another message", keySuite)
+	}
+	return nil
+}
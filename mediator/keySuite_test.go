@@ -0,0 +1,94 @@
+package mediator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySuites(t *testing.T) {
+	tests := []struct {
+		keySuite       string
+		encryptionType string
+		signingType    string
+		signingAlg     string
+	}{
+		{config.KEY_SUITE_ED25519, "X25519KeyAgreementKey2020", "Ed25519VerificationKey2020", "EdDSA"},
+		{config.KEY_SUITE_P256, "JsonWebKey2020", "JsonWebKey2020", "ES256"},
+		{config.KEY_SUITE_P384, "JsonWebKey2020", "JsonWebKey2020", "ES256"},
+		{config.KEY_SUITE_SECP256K1, "JsonWebKey2020", "JsonWebKey2020", "ES256K"},
+	}
+	for _, test := range tests {
+		encryption, signing, err := generateKeyPairs(test.keySuite)
+		assert.Nil(t, err)
+
+		did := fmt.Sprintf("did:peer:2.E%s.V%s", encryption.public, signing.public)
+		doc, err := NewPeerDidResolver().ResolveDidAsJson(did)
+		assert.Nil(t, err)
+		vms := doc.DidDocument.VerificationMethod
+		assert.Len(t, vms, 2)
+		assert.Equal(t, test.encryptionType, vms[0].Type, test.keySuite)
+		assert.Equal(t, test.signingType, vms[1].Type, test.keySuite)
+		// the secrets are found by the fragment of the verification method
+		assert.Equal(t, did+"#"+encryption.secret.Id, vms[0].ID)
+		assert.Equal(t, did+"#"+signing.secret.Id, vms[1].ID)
+
+		if test.signingType == "JsonWebKey2020" {
+			var public, private ecJwk
+			assert.Nil(t, json.Unmarshal(vms[1].PublicKeyJwk, &public))
+			assert.Nil(t, json.Unmarshal([]byte(signing.secret.SecretMaterial.(didcomm.SecretMaterialJwk).PrivateKeyJwk), &private))
+			assert.Equal(t, private.X, public.X)
+			assert.Equal(t, private.Y, public.Y)
+			assert.Empty(t, public.D)
+			assert.Equal(t, didcomm.SecretTypeJsonWebKey2020, signing.secret.Type)
+		}
+
+		method, private, public, err := tokenKeys(signing.secret)
+		assert.Nil(t, err)
+		assert.Equal(t, test.signingAlg, method.Alg())
+		signed, err := jwt.NewWithClaims(method, jwt.MapClaims{"invitationId": "1"}).SignedString(private)
+		assert.Nil(t, err)
+		tok, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return public, nil })
+		assert.Nil(t, err)
+		assert.True(t, tok.Valid)
+	}
+}
+
+func TestMultikeyJwk(t *testing.T) {
+	// P-256 test vector of the did:key specification
+	jwk, err := multikeyJwk("zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"kty":"EC","crv":"P-256","x":"fyNYMN0976ci7xqiSdag3buk-ZCwgXU4kz9XNkBlNUI","y":"hW2ojTNfH7Jbi8--CJUo3OCbH3y5n91g-IMA9MLMbTU"}`, string(jwk))
+
+	multikey, err := jwkMultikey(string(jwk))
+	assert.Nil(t, err)
+	assert.Equal(t, "zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169", multikey)
+
+	_, err = multikeyJwk("z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp")
+	assert.ErrorIs(t, err, ErrInvalidPeerDid)
+
+	// P-384 test vector of the did:key specification
+	jwk, err = multikeyJwk("z82Lm1MpAkeJcix9K8TMiLd5NMAhnwkjjCBeWHXyu3U4oT2MVJJKXkcVBgjGhnLBn2Kaau9")
+	assert.Nil(t, err)
+	var p384 ecJwk
+	assert.Nil(t, json.Unmarshal(jwk, &p384))
+	assert.Equal(t, "P-384", p384.Crv)
+	assert.Equal(t, "lInTxl8fjLKp_UCrxI0WDklahi-7-_6JbtiHjiRvMvhedhKVdHBfi2HCY8t_QJyc", p384.X)
+	multikey, err = jwkMultikey(string(jwk))
+	assert.Nil(t, err)
+	assert.Equal(t, "z82Lm1MpAkeJcix9K8TMiLd5NMAhnwkjjCBeWHXyu3U4oT2MVJJKXkcVBgjGhnLBn2Kaau9", multikey)
+}
+
+func TestCheckKeySuite(t *testing.T) {
+	config.Logger = slog.Default()
+	for _, keySuite := range []string{config.KEY_SUITE_ED25519, config.KEY_SUITE_P256, config.KEY_SUITE_P384, config.KEY_SUITE_SECP256K1} {
+		assert.Nil(t, checkKeySuite(keySuite, slog.Default()), keySuite)
+	}
+}
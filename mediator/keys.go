@@ -0,0 +1,356 @@
+package mediator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+)
+
+const (
+	KEY_PURPOSE_KEY_AGREEMENT  = "keyAgreement"
+	KEY_PURPOSE_AUTHENTICATION = "authentication"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	// the keys of a did:peer are part of the DID, changing them means creating another DID
+	ErrKeysNotChangeable = errors.New("keys can only be changed for the did:web of the mediator")
+	ErrLastKey           = errors.New("the last active key of a purpose can not be retired")
+)
+
+// Key is the public part of a stored secret
+type Key struct {
+	Id                 string     `json:"id"`
+	Did                string     `json:"did"`
+	Purpose            string     `json:"purpose,omitempty"`
+	PublicKeyMultibase string     `json:"publicKeyMultibase"`
+	PublicKeyJwk       Jwk        `json:"publicKeyJwk"`
+	Added              time.Time  `json:"added"`
+	Retired            *time.Time `json:"retired,omitempty"`
+}
+
+// Jwk is a public key as published in the JWKS
+type Jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+type cachedJwks struct {
+	jwks Jwks
+	// a retired key drops out of the JWKS at this time, zero if no key does
+	expires time.Time
+}
+
+// Keys lists the public parts of the secrets of a DID, all secrets if the DID is empty
+func (m *Mediator) Keys(did string) ([]Key, error) {
+	secrets, err := m.SecretsResolver.ListSecrets(did)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Id < secrets[j].Id
+	})
+	keys := []Key{}
+	for _, secret := range secrets {
+		key, err := newKey(secret)
+		if err != nil {
+			m.Logger.Warn("Secret without public key", "id", secret.Id, "msg", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// GenerateKeys adds a key agreement and an authentication key to the did:web of the mediator,
+// the new keys are used for signing while the former keys stay valid
+func (m *Mediator) GenerateKeys() ([]Key, error) {
+	m.keysLock.Lock()
+	defer m.keysLock.Unlock()
+	return m.generateKeys()
+}
+
+// RotateKeys generates new keys and retires all former keys of the did:web of the mediator
+func (m *Mediator) RotateKeys() ([]Key, error) {
+	m.keysLock.Lock()
+	defer m.keysLock.Unlock()
+	former, err := m.SecretsResolver.ListSecrets(m.Did)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := m.generateKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range former {
+		if secret.IsRetired() || webDidKeyIndex(secret.Id) == 0 {
+			continue
+		}
+		if err = m.SecretsResolver.RetireSecret(secret.Id); err != nil {
+			return nil, err
+		}
+	}
+	return keys, m.refreshDidDocument()
+}
+
+// RetireKey removes a key from the did:web of the mediator, the secret is kept
+// to decrypt messages and verify tokens which were created before
+func (m *Mediator) RetireKey(id string) error {
+	m.keysLock.Lock()
+	defer m.keysLock.Unlock()
+	secrets, err := m.SecretsResolver.ListSecrets(secretsresolver.SecretDid(id))
+	if err != nil {
+		return err
+	}
+	var retired *secretsresolver.StoredSecret
+	for i := range secrets {
+		if secrets[i].Id == id {
+			retired = &secrets[i]
+		}
+	}
+	if retired == nil {
+		return ErrKeyNotFound
+	}
+	if m.Document() == nil || secretsresolver.SecretDid(id) != m.Did || webDidKeyIndex(id) == 0 {
+		return ErrKeysNotChangeable
+	}
+	if retired.IsRetired() {
+		return nil
+	}
+	purpose := keyPurpose(retired.Id, retired.Type)
+	active := 0
+	for _, secret := range secrets {
+		if !secret.IsRetired() && webDidKeyIndex(secret.Id) > 0 && keyPurpose(secret.Id, secret.Type) == purpose {
+			active++
+		}
+	}
+	if active <= 1 {
+		return ErrLastKey
+	}
+	if err = m.SecretsResolver.RetireSecret(id); err != nil {
+		return err
+	}
+	return m.refreshDidDocument()
+}
+
+// Jwks returns the signing keys of the mediator DID. Retired keys are kept as long as tokens
+// signed by them can be valid. The keys are cached until they change or a retired key expires.
+func (m *Mediator) Jwks() (Jwks, error) {
+	m.documentLock.RLock()
+	cached, version := m.jwks, m.keysVersion
+	m.documentLock.RUnlock()
+	if cached != nil && (cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.jwks, nil
+	}
+	secrets, err := m.SecretsResolver.ListSecrets(m.Did)
+	if err != nil {
+		return Jwks{}, err
+	}
+	cached, err = newJwks(secrets)
+	if err != nil {
+		return Jwks{}, err
+	}
+	m.documentLock.Lock()
+	// keys which changed in the meantime are not overwritten by the former ones
+	if m.keysVersion == version {
+		m.jwks = cached
+	}
+	m.documentLock.Unlock()
+	return cached.jwks, nil
+}
+
+// ReloadKeys builds the DID document of a did:web mediator again if another instance changed its keys
+func (m *Mediator) ReloadKeys() error {
+	if m.Document() == nil {
+		return nil
+	}
+	secrets, err := m.SecretsResolver.ListSecrets(m.Did)
+	if err != nil {
+		return err
+	}
+	m.documentLock.RLock()
+	changed := keysVersion(secrets) != m.keysVersion
+	m.documentLock.RUnlock()
+	if !changed {
+		return nil
+	}
+	m.keysLock.Lock()
+	defer m.keysLock.Unlock()
+	m.Logger.Info("Keys of the mediator DID changed, reloading the DID document", "did", m.Did)
+	return m.refreshDidDocument()
+}
+
+// WatchKeys reloads the keys in the interval until the context is done
+func (m *Mediator) WatchKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.ReloadKeys(); err != nil {
+				m.Logger.Error("Unable to reload the keys of the mediator DID", "msg", err)
+			}
+		}
+	}
+}
+
+func newJwks(secrets []secretsresolver.StoredSecret) (*cachedJwks, error) {
+	expiration := time.Minute * time.Duration(config.CurrentConfiguration.TokenExpiration)
+	cached := &cachedJwks{jwks: Jwks{Keys: []Jwk{}}}
+	for _, secret := range secrets {
+		if keyPurpose(secret.Id, secret.Type) != KEY_PURPOSE_AUTHENTICATION {
+			continue
+		}
+		if secret.IsRetired() {
+			expires := secret.Retired.Add(expiration)
+			if !time.Now().Before(expires) {
+				continue
+			}
+			if cached.expires.IsZero() || expires.Before(cached.expires) {
+				cached.expires = expires
+			}
+		}
+		key, err := newKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		method, _, _, err := tokenKeys(secret.Secret)
+		if err != nil {
+			return nil, err
+		}
+		jwk := key.PublicKeyJwk
+		jwk.Kid = secret.Id
+		jwk.Use = "sig"
+		jwk.Alg = method.Alg()
+		cached.jwks.Keys = append(cached.jwks.Keys, jwk)
+	}
+	return cached, nil
+}
+
+// keysVersion identifies the keys and their retirements, it changes with every generated, rotated or retired key
+func keysVersion(secrets []secretsresolver.StoredSecret) string {
+	versions := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		version := secret.Id
+		if secret.IsRetired() {
+			version += "@" + strconv.FormatInt(secret.Retired.UnixNano(), 10)
+		}
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, " ")
+}
+
+func (m *Mediator) generateKeys() ([]Key, error) {
+	if m.Document() == nil {
+		return nil, ErrKeysNotChangeable
+	}
+	secrets, err := m.SecretsResolver.ListSecrets(m.Did)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	for _, secret := range secrets {
+		index = max(index, webDidKeyIndex(secret.Id))
+	}
+	created, err := createWebDidKeys(m.Did, index+1, m.SecretsResolver)
+	if err != nil {
+		return nil, err
+	}
+	keys := []Key{}
+	for _, secret := range created {
+		key, err := newKey(secretsresolver.StoredSecret{Secret: secret, Added: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, m.refreshDidDocument()
+}
+
+func newKey(secret secretsresolver.StoredSecret) (Key, error) {
+	publicKeyMultibase, err := publicKeyFromSecret(secret.Secret)
+	if err != nil {
+		return Key{}, err
+	}
+	jwk, err := publicJwk(publicKeyMultibase)
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{
+		Id:                 secret.Id,
+		Did:                secretsresolver.SecretDid(secret.Id),
+		Purpose:            keyPurpose(secret.Id, secret.Type),
+		PublicKeyMultibase: publicKeyMultibase,
+		PublicKeyJwk:       jwk,
+		Added:              secret.Added,
+	}
+	if secret.IsRetired() {
+		key.Retired = &secret.Retired
+	}
+	return key, nil
+}
+
+// keyPurpose is taken from the fragment of a did:web key or the purpose code of a did:peer:2 key,
+// other keys are classified by their type
+func keyPurpose(id string, secretType didcomm.SecretType) string {
+	did, fragment, _ := strings.Cut(id, "#")
+	switch {
+	case strings.HasPrefix("#"+fragment, webDidKeyAgreementFragment):
+		return KEY_PURPOSE_KEY_AGREEMENT
+	case strings.HasPrefix("#"+fragment, webDidAuthenticationFragment):
+		return KEY_PURPOSE_AUTHENTICATION
+	case strings.HasPrefix(did, "did:peer:2") && strings.Contains(did, ".Ez"+fragment):
+		return KEY_PURPOSE_KEY_AGREEMENT
+	case strings.HasPrefix(did, "did:peer:2") && strings.Contains(did, ".Vz"+fragment):
+		return KEY_PURPOSE_AUTHENTICATION
+	}
+	switch secretType {
+	case didcomm.SecretTypeX25519KeyAgreementKey2019, didcomm.SecretTypeX25519KeyAgreementKey2020:
+		return KEY_PURPOSE_KEY_AGREEMENT
+	case didcomm.SecretTypeEd25519VerificationKey2018, didcomm.SecretTypeEd25519VerificationKey2020, didcomm.SecretTypeEcdsaSecp256k1VerificationKey2019:
+		return KEY_PURPOSE_AUTHENTICATION
+	}
+	return ""
+}
+
+// publicJwk converts a multikey to its JWK, Ed25519 and X25519 keys are octet key pairs
+func publicJwk(publicKeyMultibase string) (Jwk, error) {
+	codec, raw, err := decodeMultikey(publicKeyMultibase)
+	if err != nil {
+		return Jwk{}, err
+	}
+	switch codec {
+	case multicodecEd25519Pub:
+		return Jwk{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(raw)}, nil
+	case multicodecX25519Pub:
+		return Jwk{Kty: "OKP", Crv: "X25519", X: base64.RawURLEncoding.EncodeToString(raw)}, nil
+	}
+	ecJwk, err := multikeyJwk(publicKeyMultibase)
+	if err != nil {
+		return Jwk{}, err
+	}
+	var jwk Jwk
+	err = json.Unmarshal(ecJwk, &jwk)
+	return jwk, err
+}
@@ -0,0 +1,118 @@
+package mediator
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLifecycle(t *testing.T) {
+	config.CurrentConfiguration.Url = "https://mediator.example.com"
+	config.CurrentConfiguration.TokenExpiration = 60
+	did := "did:web:mediator.example.com"
+	m := &Mediator{
+		SecretsResolver: secretsresolver.NewDemo(),
+		DidResolver:     NewResolverChain(),
+		Did:             did,
+		Logger:          slog.Default(),
+	}
+	assert.Nil(t, CreateWebDidKeys(did, m.SecretsResolver))
+	assert.Nil(t, m.refreshDidDocument())
+
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix(), "invitationId": "did:peer:2.abc"}
+	token, err := GenerateSignedToken(did, claims, m.SecretsResolver, m.DidResolver)
+	assert.Nil(t, err)
+
+	keys, err := m.RotateKeys()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, []string{did + "#authentication-2"}, m.Document().Authentication)
+	assert.Equal(t, []string{did + "#key-agreement-2"}, m.Document().KeyAgreement)
+
+	// tokens of the retired key stay valid and the key stays in the JWKS
+	invitationId, err := VerifySignedToken(token, did, m.SecretsResolver, m.DidResolver)
+	assert.Nil(t, err)
+	assert.Equal(t, "did:peer:2.abc", invitationId)
+	jwks, err := m.Jwks()
+	assert.Nil(t, err)
+	assert.Len(t, jwks.Keys, 2)
+	for _, jwk := range jwks.Keys {
+		assert.Equal(t, "EdDSA", jwk.Alg)
+		assert.Equal(t, "sig", jwk.Use)
+	}
+
+	assert.ErrorIs(t, m.RetireKey(did+"#authentication-2"), ErrLastKey)
+	assert.ErrorIs(t, m.RetireKey(did+"#authentication-9"), ErrKeyNotFound)
+	assert.ErrorIs(t, m.RetireKey(secretsresolver.DID+"#6Mkno2XmnAxWb7YbyDJw9hmqWcTuAwQKbtaiw9tjqRjDvMz"), ErrKeysNotChangeable)
+
+	_, err = m.GenerateKeys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{did + "#authentication-3", did + "#authentication-2"}, m.Document().Authentication)
+	assert.Nil(t, m.RetireKey(did+"#authentication-2"))
+	assert.Equal(t, []string{did + "#authentication-3"}, m.Document().Authentication)
+
+	keys, err = m.Keys(did)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 6)
+	retired := 0
+	for _, key := range keys {
+		if key.Retired != nil {
+			retired++
+		}
+		assert.NotEmpty(t, key.Purpose)
+		assert.Equal(t, "OKP", key.PublicKeyJwk.Kty)
+	}
+	assert.Equal(t, 3, retired)
+}
+
+func TestReloadKeys(t *testing.T) {
+	config.CurrentConfiguration.Url = "https://mediator.example.com"
+	config.CurrentConfiguration.TokenExpiration = 60
+	did := "did:web:mediator.example.com"
+	secrets := secretsresolver.NewDemo()
+	assert.Nil(t, CreateWebDidKeys(did, secrets))
+	// two instances share the secrets database
+	m := &Mediator{SecretsResolver: secrets, DidResolver: NewResolverChain(), Did: did, Logger: slog.Default()}
+	other := &Mediator{SecretsResolver: secrets, DidResolver: NewResolverChain(), Did: did, Logger: slog.Default()}
+	assert.Nil(t, m.refreshDidDocument())
+	assert.Nil(t, other.refreshDidDocument())
+	jwks, err := other.Jwks()
+	assert.Nil(t, err)
+	assert.Len(t, jwks.Keys, 1)
+
+	_, err = m.RotateKeys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{did + "#authentication-1"}, other.Document().Authentication)
+	jwks, err = other.Jwks()
+	assert.Nil(t, err)
+	assert.Len(t, jwks.Keys, 1)
+
+	assert.Nil(t, other.ReloadKeys())
+	assert.Equal(t, []string{did + "#authentication-2"}, other.Document().Authentication)
+	jwks, err = other.Jwks()
+	assert.Nil(t, err)
+	assert.Len(t, jwks.Keys, 2)
+}
+
+func TestKeys_PeerDid(t *testing.T) {
+	m := &Mediator{
+		SecretsResolver: secretsresolver.NewDemo(),
+		DidResolver:     NewResolverChain(),
+		Did:             secretsresolver.DID,
+		Logger:          slog.Default(),
+	}
+	_, err := m.GenerateKeys()
+	assert.ErrorIs(t, err, ErrKeysNotChangeable)
+
+	keys, err := m.Keys(secretsresolver.DID)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	purposes := []string{keys[0].Purpose, keys[1].Purpose}
+	assert.ElementsMatch(t, []string{KEY_PURPOSE_KEY_AGREEMENT, KEY_PURPOSE_AUTHENTICATION}, purposes)
+}
@@ -1,14 +1,20 @@
 package mediator
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/cloudevent"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	connectionManager "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/connectionManager"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
 	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
 )
 
@@ -19,7 +25,21 @@ type Mediator struct {
 	DidResolver       DidResolver
 	Did               string
 	Database          database.Adapter
+	Outbox            *outbox.Outbox
+	Publishers        *cloudevent.Pool
 	Logger            *slog.Logger
+	// document of a did:web mediator, nil for a did:peer mediator
+	DidDocument *DidDocument
+	// nil if the cache is disabled
+	ResolverCache *CachingDidResolver
+
+	documentLock sync.RWMutex
+	// ids and retirements of the keys the document was built from, compared by ReloadKeys
+	keysVersion string
+	// signing keys of the JWKS, nil until they are built again
+	jwks *cachedJwks
+	// serializes generating, rotating and retiring keys
+	keysLock sync.Mutex
 }
 
 func NewMediator(logger *slog.Logger) *Mediator {
@@ -33,12 +53,19 @@ func NewMediator(logger *slog.Logger) *Mediator {
 		m.Database = database.NewCassandra()
 	}
 
+	m.Outbox = outbox.NewOutbox(m.Database)
+	m.Publishers = cloudevent.NewPool(time.Duration(config.CurrentConfiguration.CloudForwarding.Publishers.IdleTimeout) * time.Second)
+
 	// create connection manager
 	connectionManager := connectionManager.NewConnectionManager(m.Database)
 	m.ConnectionManager = connectionManager
 
 	// create DidResolver
 	m.DidResolver = NewDidResolver()
+	if cache := config.CurrentConfiguration.DidComm.ResolverCache; cache.MaxEntries > 0 {
+		m.ResolverCache = NewCachingDidResolver(m.DidResolver, cache.MaxEntries, time.Duration(cache.Ttl)*time.Second, time.Duration(cache.NegativeTtl)*time.Second)
+		m.DidResolver = m.ResolverCache
+	}
 
 	if config.CurrentConfiguration.Database.InMemory {
 		m.SecretsResolver = secretsresolver.NewDemo()
@@ -46,6 +73,12 @@ func NewMediator(logger *slog.Logger) *Mediator {
 		m.SecretsResolver = secretsresolver.NewCassandra()
 	}
 
+	// fail before any DID gets keys which the didcomm library can't use
+	if err := checkKeySuite(config.CurrentConfiguration.DidComm.KeySuite, logger); err != nil {
+		config.Logger.Error("Key suite is not supported by the didcomm library", "keySuite", config.CurrentConfiguration.DidComm.KeySuite, "msg", err)
+		panic("Mediator can not be used without a supported key suite")
+	}
+
 	// create peer did of mediator
 	m.createDidIfNeeded()
 
@@ -55,6 +88,12 @@ func NewMediator(logger *slog.Logger) *Mediator {
 	return &m
 }
 
+// Close stops publishing the outbox after the current events, then closes the publishers and the database
+func (m *Mediator) Close() error {
+	m.Outbox.Stop()
+	return errors.Join(m.Publishers.Close(), m.Database.Close())
+}
+
 func (m *Mediator) createDidIfNeeded() {
 
 	// check
@@ -63,7 +102,12 @@ func (m *Mediator) createDidIfNeeded() {
 	if err != nil {
 		config.Logger.Error("Unable to get mediator did", "msg", err)
 	}
-	if peerDid == "" {
+	if config.CurrentConfiguration.DidComm.WebDid != "" {
+		m.createWebDidIfNeeded(peerDid)
+		return
+	}
+	// a did:web mediator which is configured back to did:peer gets a new peer did
+	if peerDid == "" || strings.HasPrefix(peerDid, "did:web:") {
 		services, err := m.CreateMediatorService()
 		if err != nil {
 			config.Logger.Error("Unable to create mediator service", "msg", err)
@@ -87,6 +131,63 @@ func (m *Mediator) createDidIfNeeded() {
 
 }
 
+func (m *Mediator) createWebDidIfNeeded(storedDid string) {
+	webDid := config.CurrentConfiguration.DidComm.WebDid
+	// a changed did:web gets new keys, unless it was used before and still has active keys
+	if storedDid != webDid {
+		err := CreateWebDidKeys(webDid, m.SecretsResolver)
+		if err != nil {
+			config.Logger.Error("Unable to create mediator DID", "msg", err)
+			panic("Mediator can not be used without a DID")
+		}
+		err = m.Database.StoreMediatorDid(webDid)
+		if err != nil {
+			config.Logger.Error("Unable to store mediator DID", "msg", err)
+			panic("Mediator can not be used without a DID")
+		}
+	}
+	m.Did = webDid
+	if err := m.refreshDidDocument(); err != nil {
+		config.Logger.Error("Unable to create mediator DID document", "msg", err)
+		panic("Mediator can not be used without a DID document")
+	}
+
+	config.Logger.Info(fmt.Sprintf("Mediator Web DID: %s", webDid))
+}
+
+// refreshDidDocument builds the document of a did:web mediator again after its keys changed
+func (m *Mediator) refreshDidDocument() error {
+	services, err := m.CreateMediatorService()
+	if err != nil {
+		return err
+	}
+	secrets, err := m.SecretsResolver.ListSecrets(m.Did)
+	if err != nil {
+		return err
+	}
+	doc, err := WebDidDocument(m.Did, services, m.SecretsResolver)
+	if err != nil {
+		return err
+	}
+	// the own document is not fetched over the network
+	if resolver, ok := m.DidResolver.(interface{ AddLocalDocument(DidDocument) }); ok {
+		resolver.AddLocalDocument(*doc)
+	}
+	m.documentLock.Lock()
+	defer m.documentLock.Unlock()
+	m.DidDocument = doc
+	m.keysVersion = keysVersion(secrets)
+	m.jwks = nil
+	return nil
+}
+
+// Document returns the document of a did:web mediator, nil for a did:peer mediator
+func (m *Mediator) Document() *DidDocument {
+	m.documentLock.RLock()
+	defer m.documentLock.RUnlock()
+	return m.DidDocument
+}
+
 func (m *Mediator) CreateMediatorService() (service []didcomm.Service, err error) {
 
 	s, err := CreateServiceEntry()
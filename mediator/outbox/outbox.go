@@ -0,0 +1,314 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEventNotFound = errors.New("outbox event not found")
+	ErrEventNotDead  = errors.New("only dead events can be replayed")
+	// the payload of the event is the DIDComm message, publishing it would bypass the mapping of the connection
+	ErrEventNotReplayable = errors.New("the cloud event of the message could not be created, it can not be replayed")
+)
+
+// leaseDuration is the time an instance has to publish a claimed event before another instance may take it over
+const leaseDuration = time.Minute
+
+// PublishFunc publishes the payload of the event to the topic
+type PublishFunc func(topic string, event database.OutboxEvent) error
+
+// Outbox stores cloud events before they are published and retries them until the broker accepts them.
+// Events which keep failing are dead, they are published to the dead-letter topic and kept for a replay.
+type Outbox struct {
+	database database.Adapter
+	wake     chan struct{}
+	quit     chan struct{}
+	mu       sync.Mutex
+	stopped  bool
+	running  sync.WaitGroup
+
+	maxAttempts      int
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	pollInterval     time.Duration
+	deadLetterTopic  string
+	concurrency      int
+}
+
+func NewOutbox(database database.Adapter) *Outbox {
+	outboxConfig := config.CurrentConfiguration.CloudForwarding.Outbox
+	return &Outbox{
+		database:         database,
+		wake:             make(chan struct{}, 1),
+		quit:             make(chan struct{}),
+		maxAttempts:      outboxConfig.MaxAttempts,
+		retryInterval:    time.Duration(outboxConfig.RetryInterval) * time.Second,
+		maxRetryInterval: time.Duration(outboxConfig.MaxRetryInterval) * time.Second,
+		pollInterval:     time.Duration(outboxConfig.PollInterval) * time.Second,
+		deadLetterTopic:  outboxConfig.DeadLetterTopic,
+		concurrency:      max(outboxConfig.Concurrency, 1),
+	}
+}
+
+// Add stores a pending event, it is published by Run
+func (o *Outbox) Add(event database.OutboxEvent) (database.OutboxEvent, error) {
+	event.Id = uuid.NewString()
+	event.Status = database.OUTBOX_STATUS_PENDING
+	event.Added = time.Now()
+	event.NextAttempt = event.Added
+	if err := o.database.AddOutboxEvent(event); err != nil {
+		return event, err
+	}
+	o.notify()
+	return event, nil
+}
+
+// AddDead stores an event which can not be published at all, e.g. because its payload could not be created.
+// It is only published to the dead-letter topic and can not be replayed.
+func (o *Outbox) AddDead(event database.OutboxEvent, cause error) (database.OutboxEvent, error) {
+	event.Id = uuid.NewString()
+	event.Status = database.OUTBOX_STATUS_DEAD
+	event.Unmapped = true
+	event.LastError = cause.Error()
+	event.Added = time.Now()
+	event.NextAttempt = event.Added
+	if err := o.database.AddOutboxEvent(event); err != nil {
+		return event, err
+	}
+	config.Logger.Error("Cloud event is dead", "id", event.Id, "topic", event.Topic, "err", cause)
+	o.notify()
+	return event, nil
+}
+
+// Events returns the events of a status in the order they were added
+func (o *Outbox) Events(status string) ([]database.OutboxEvent, error) {
+	events, err := o.database.GetOutboxEvents(status)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Added.Before(events[j].Added)
+	})
+	return events, nil
+}
+
+// Replay publishes a dead event again with a fresh number of attempts
+func (o *Outbox) Replay(id string) (database.OutboxEvent, error) {
+	event, err := o.database.GetOutboxEvent(id)
+	if err != nil {
+		return database.OutboxEvent{}, err
+	}
+	if event == nil {
+		return database.OutboxEvent{}, ErrEventNotFound
+	}
+	if event.Status != database.OUTBOX_STATUS_DEAD {
+		return *event, ErrEventNotDead
+	}
+	if event.Unmapped {
+		return *event, ErrEventNotReplayable
+	}
+	event.Status = database.OUTBOX_STATUS_PENDING
+	event.Attempts = 0
+	event.DeadLettered = false
+	event.NextAttempt = time.Now()
+	if err = o.database.UpdateOutboxEvent(*event); err != nil {
+		return *event, err
+	}
+	o.notify()
+	return *event, nil
+}
+
+func (o *Outbox) Delete(id string) error {
+	event, err := o.database.GetOutboxEvent(id)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		return ErrEventNotFound
+	}
+	return o.database.DeleteOutboxEvent(id)
+}
+
+// Run dispatches the events after every poll interval and whenever events are added, until the context is done
+// or the outbox is stopped
+func (o *Outbox) Run(ctx context.Context, publish PublishFunc) {
+	o.mu.Lock()
+	if o.stopped {
+		o.mu.Unlock()
+		return
+	}
+	o.running.Add(1)
+	o.mu.Unlock()
+	defer o.running.Done()
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		o.Dispatch(publish)
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.quit:
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Stop ends Run and waits until the events which are being published are done
+func (o *Outbox) Stop() {
+	o.mu.Lock()
+	if !o.stopped {
+		o.stopped = true
+		close(o.quit)
+	}
+	o.mu.Unlock()
+	o.running.Wait()
+}
+
+// Dispatch publishes the due events once. Events of a recipient are published in the order they were added,
+// a failed event holds back the later events of its recipient. Up to concurrency recipients are published in parallel.
+func (o *Outbox) Dispatch(publish PublishFunc) {
+	events, err := o.Events(database.OUTBOX_STATUS_PENDING)
+	if err != nil {
+		config.Logger.Error("Unable to read outbox", "err", err)
+		return
+	}
+	recipients := []string{}
+	queues := map[string][]database.OutboxEvent{}
+	for _, event := range events {
+		if _, ok := queues[event.RecipientDid]; !ok {
+			recipients = append(recipients, event.RecipientDid)
+		}
+		queues[event.RecipientDid] = append(queues[event.RecipientDid], event)
+	}
+
+	work := make(chan []database.OutboxEvent)
+	var workers sync.WaitGroup
+	for i := 0; i < min(o.concurrency, len(recipients)); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for queue := range work {
+				o.dispatchQueue(queue, publish)
+			}
+		}()
+	}
+	for _, recipient := range recipients {
+		work <- queues[recipient]
+	}
+	close(work)
+	workers.Wait()
+
+	if o.deadLetterTopic != "" {
+		o.deadLetter(publish)
+	}
+}
+
+// dispatchQueue publishes the events of one recipient until an event is not due or fails
+func (o *Outbox) dispatchQueue(events []database.OutboxEvent, publish PublishFunc) {
+	for _, event := range events {
+		if !o.claim(event) {
+			return
+		}
+		if err := publish(event.Topic, event); err != nil {
+			o.retry(event, err)
+			return
+		}
+		err := tracing.Database(eventContext(event), "DeleteOutboxEvent", func() error {
+			return o.database.DeleteOutboxEvent(event.Id)
+		})
+		if err != nil {
+			config.Logger.Error("Unable to delete published cloud event", "id", event.Id, "err", err)
+		}
+	}
+}
+
+func (o *Outbox) deadLetter(publish PublishFunc) {
+	events, err := o.Events(database.OUTBOX_STATUS_DEAD)
+	if err != nil {
+		config.Logger.Error("Unable to read outbox", "err", err)
+		return
+	}
+	for _, event := range events {
+		if event.DeadLettered || !o.claim(event) {
+			continue
+		}
+		if err := publish(o.deadLetterTopic, event); err != nil {
+			config.Logger.Warn("Publishing to the dead-letter topic failed", "id", event.Id, "topic", o.deadLetterTopic, "err", err)
+			event.NextAttempt = time.Now().Add(o.maxRetryInterval)
+		} else {
+			event.DeadLettered = true
+		}
+		if err := o.update(event); err != nil {
+			config.Logger.Error("Unable to update outbox event", "id", event.Id, "err", err)
+		}
+	}
+}
+
+func (o *Outbox) claim(event database.OutboxEvent) bool {
+	if event.NextAttempt.After(time.Now()) {
+		return false
+	}
+	claimed, err := tracing.Query(eventContext(event), "ClaimOutboxEvent", func() (bool, error) {
+		return o.database.ClaimOutboxEvent(event.Id, time.Now().Add(leaseDuration))
+	})
+	if err != nil {
+		config.Logger.Error("Unable to claim outbox event", "id", event.Id, "err", err)
+		return false
+	}
+	return claimed
+}
+
+func (o *Outbox) retry(event database.OutboxEvent, cause error) {
+	event.Attempts++
+	event.LastError = cause.Error()
+	if event.Attempts >= o.maxAttempts {
+		event.Status = database.OUTBOX_STATUS_DEAD
+		event.NextAttempt = time.Now()
+		config.Logger.Error("Cloud event is dead", "id", event.Id, "topic", event.Topic, "attempts", event.Attempts, "err", cause)
+	} else {
+		event.NextAttempt = time.Now().Add(o.backoff(event.Attempts))
+		config.Logger.Warn("Publishing cloud event failed", "id", event.Id, "topic", event.Topic, "attempts", event.Attempts, "retry", event.NextAttempt, "err", cause)
+	}
+	if err := o.update(event); err != nil {
+		config.Logger.Error("Unable to update outbox event", "id", event.Id, "err", err)
+	}
+}
+
+// update stores the event in a span of the trace of the message which added it
+func (o *Outbox) update(event database.OutboxEvent) error {
+	return tracing.Database(eventContext(event), "UpdateOutboxEvent", func() error {
+		return o.database.UpdateOutboxEvent(event)
+	})
+}
+
+// eventContext continues the trace of the message which added the event, the dispatcher has no context of its own
+func eventContext(event database.OutboxEvent) context.Context {
+	return tracing.WithTraceContext(context.Background(), event.TraceParent, event.TraceState)
+}
+
+// backoff doubles the retry interval with every failed attempt
+func (o *Outbox) backoff(attempts int) time.Duration {
+	interval := o.retryInterval
+	for i := 1; i < attempts && interval < o.maxRetryInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, o.maxRetryInterval)
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
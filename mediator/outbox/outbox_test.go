@@ -0,0 +1,170 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestOutbox() *Outbox {
+	config.Logger = slog.Default()
+	config.CurrentConfiguration.CloudForwarding.Outbox.MaxAttempts = 2
+	config.CurrentConfiguration.CloudForwarding.Outbox.RetryInterval = 5
+	config.CurrentConfiguration.CloudForwarding.Outbox.MaxRetryInterval = 300
+	config.CurrentConfiguration.CloudForwarding.Outbox.PollInterval = 5
+	config.CurrentConfiguration.CloudForwarding.Outbox.Concurrency = 1
+	config.CurrentConfiguration.CloudForwarding.Outbox.DeadLetterTopic = "dead-letter"
+	o := NewOutbox(database.NewDemo())
+	// retry immediately
+	o.retryInterval = 0
+	o.maxRetryInterval = 0
+	return o
+}
+
+func TestDispatch_Published(t *testing.T) {
+	o := newTestOutbox()
+	_, err := o.Add(database.OutboxEvent{RecipientDid: "did:peer:2.a", Topic: "devices", Payload: "{}"})
+	assert.Nil(t, err)
+
+	topics := []string{}
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		topics = append(topics, topic)
+		return nil
+	})
+	assert.Equal(t, []string{"devices"}, topics)
+	events, err := o.Events(database.OUTBOX_STATUS_PENDING)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+}
+
+func TestDispatch_DeadAndReplay(t *testing.T) {
+	o := newTestOutbox()
+	added, err := o.Add(database.OutboxEvent{RecipientDid: "did:peer:2.a", Topic: "devices", Payload: "{}"})
+	assert.Nil(t, err)
+
+	topics := []string{}
+	failing := func(topic string, event database.OutboxEvent) error {
+		topics = append(topics, topic)
+		if topic == "devices" {
+			return errors.New("broker down")
+		}
+		return nil
+	}
+	o.Dispatch(failing)
+	o.Dispatch(failing)
+	// the second failure is the last attempt, the dead event goes to the dead-letter topic once
+	o.Dispatch(failing)
+	assert.Equal(t, []string{"devices", "devices", "dead-letter"}, topics)
+
+	dead, err := o.Events(database.OUTBOX_STATUS_DEAD)
+	assert.Nil(t, err)
+	assert.Len(t, dead, 1)
+	assert.Equal(t, "broker down", dead[0].LastError)
+	assert.True(t, dead[0].DeadLettered)
+
+	_, err = o.Replay("unknown")
+	assert.ErrorIs(t, err, ErrEventNotFound)
+	replayed, err := o.Replay(added.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, database.OUTBOX_STATUS_PENDING, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	_, err = o.Replay(added.Id)
+	assert.ErrorIs(t, err, ErrEventNotDead)
+
+	o.Dispatch(func(topic string, event database.OutboxEvent) error { return nil })
+	pending, err := o.Events(database.OUTBOX_STATUS_PENDING)
+	assert.Nil(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestAddDead_NotReplayable(t *testing.T) {
+	o := newTestOutbox()
+	dead, err := o.AddDead(database.OutboxEvent{RecipientDid: "did:peer:2.a", Topic: "devices/{recipientDid}", Payload: `{"type":"device-message"}`}, errors.New("mapping failed"))
+	assert.Nil(t, err)
+	assert.True(t, dead.Unmapped)
+
+	// the DIDComm message is only published to the dead-letter topic
+	topics := []string{}
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		topics = append(topics, topic)
+		return nil
+	})
+	assert.Equal(t, []string{"dead-letter"}, topics)
+
+	_, err = o.Replay(dead.Id)
+	assert.ErrorIs(t, err, ErrEventNotReplayable)
+	events, err := o.Events(database.OUTBOX_STATUS_DEAD)
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		topics = append(topics, topic)
+		return nil
+	})
+	assert.Equal(t, []string{"dead-letter"}, topics)
+}
+
+func TestDispatch_RecipientOrder(t *testing.T) {
+	o := newTestOutbox()
+	o.maxAttempts = 10
+	first, _ := o.Add(database.OutboxEvent{RecipientDid: "did:peer:2.a", Topic: "devices", Payload: "1"})
+	o.Add(database.OutboxEvent{RecipientDid: "did:peer:2.a", Topic: "devices", Payload: "2"})
+	o.Add(database.OutboxEvent{RecipientDid: "did:peer:2.b", Topic: "devices", Payload: "3"})
+
+	published := []string{}
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		if event.Id == first.Id {
+			return errors.New("broker down")
+		}
+		published = append(published, event.Payload)
+		return nil
+	})
+	// the failed event holds back the later event of its recipient only
+	assert.Equal(t, []string{"3"}, published)
+
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		published = append(published, event.Payload)
+		return nil
+	})
+	assert.Equal(t, []string{"3", "1", "2"}, published)
+}
+
+func TestDispatch_Concurrent(t *testing.T) {
+	o := newTestOutbox()
+	o.concurrency = 4
+	for i := 0; i < 5; i++ {
+		for _, recipient := range []string{"did:peer:2.a", "did:peer:2.b", "did:peer:2.c"} {
+			o.Add(database.OutboxEvent{RecipientDid: recipient, Topic: "devices", Payload: fmt.Sprint(i)})
+		}
+	}
+
+	var mu sync.Mutex
+	published := map[string][]string{}
+	o.Dispatch(func(topic string, event database.OutboxEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		published[event.RecipientDid] = append(published[event.RecipientDid], event.Payload)
+		return nil
+	})
+	// recipients are published in parallel, the events of each recipient in order
+	for _, recipient := range []string{"did:peer:2.a", "did:peer:2.b", "did:peer:2.c"} {
+		assert.Equal(t, []string{"0", "1", "2", "3", "4"}, published[recipient])
+	}
+
+	done := make(chan struct{})
+	go func() {
+		o.Run(context.Background(), func(topic string, event database.OutboxEvent) error { return nil })
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	o.Stop()
+	<-done
+}
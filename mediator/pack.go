@@ -1,18 +1,24 @@
 package mediator
 
 import (
+	"errors"
+	"time"
+
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/callback"
 )
 
 func (m *Mediator) PackEncryptedMessage(message didcomm.Message, to string, from string) (response string, err error) {
+	defer func(start time.Time) { metrics.Observe(metrics.PackDuration, start, err, "pack_encrypted") }(time.Now())
 
 	// set PackEncryptedOptions
 	pencryptOpt := didcomm.PackEncryptedOptions{
 		ProtectSender: false,
 		Forward:       true,
 		EncAlgAuth:    didcomm.AuthCryptAlgA256cbcHs512Ecdh1puA256kw,
-		EncAlgAnon:    didcomm.AnonCryptAlgA256cbcHs512EcdhEsA256kw,
+		EncAlgAnon:    anonCryptAlg(config.CurrentConfiguration.DidComm.KeySuite),
 	}
 
 	// prepare callback
@@ -36,6 +42,7 @@ func (m *Mediator) PackEncryptedMessage(message didcomm.Message, to string, from
 }
 
 func (m *Mediator) PackPlainMessage(message didcomm.Message) (response string, err error) {
+	defer func(start time.Time) { metrics.Observe(metrics.PackDuration, start, err, "pack_plain") }(time.Now())
 	strCh := make(chan string, 1)
 	errCh := make(chan callback.PackErrorPair, 1)
 	cb := callback.NewPackResultCallback(strCh, errCh)
@@ -51,3 +58,33 @@ func (m *Mediator) PackPlainMessage(message didcomm.Message) (response string, e
 		return m, nil
 	}
 }
+
+func (m *Mediator) PackSignedMessage(message didcomm.Message, signBy string) (response string, err error) {
+	defer func(start time.Time) { metrics.Observe(metrics.PackDuration, start, err, "pack_signed") }(time.Now())
+	sucCh := make(chan callback.PackSignedSuccessPair, 1)
+	errCh := make(chan callback.PackSignedErrorPair, 1)
+	cb := callback.NewPackSignedResultCallback(sucCh, errCh)
+	dc := m.Messages
+	message.Typ = "application/didcomm-plain+json"
+	dc.PackSigned(message, signBy, cb)
+
+	select {
+	case e := <-errCh:
+		m.Logger.Error("Error packing signed message:", "msg", e.Msg)
+		return "", e.Err
+	case suc := <-sucCh:
+		return suc.Result, nil
+	}
+}
+
+// AuthenticationKid returns the id of the first authentication key of the mediator DID
+func (m *Mediator) AuthenticationKid() (string, error) {
+	doc, err := m.DidResolver.ResolveDid(m.Did)
+	if err != nil {
+		return "", err
+	}
+	if len(doc.Authentication) == 0 {
+		return "", errors.New("no authentication key found for mediator DID")
+	}
+	return doc.Authentication[0], nil
+}
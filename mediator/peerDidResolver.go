@@ -0,0 +1,430 @@
+package mediator
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+
+	multibase "github.com/multiformats/go-multibase"
+)
+
+// https://identity.foundation/peer-did-method-spec/
+
+const (
+	multicodecEd25519Pub = 0xed
+	multicodecX25519Pub  = 0xec
+	multicodecJson       = 0x0200
+	multihashSha256      = 0x12
+)
+
+// MAX_PEER_LONG_FORMS bounds the remembered did:peer:4 long forms, the least recently used ones are dropped first
+const MAX_PEER_LONG_FORMS = 10000
+
+var ErrInvalidPeerDid = errors.New("invalid did:peer")
+
+// PeerDidResolver resolves did:peer numalgo 0, 2 and 4 without calling an external resolver
+type PeerDidResolver struct {
+	// long form documents of did:peer:4, a short form DID can only be resolved after its long form was seen
+	mu           sync.Mutex
+	longForms    map[string]*list.Element
+	lru          *list.List
+	maxLongForms int
+}
+
+type longForm struct {
+	shortForm  string
+	encodedDoc string
+}
+
+func NewPeerDidResolver() *PeerDidResolver {
+	return &PeerDidResolver{longForms: map[string]*list.Element{}, lru: list.New(), maxLongForms: MAX_PEER_LONG_FORMS}
+}
+
+func (p *PeerDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	didDoc, err := p.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+func (p *PeerDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	didDocJSON, err := p.ResolveDidAsJson(did)
+	if err != nil {
+		return nil, err
+	}
+	return didDocJsonToDidDoc(*didDocJSON), nil
+}
+
+func (p *PeerDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	var doc *DidDocument
+	var err error
+	switch {
+	case strings.HasPrefix(did, "did:peer:0"):
+		doc, err = resolvePeerDid0(did)
+	case strings.HasPrefix(did, "did:peer:2"):
+		doc, err = resolvePeerDid2(did)
+	case strings.HasPrefix(did, "did:peer:4"):
+		doc, err = p.resolvePeerDid4(did)
+	default:
+		err = fmt.Errorf("%w: unsupported numalgo in %s", ErrInvalidPeerDid, did)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DidDocumentJSON{
+		DidDocument:               *doc,
+		DidResolutionMetadataJSON: DidResolutionMetadataJSON{ContentType: "application/did+ld+json"},
+	}, nil
+}
+
+func newDidDocument(did string) *DidDocument {
+	return &DidDocument{
+		Context:            []string{"https://www.w3.org/ns/did/v1"},
+		ID:                 did,
+		VerificationMethod: []VerificationMethod{},
+		Service:            []Service{},
+	}
+}
+
+// peerKeyId is the verification method id used by NumAlgo2 to store the secrets,
+// the fragment is the multibase key without the leading z
+func peerKeyId(did string, publicKeyMultibase string) string {
+	return did + "#" + publicKeyMultibase[1:]
+}
+
+func addMultibaseKey(doc *DidDocument, id string, publicKeyMultibase string) error {
+	vmType, err := multikeyType(publicKeyMultibase)
+	if err != nil {
+		return err
+	}
+	vm := VerificationMethod{
+		ID:         id,
+		Type:       vmType,
+		Controller: doc.ID,
+	}
+	// the didcomm library expects NIST and secp256k1 keys as JWK
+	if vmType == "JsonWebKey2020" {
+		if vm.PublicKeyJwk, err = multikeyJwk(publicKeyMultibase); err != nil {
+			return err
+		}
+	} else {
+		vm.PublicKeyMultibase = publicKeyMultibase
+	}
+	doc.VerificationMethod = append(doc.VerificationMethod, vm)
+	return nil
+}
+
+// https://identity.foundation/peer-did-method-spec/#method-0-inception-key-without-doc
+func resolvePeerDid0(did string) (*DidDocument, error) {
+	return resolveInceptionKey(did, strings.TrimPrefix(did, "did:peer:0"), peerKeyId)
+}
+
+// resolveInceptionKey builds the document of a single multikey as done by did:peer:0 and did:key,
+// an Ed25519 key is used for authentication and the key agreement key is derived from it,
+// a P-256 or P-384 key is used for both
+func resolveInceptionKey(did string, key string, keyId func(did string, publicKeyMultibase string) string) (*DidDocument, error) {
+	doc := newDidDocument(did)
+	codec, raw, err := decodeMultikey(key)
+	if err != nil {
+		return nil, err
+	}
+	id := keyId(did, key)
+	if err = addMultibaseKey(doc, id, key); err != nil {
+		return nil, err
+	}
+	switch codec {
+	case multicodecEd25519Pub:
+		doc.Authentication = []string{id}
+		doc.AssertionMethod = []string{id}
+		doc.CapabilityInvocation = []string{id}
+		doc.CapabilityDelegation = []string{id}
+		x25519, err := ed25519PublicKeyToX25519(raw)
+		if err != nil {
+			return nil, err
+		}
+		x25519Multibase, err := encodeMultikey(multicodecX25519Pub, x25519)
+		if err != nil {
+			return nil, err
+		}
+		kaId := keyId(did, x25519Multibase)
+		if err = addMultibaseKey(doc, kaId, x25519Multibase); err != nil {
+			return nil, err
+		}
+		doc.KeyAgreement = []string{kaId}
+	case multicodecX25519Pub:
+		doc.KeyAgreement = []string{id}
+	case multicodecP256Pub, multicodecP384Pub:
+		doc.Authentication = []string{id}
+		doc.AssertionMethod = []string{id}
+		doc.KeyAgreement = []string{id}
+	case multicodecSecp256k1Pub:
+		doc.Authentication = []string{id}
+		doc.AssertionMethod = []string{id}
+	}
+	return doc, nil
+}
+
+// https://identity.foundation/peer-did-method-spec/#method-2-multiple-inception-key-without-doc
+func resolvePeerDid2(did string) (*DidDocument, error) {
+	elements := strings.Split(strings.TrimPrefix(did, "did:peer:2"), ".")
+	if len(elements) < 2 || elements[0] != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPeerDid, did)
+	}
+	doc := newDidDocument(did)
+	for _, element := range elements[1:] {
+		if len(element) < 2 {
+			return nil, fmt.Errorf("%w: empty element in %s", ErrInvalidPeerDid, did)
+		}
+		purpose, value := element[0], element[1:]
+		if purpose == 'S' {
+			service, err := decodeAbbreviatedService(value, did, len(doc.Service))
+			if err != nil {
+				return nil, err
+			}
+			doc.Service = append(doc.Service, service)
+			continue
+		}
+		id := peerKeyId(did, value)
+		if err := addMultibaseKey(doc, id, value); err != nil {
+			return nil, err
+		}
+		switch purpose {
+		case 'E':
+			doc.KeyAgreement = append(doc.KeyAgreement, id)
+		case 'V':
+			doc.Authentication = append(doc.Authentication, id)
+		case 'A':
+			doc.AssertionMethod = append(doc.AssertionMethod, id)
+		case 'I':
+			doc.CapabilityInvocation = append(doc.CapabilityInvocation, id)
+		case 'D':
+			doc.CapabilityDelegation = append(doc.CapabilityDelegation, id)
+		default:
+			return nil, fmt.Errorf("%w: unknown purpose code %c", ErrInvalidPeerDid, purpose)
+		}
+	}
+	return doc, nil
+}
+
+// decodeAbbreviatedService decodes the service encoding of encodeServiceToB64URL,
+// the former encoding with the uri directly in s is supported as well.
+func decodeAbbreviatedService(encoded string, did string, index int) (Service, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return Service{}, fmt.Errorf("%w: service is not base64url encoded", ErrInvalidPeerDid)
+	}
+	var abbreviated struct {
+		ID string          `json:"id"`
+		T  string          `json:"t"`
+		S  json.RawMessage `json:"s"`
+		A  []string        `json:"a"`
+		R  []string        `json:"r"`
+	}
+	if err = json.Unmarshal(raw, &abbreviated); err != nil {
+		return Service{}, fmt.Errorf("%w: service is not valid json", ErrInvalidPeerDid)
+	}
+
+	endpoint := ServiceEndpoint{Accept: abbreviated.A, RoutingKeys: abbreviated.R}
+	if err = json.Unmarshal(abbreviated.S, &endpoint.Uri); err != nil {
+		var s struct {
+			Uri string   `json:"uri"`
+			A   []string `json:"a"`
+			R   []string `json:"r"`
+		}
+		if err = json.Unmarshal(abbreviated.S, &s); err != nil {
+			return Service{}, fmt.Errorf("%w: service endpoint can not be decoded", ErrInvalidPeerDid)
+		}
+		endpoint = ServiceEndpoint{Uri: s.Uri, Accept: s.A, RoutingKeys: s.R}
+	}
+	if endpoint.Accept == nil {
+		endpoint.Accept = []string{}
+	}
+	if endpoint.RoutingKeys == nil {
+		endpoint.RoutingKeys = []string{}
+	}
+
+	serviceType := abbreviated.T
+	if serviceType == "dm" {
+		serviceType = "DIDCommMessaging"
+	}
+	id := abbreviated.ID
+	if id == "" {
+		id = "#service"
+		if index > 0 {
+			id = fmt.Sprintf("#service-%d", index)
+		}
+	}
+	if strings.HasPrefix(id, "#") {
+		id = did + id
+	}
+	return Service{ID: id, Type: serviceType, ServiceEndpoint: endpoint}, nil
+}
+
+// https://identity.foundation/did-method-peer/#method-4-short-form-and-long-form
+func (p *PeerDidResolver) resolvePeerDid4(did string) (*DidDocument, error) {
+	hash, encodedDoc, isLongForm := strings.Cut(strings.TrimPrefix(did, "did:peer:4"), ":")
+	shortForm := "did:peer:4" + hash
+	if !isLongForm {
+		cached, ok := p.loadLongForm(shortForm)
+		if !ok {
+			return nil, fmt.Errorf("%w: long form of %s is unknown", ErrInvalidPeerDid, did)
+		}
+		encodedDoc = cached
+	}
+
+	_, digest, err := multibase.Decode(hash)
+	if err != nil || len(digest) != 34 || digest[0] != multihashSha256 || digest[1] != sha256.Size {
+		return nil, fmt.Errorf("%w: hash is not a sha2-256 multihash", ErrInvalidPeerDid)
+	}
+	sum := sha256.Sum256([]byte(encodedDoc))
+	if !bytes.Equal(digest[2:], sum[:]) {
+		return nil, fmt.Errorf("%w: hash does not match document", ErrInvalidPeerDid)
+	}
+
+	codec, docJson, err := decodeMultikey(encodedDoc)
+	if err != nil || codec != multicodecJson {
+		return nil, fmt.Errorf("%w: document is not multicodec json", ErrInvalidPeerDid)
+	}
+	doc := newDidDocument(did)
+	if err = json.Unmarshal(docJson, doc); err != nil {
+		return nil, fmt.Errorf("%w: document can not be decoded", ErrInvalidPeerDid)
+	}
+	if isLongForm {
+		p.storeLongForm(shortForm, encodedDoc)
+	}
+
+	contextualize(doc, did)
+	return doc, nil
+}
+
+func (p *PeerDidResolver) loadLongForm(shortForm string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	element, ok := p.longForms[shortForm]
+	if !ok {
+		return "", false
+	}
+	p.lru.MoveToFront(element)
+	return element.Value.(*longForm).encodedDoc, true
+}
+
+func (p *PeerDidResolver) storeLongForm(shortForm string, encodedDoc string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.longForms[shortForm]; ok {
+		p.lru.MoveToFront(element)
+		return
+	}
+	p.longForms[shortForm] = p.lru.PushFront(&longForm{shortForm: shortForm, encodedDoc: encodedDoc})
+	for p.lru.Len() > p.maxLongForms {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		delete(p.longForms, oldest.Value.(*longForm).shortForm)
+	}
+}
+
+// contextualize sets the document id and makes relative ids absolute
+func contextualize(doc *DidDocument, did string) {
+	doc.ID = did
+	absolute := func(id string) string {
+		if strings.HasPrefix(id, "#") {
+			return did + id
+		}
+		return id
+	}
+	for i := range doc.VerificationMethod {
+		doc.VerificationMethod[i].ID = absolute(doc.VerificationMethod[i].ID)
+		if doc.VerificationMethod[i].Controller == "" {
+			doc.VerificationMethod[i].Controller = did
+		}
+	}
+	for _, refs := range []*[]string{&doc.KeyAgreement, &doc.Authentication, &doc.AssertionMethod, &doc.CapabilityInvocation, &doc.CapabilityDelegation} {
+		for i := range *refs {
+			(*refs)[i] = absolute((*refs)[i])
+		}
+	}
+	for i := range doc.Service {
+		doc.Service[i].ID = absolute(doc.Service[i].ID)
+	}
+}
+
+func decodeMultikey(publicKeyMultibase string) (codec uint64, raw []byte, err error) {
+	_, data, err := multibase.Decode(publicKeyMultibase)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %s", ErrInvalidPeerDid, err.Error())
+	}
+	codec, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, fmt.Errorf("%w: invalid multicodec prefix", ErrInvalidPeerDid)
+	}
+	return codec, data[n:], nil
+}
+
+func encodeMultikey(codec uint64, raw []byte) (string, error) {
+	data := binary.AppendUvarint(nil, codec)
+	return multibase.Encode(multibase.Base58BTC, append(data, raw...))
+}
+
+func multikeyType(publicKeyMultibase string) (string, error) {
+	codec, _, err := decodeMultikey(publicKeyMultibase)
+	if err != nil {
+		return "", err
+	}
+	switch codec {
+	case multicodecEd25519Pub:
+		return "Ed25519VerificationKey2020", nil
+	case multicodecX25519Pub:
+		return "X25519KeyAgreementKey2020", nil
+	case multicodecP256Pub, multicodecP384Pub, multicodecSecp256k1Pub:
+		return "JsonWebKey2020", nil
+	default:
+		return "", fmt.Errorf("%w: unsupported key type 0x%x", ErrInvalidPeerDid, codec)
+	}
+}
+
+// ed25519PublicKeyToX25519 converts the edwards point to its montgomery form u = (1 + y) / (1 - y)
+func ed25519PublicKeyToX25519(publicKey []byte) ([]byte, error) {
+	if len(publicKey) != 32 {
+		return nil, errors.New("invalid ed25519 public key length")
+	}
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+	// y is encoded little endian, the highest bit is the sign of x
+	le := make([]byte, 32)
+	copy(le, publicKey)
+	le[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(le))
+	if y.Cmp(p) >= 0 {
+		return nil, errors.New("invalid ed25519 public key")
+	}
+
+	one := big.NewInt(1)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, p)
+	if denominator.Sign() == 0 {
+		return nil, errors.New("invalid ed25519 public key")
+	}
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, new(big.Int).ModInverse(denominator, p))
+	u.Mod(u, p)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return reverse(out), nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
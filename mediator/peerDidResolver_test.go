@@ -0,0 +1,93 @@
+package mediator
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+
+	multibase "github.com/multiformats/go-multibase"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	config.Logger = slog.Default()
+}
+
+const testPeerDid2 = "did:peer:2.Ez6LSmg6eZ5FdMcE8PPSMMBWXDvDPQ2weFhbTWjabmgeo3hQh.Vz6Mknr5Zt1YeLF6XpCchBSCrepSoaXpFV93TR5YyhnU3nu8A.SeyJ0IjoiZG0iLCJzIjp7InVyaSI6Imh0dHA6Ly9sb2NhbGhvc3Q6OTA5MC9tZXNzYWdlL3JlY2VpdmUiLCJhIjpbImRpZGNvbW0vdjIiXSwiciI6W119fQ"
+
+func TestPeerDidResolver_NumAlgo2(t *testing.T) {
+	doc, err := NewPeerDidResolver().ResolveDidAsJson(testPeerDid2)
+	assert.Nil(t, err)
+
+	kaId := testPeerDid2 + "#6LSmg6eZ5FdMcE8PPSMMBWXDvDPQ2weFhbTWjabmgeo3hQh"
+	authId := testPeerDid2 + "#6Mknr5Zt1YeLF6XpCchBSCrepSoaXpFV93TR5YyhnU3nu8A"
+	assert.Equal(t, []string{kaId}, doc.KeyAgreement)
+	assert.Equal(t, []string{authId}, doc.Authentication)
+	assert.Equal(t, "X25519KeyAgreementKey2020", doc.VerificationMethod[0].Type)
+	assert.Equal(t, "Ed25519VerificationKey2020", doc.VerificationMethod[1].Type)
+	assert.Equal(t, "z6LSmg6eZ5FdMcE8PPSMMBWXDvDPQ2weFhbTWjabmgeo3hQh", doc.VerificationMethod[0].PublicKeyMultibase)
+
+	assert.Len(t, doc.Service, 1)
+	assert.Equal(t, "DIDCommMessaging", doc.Service[0].Type)
+	assert.Equal(t, "http://localhost:9090/message/receive", doc.Service[0].ServiceEndpoint.Uri)
+	assert.Equal(t, []string{"didcomm/v2"}, doc.Service[0].ServiceEndpoint.Accept)
+}
+
+func TestPeerDidResolver_NumAlgo0(t *testing.T) {
+	did := "did:peer:0z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	doc, err := NewPeerDidResolver().ResolveDidAsJson(did)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{did + "#6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}, doc.Authentication)
+	assert.Equal(t, []string{did + "#6LSj72tK8brWgZja8NLRwPigth2T9QRiG1uH9oKZuKjdh9p"}, doc.KeyAgreement)
+}
+
+func TestPeerDidResolver_NumAlgo4(t *testing.T) {
+	inputDoc := `{"@context":["https://www.w3.org/ns/did/v1"],` +
+		`"verificationMethod":[{"id":"#key-1","type":"Multikey","publicKeyMultibase":"z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}],` +
+		`"authentication":["#key-1"],` +
+		`"service":[{"id":"#didcomm","type":"DIDCommMessaging","serviceEndpoint":"https://example.com/didcomm"}]}`
+	encodedDoc, err := encodeMultikey(multicodecJson, []byte(inputDoc))
+	assert.Nil(t, err)
+	sum := sha256.Sum256([]byte(encodedDoc))
+	hash, err := multibase.Encode(multibase.Base58BTC, append([]byte{multihashSha256, sha256.Size}, sum[:]...))
+	assert.Nil(t, err)
+
+	resolver := NewPeerDidResolver()
+	shortForm := "did:peer:4" + hash
+	_, err = resolver.ResolveDid(shortForm)
+	assert.ErrorIs(t, err, ErrInvalidPeerDid)
+
+	longForm := shortForm + ":" + encodedDoc
+	doc, err := resolver.ResolveDid(longForm)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{longForm + "#key-1"}, doc.Authentication)
+	assert.Equal(t, longForm+"#didcomm", doc.Service[0].Id)
+
+	doc, err = resolver.ResolveDid(shortForm)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{shortForm + "#key-1"}, doc.Authentication)
+
+	_, err = resolver.ResolveDid(shortForm + "x:" + encodedDoc)
+	assert.ErrorIs(t, err, ErrInvalidPeerDid)
+
+	// the least recently used long form is dropped at the limit
+	resolver.maxLongForms = 1
+	resolver.storeLongForm("did:peer:4other", encodedDoc)
+	_, err = resolver.ResolveDid(shortForm)
+	assert.ErrorIs(t, err, ErrInvalidPeerDid)
+}
+
+func TestKeyDidResolver(t *testing.T) {
+	did := "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	doc, err := NewKeyDidResolver().ResolveDidAsJson(did)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{did + "#z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}, doc.Authentication)
+	assert.Equal(t, []string{did + "#z6LSj72tK8brWgZja8NLRwPigth2T9QRiG1uH9oKZuKjdh9p"}, doc.KeyAgreement)
+
+	_, err = NewKeyDidResolver().ResolveDidAsJson("did:key:abc")
+	assert.ErrorIs(t, err, ErrInvalidKeyDid)
+}
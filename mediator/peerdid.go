@@ -22,21 +22,14 @@ import (
 
 func NumAlgo2(services []didcomm.Service, secretResolver secretsresolver.Adapter, didResolver DidResolver) (peerDid string, err error) {
 
-	encPub, encPriv, err := generateX25519Base58BTC()
+	encryption, signing, err := generateKeyPairs(config.CurrentConfiguration.DidComm.KeySuite)
 	if err != nil {
-		config.Logger.Error("Error generating X25518 keypair:", err)
+		config.Logger.Error("Error generating keypairs", "keySuite", config.CurrentConfiguration.DidComm.KeySuite, "err", err)
 		return
 	}
-	encSecret := *createSecretFromKeyPair(encPub, encPriv, didcomm.SecretTypeX25519KeyAgreementKey2020)
-
-	signPub, singPriv, err := generateEd25519Base58BTC()
-	if err != nil {
-		config.Logger.Error("Error generating Ed25519 keypair:", err)
-		return
-	}
-	signSecret := *createSecretFromKeyPair(signPub, singPriv, didcomm.SecretTypeEd25519VerificationKey2020)
+	encSecret, signSecret := encryption.secret, signing.secret
 	serviceB64URL := encodeServicesToB64URL(services)
-	peerDid = fmt.Sprintf("did:peer:2.E%s.V%s%s", encPub, signPub, serviceB64URL)
+	peerDid = fmt.Sprintf("did:peer:2.E%s.V%s%s", encryption.public, signing.public, serviceB64URL)
 
 	didDoc, err := didResolver.ResolveDid(peerDid)
 	if err != nil {
@@ -162,16 +155,14 @@ func encodeServicesToB64URL(services []didcomm.Service) (service string) {
 	return serviceString
 }
 
-func createSecretFromKeyPair(public string, private string, t didcomm.SecretType) *didcomm.Secret {
+func createSecretFromKeyPair(public string, private didcomm.SecretMaterial, t didcomm.SecretType) *didcomm.Secret {
 	// first character is the multibase identifier
 	// expected to be z for base58btc
 	id := public[1:]
 	secret := didcomm.Secret{
-		Id:   id,
-		Type: t,
-		SecretMaterial: didcomm.SecretMaterialMultibase{
-			PrivateKeyMultibase: private,
-		},
+		Id:             id,
+		Type:           t,
+		SecretMaterial: private,
 	}
 	return &secret
 }
@@ -192,15 +183,14 @@ func GenerateSignedToken(did string, payload jwt.MapClaims, secretResolver secre
 		return "", errors.New("No secret found")
 	}
 
-	_, by, err := multibase.Decode(secret.SecretMaterial.(didcomm.SecretMaterialMultibase).PrivateKeyMultibase)
-
+	method, privateKey, _, err := tokenKeys(*secret)
 	if err != nil {
 		return "", err
 	}
 
-	privateKey := ed25519.PrivateKey(by[2:])
-
-	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	token := jwt.NewWithClaims(method, payload)
+	// the key id allows to verify the token with the JWKS of the mediator
+	token.Header["kid"] = secret.Id
 
 	return token.SignedString(privateKey)
 }
@@ -216,17 +206,6 @@ func VerifySignedToken(tokenString, did string, secretResolver secretsresolver.A
 		return "", errors.New("No auth key found")
 	}
 
-	secret := secretResolver.GetPlainSecret(doc.Authentication[0])
-	if secret == nil {
-		return "", errors.New("No secret found")
-	}
-
-	_, by, err := multibase.Decode(secret.SecretMaterial.(didcomm.SecretMaterialMultibase).PrivateKeyMultibase)
-
-	if err != nil {
-		return "", err
-	}
-
 	parts := strings.Split(tokenString, "Bearer")
 
 	if len(parts) > 1 {
@@ -236,13 +215,29 @@ func VerifySignedToken(tokenString, did string, secretResolver secretsresolver.A
 	}
 
 	tok, err := jwt.Parse(strings.TrimLeft(tokenString, " "), func(token *jwt.Token) (interface{}, error) {
+		// tokens of retired keys name their key and stay valid until they expire
+		kid := doc.Authentication[0]
+		if k, ok := token.Header["kid"].(string); ok {
+			kid = k
+		}
+		secret := secretResolver.GetPlainSecret(kid)
+		if secret == nil || !strings.HasPrefix(kid, did+"#") || keyPurpose(secret.Id, secret.Type) != KEY_PURPOSE_AUTHENTICATION {
+			return nil, fmt.Errorf("Unknown key: %s", kid)
+		}
+		method, _, publicKey, err := tokenKeys(*secret)
+		if err != nil {
+			return nil, err
+		}
 		// Überprüfen Sie, ob der Signierungs-Algorithmus korrekt ist
-		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+		if token.Method.Alg() != method.Alg() {
 			return nil, fmt.Errorf("Unsupported Algorithm: %v", token.Header["alg"])
 		}
 
-		return ed25519.PrivateKey(by[2:]).Public(), nil
+		return publicKey, nil
 	})
+	if tok == nil {
+		return "", err
+	}
 
 	claims, ok := tok.Claims.(jwt.MapClaims)
 
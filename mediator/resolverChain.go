@@ -0,0 +1,221 @@
+package mediator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrNoResolver = errors.New("no resolver configured for DID method")
+
+// ResolverChain routes a DID to the resolvers of its method, which are tried in order until one resolves it
+type ResolverChain struct {
+	entries []resolverChainEntry
+	// documents served by the connector itself, e.g. of a did:web mediator
+	local sync.Map
+}
+
+type resolverChainEntry struct {
+	methods  []string
+	resolver DidResolver
+}
+
+func NewResolverChain() *ResolverChain {
+	return &ResolverChain{}
+}
+
+// NewDidResolver creates the resolver chain of the configuration
+func NewDidResolver() *ResolverChain {
+	chain := NewResolverChain()
+	// local resolvers are shared, the did:peer resolver remembers long form DIDs
+	var peer *PeerDidResolver
+	var key *KeyDidResolver
+	var web *WebDidResolver
+	for _, resolver := range config.DidResolvers() {
+		client := resolverClient(resolver.Timeout)
+		switch resolver.Type {
+		case config.RESOLVER_LOCAL:
+			for _, method := range resolver.Methods {
+				if (method == "peer" || method == "*") && peer == nil {
+					peer = NewPeerDidResolver()
+				}
+				if (method == "key" || method == "*") && key == nil {
+					key = NewKeyDidResolver()
+				}
+				if (method == "web" || method == "*") && web == nil {
+					web = NewWebDidResolver(client)
+				}
+				switch method {
+				case "peer":
+					chain.Add([]string{"peer"}, peer)
+				case "key":
+					chain.Add([]string{"key"}, key)
+				case "web":
+					chain.Add([]string{"web"}, web)
+				case "*":
+					chain.Add([]string{"peer"}, peer)
+					chain.Add([]string{"key"}, key)
+					chain.Add([]string{"web"}, web)
+				default:
+					config.Logger.Warn("DID method can not be resolved locally", "method", method)
+				}
+			}
+		case config.RESOLVER_UNIVERSAL:
+			chain.Add(resolver.Methods, NewUniverseDidResolver(resolver.Url, client))
+		}
+	}
+	return chain
+}
+
+// resolverClient returns an outbound client with the timeout in seconds, nil keeps the outbound client
+func resolverClient(timeout int) *http.Client {
+	if timeout <= 0 {
+		return nil
+	}
+	client := *transport.Client()
+	client.Timeout = time.Duration(timeout) * time.Second
+	return &client
+}
+
+// Add appends a resolver for the DID methods, * matches all methods
+func (c *ResolverChain) Add(methods []string, resolver DidResolver) {
+	c.entries = append(c.entries, resolverChainEntry{methods: methods, resolver: resolver})
+}
+
+// AddLocalDocument resolves the DID of the document without fetching it
+func (c *ResolverChain) AddLocalDocument(doc DidDocument) {
+	c.local.Store(doc.ID, doc)
+}
+
+func (c *ResolverChain) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	didDoc, err := c.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+// ResolveDid is called by the didcomm library without context, the resolution is a trace of its own which is
+// linked to the messages the library works on
+func (c *ResolverChain) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	return c.ResolveDidContext(context.Background(), did)
+}
+
+// ResolveDidContext resolves the DID in a child span of ctx
+func (c *ResolverChain) ResolveDidContext(ctx context.Context, did string) (*didcomm.DidDoc, error) {
+	if doc, ok := c.local.Load(did); ok {
+		return didDocJsonToDidDoc(DidDocumentJSON{DidDocument: doc.(DidDocument)}), nil
+	}
+	_, span := c.startResolve(ctx, did)
+	start := time.Now()
+	doc, err := c.resolveDid(did)
+	metrics.Observe(metrics.ResolveDuration, start, err, c.methodLabel(did))
+	tracing.End(span, err)
+	return doc, err
+}
+
+func (c *ResolverChain) resolveDid(did string) (*didcomm.DidDoc, error) {
+	var errs []error
+	for _, resolver := range c.resolvers(did) {
+		doc, err := resolver.ResolveDid(did)
+		if err == nil {
+			return doc, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, chainError(did, errs)
+}
+
+// ResolveDidAsJson is called without context, the resolution is a trace of its own
+func (c *ResolverChain) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	return c.ResolveDidAsJsonContext(context.Background(), did)
+}
+
+// ResolveDidAsJsonContext resolves the DID document in a child span of ctx
+func (c *ResolverChain) ResolveDidAsJsonContext(ctx context.Context, did string) (*DidDocumentJSON, error) {
+	if doc, ok := c.local.Load(did); ok {
+		return &DidDocumentJSON{
+			DidDocument:               doc.(DidDocument),
+			DidResolutionMetadataJSON: DidResolutionMetadataJSON{ContentType: "application/did+ld+json"},
+		}, nil
+	}
+	_, span := c.startResolve(ctx, did)
+	start := time.Now()
+	doc, err := c.resolveDidAsJson(did)
+	metrics.Observe(metrics.ResolveDuration, start, err, c.methodLabel(did))
+	tracing.End(span, err)
+	return doc, err
+}
+
+func (c *ResolverChain) resolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	var errs []error
+	for _, resolver := range c.resolvers(did) {
+		doc, err := resolver.ResolveDidAsJson(did)
+		if err == nil {
+			return doc, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, chainError(did, errs)
+}
+
+// startResolve starts the span of a resolution. A resolution of the didcomm library has no parent, it is linked to
+// the messages which wait for the library to resolve the DID.
+func (c *ResolverChain) startResolve(ctx context.Context, did string) (context.Context, trace.Span) {
+	options := []trace.SpanStartOption{trace.WithAttributes(attribute.String("did.method", c.methodLabel(did)))}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		options = append(options, trace.WithLinks(tracing.Links(did)...))
+	}
+	return tracing.Start(ctx, "ResolveDid", options...)
+}
+
+// resolvers returns the resolvers of the DID method in the configured order
+func (c *ResolverChain) resolvers(did string) []DidResolver {
+	didMethod := didMethod(did)
+	if didMethod == "" {
+		return nil
+	}
+	resolvers := []DidResolver{}
+	for _, entry := range c.entries {
+		for _, method := range entry.methods {
+			if method == "*" || method == didMethod {
+				resolvers = append(resolvers, entry.resolver)
+				break
+			}
+		}
+	}
+	return resolvers
+}
+
+// methodLabel keeps the metrics of DID methods without resolver together
+func (c *ResolverChain) methodLabel(did string) string {
+	if len(c.resolvers(did)) == 0 {
+		return "unsupported"
+	}
+	return didMethod(did)
+}
+
+// didMethod returns the method of the DID, empty if it is no DID
+func didMethod(did string) string {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) != 3 || parts[0] != "did" {
+		return ""
+	}
+	return parts[1]
+}
+
+func chainError(did string, errs []error) error {
+	if len(errs) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoResolver, did)
+	}
+	return errors.Join(errs...)
+}
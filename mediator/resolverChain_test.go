@@ -0,0 +1,91 @@
+package mediator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestUniversalResolver(did string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1.0/identifiers/"+did {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"didDocument":{"id":"` + did + `","verificationMethod":[],"service":[]},"didResolutionMetadata":{"contentType":"application/did+ld+json"}}`))
+	}))
+}
+
+func TestResolverChain_RoutingAndFallback(t *testing.T) {
+	ebsiDid := "did:ebsi:zabc"
+	indyDid := "did:indy:sovrin:abc"
+	ebsi := newTestUniversalResolver(ebsiDid)
+	defer ebsi.Close()
+	indy := newTestUniversalResolver(indyDid)
+	defer indy.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	chain := NewResolverChain()
+	chain.Add([]string{"peer"}, NewPeerDidResolver())
+	chain.Add([]string{"ebsi"}, NewUniverseDidResolver(down.URL, down.Client()))
+	chain.Add([]string{"ebsi"}, NewUniverseDidResolver(ebsi.URL, ebsi.Client()))
+	chain.Add([]string{"indy"}, NewUniverseDidResolver(indy.URL, indy.Client()))
+
+	doc, err := chain.ResolveDidAsJson(ebsiDid)
+	assert.Nil(t, err)
+	assert.Equal(t, ebsiDid, doc.ID)
+
+	doc, err = chain.ResolveDidAsJson(indyDid)
+	assert.Nil(t, err)
+	assert.Equal(t, indyDid, doc.ID)
+
+	_, err = chain.ResolveDid(testPeerDid2)
+	assert.Nil(t, err)
+
+	_, err = chain.ResolveDid("did:ebsi:unknown")
+	assert.NotNil(t, err)
+
+	_, err = chain.ResolveDid("did:example:123")
+	assert.ErrorIs(t, err, ErrNoResolver)
+}
+
+func TestResolverChain_ResolveDidContext(t *testing.T) {
+	did := "did:ebsi:zabc"
+	server := newTestUniversalResolver(did)
+	defer server.Close()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	chain := NewResolverChain()
+	chain.Add([]string{"ebsi"}, NewUniverseDidResolver(server.URL, server.Client()))
+	cache := NewCachingDidResolver(chain, 10, time.Minute, time.Minute)
+
+	ctx, span := tracing.Start(context.Background(), "handleForward")
+	_, err := ResolveDidContext(ctx, cache, did)
+	assert.Nil(t, err)
+	// the callback of the didcomm library has no context, it is linked to the message which waits for it
+	resolved := tracing.Await(ctx, did)
+	_, err = chain.ResolveDid(did)
+	resolved()
+	assert.Nil(t, err)
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "ResolveDid", spans[0].Name())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.Len(t, spans[1].Links(), 1)
+	assert.Equal(t, span.SpanContext(), spans[1].Links()[0].SpanContext)
+}
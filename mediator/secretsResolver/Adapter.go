@@ -1,6 +1,14 @@
 package secretsresolver
 
-import "github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
 
 // Is needed to have the store functionallity
 type Adapter interface {
@@ -8,4 +16,25 @@ type Adapter interface {
 	GetSecret(secretid string, cb *didcomm.OnGetSecretResult) didcomm.ErrorCode
 	FindSecrets(secretids []string, cb *didcomm.OnFindSecretsResult) didcomm.ErrorCode
 	StoreSecret(secret didcomm.Secret) error
+	// ListSecrets returns the secrets of a DID, all secrets if the DID is empty
+	ListSecrets(did string) ([]StoredSecret, error)
+	// RetireSecret marks a secret as retired, it can still be used to decrypt and verify
+	RetireSecret(secretid string) error
+}
+
+// StoredSecret is a secret with its lifecycle, Retired is zero for active secrets
+type StoredSecret struct {
+	didcomm.Secret
+	Added   time.Time
+	Retired time.Time
+}
+
+func (s StoredSecret) IsRetired() bool {
+	return !s.Retired.IsZero()
+}
+
+// SecretDid returns the DID part of a secret id
+func SecretDid(secretid string) string {
+	did, _, _ := strings.Cut(secretid, "#")
+	return did
 }
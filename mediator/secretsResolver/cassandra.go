@@ -1,6 +1,7 @@
 package secretsresolver
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
@@ -20,20 +21,51 @@ func NewCassandra() *Cassandra {
 		config.Logger.Error("NewCassandra", "Error creating session:", err)
 		panic("Error creating cassandra session")
 	}
-	return &Cassandra{
+	s := &Cassandra{
 		session:  session,
 		keyspace: config.CurrentConfiguration.Database.Keyspace,
 	}
+	if err = s.indexSecrets(); err != nil {
+		config.Logger.Error("NewCassandra", "Error indexing secrets by DID:", err)
+		panic("Error indexing secrets by DID")
+	}
+	return s
+}
+
+// indexSecrets adds the secrets which were stored before the lookup by DID existed to the lookup,
+// the secrets are only read if the lookup is still empty
+func (s *Cassandra) indexSecrets() error {
+	var did string
+	if err := s.session.Query("SELECT did FROM " + s.keyspace + ".secrets_by_did LIMIT 1").Scan(&did); err == nil {
+		return nil
+	} else if err != gocql.ErrNotFound {
+		return err
+	}
+	iter := s.session.Query("SELECT id FROM " + s.keyspace + ".secrets").Iter()
+	var id string
+	for iter.Scan(&id) {
+		if err := s.session.Query("INSERT INTO "+s.keyspace+".secrets_by_did (did, id) VALUES (?, ?)", SecretDid(id), id).Exec(); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
 }
 
+// formats of the key column, secrets stored before the material column was added are multibase
+const (
+	materialMultibase = "multibase"
+	materialJwk       = "jwk"
+)
+
 func (s *Cassandra) GetPlainSecret(secretId string) *didcomm.Secret {
-	iter := s.session.Query("SELECT id, type, key FROM "+config.CurrentConfiguration.Database.Keyspace+".secrets WHERE id = ?", secretId).Iter()
+	iter := s.session.Query("SELECT id, type, key, material FROM "+config.CurrentConfiguration.Database.Keyspace+".secrets WHERE id = ?", secretId).Iter()
 	defer iter.Close()
 	var secret didcomm.Secret
-	var key didcomm.SecretMaterialMultibase
-	for iter.Scan(&secret.Id, &secret.Type, &key.PrivateKeyMultibase) {
+	var key, material string
+	for iter.Scan(&secret.Id, &secret.Type, &key, &material) {
 		if secret.Id == secretId {
-			secret.SecretMaterial = key
+			secret.SecretMaterial = secretMaterial(key, material)
 			return &secret
 		}
 	}
@@ -92,11 +124,74 @@ func (s *Cassandra) FindSecrets(secretIds []string, cb *didcomm.OnFindSecretsRes
 }
 
 func (s *Cassandra) StoreSecret(secret didcomm.Secret) error {
-	if err := s.session.Query("INSERT INTO "+config.CurrentConfiguration.Database.Keyspace+".secrets (id, type, key, added) VALUES (?, ?, ?, ?)",
-		secret.Id, secret.Type, secret.SecretMaterial.(didcomm.SecretMaterialMultibase).PrivateKeyMultibase, time.Now()).Exec(); err != nil {
-		return err
+	var key, material string
+	switch m := secret.SecretMaterial.(type) {
+	case didcomm.SecretMaterialMultibase:
+		key, material = m.PrivateKeyMultibase, materialMultibase
+	case didcomm.SecretMaterialJwk:
+		key, material = m.PrivateKeyJwk, materialJwk
+	default:
+		return fmt.Errorf("unsupported material of secret %s", secret.Id)
 	}
-	return nil
+	// logged batch, so that a stored secret is always listed for its DID
+	batch := s.session.NewBatch(gocql.LoggedBatch)
+	batch.Query("INSERT INTO "+s.keyspace+".secrets (id, type, key, material, added) VALUES (?, ?, ?, ?, ?)",
+		secret.Id, secret.Type, key, material, time.Now())
+	batch.Query("INSERT INTO "+s.keyspace+".secrets_by_did (did, id) VALUES (?, ?)", SecretDid(secret.Id), secret.Id)
+	return s.session.ExecuteBatch(batch)
+}
+
+func (s *Cassandra) ListSecrets(did string) ([]StoredSecret, error) {
+	query := "SELECT id, type, key, material, added, retired FROM " + s.keyspace + ".secrets"
+	var values []interface{}
+	if did != "" {
+		ids, err := s.secretIds(did)
+		if err != nil || len(ids) == 0 {
+			return []StoredSecret{}, err
+		}
+		query += " WHERE id IN ?"
+		values = append(values, ids)
+	}
+	iter := s.session.Query(query, values...).Iter()
+	secrets := []StoredSecret{}
+	var secret StoredSecret
+	var key, material string
+	for iter.Scan(&secret.Id, &secret.Type, &key, &material, &secret.Added, &secret.Retired) {
+		secret.SecretMaterial = secretMaterial(key, material)
+		secrets = append(secrets, secret)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// secretIds returns the ids of the secrets of the DID from the lookup
+func (s *Cassandra) secretIds(did string) ([]string, error) {
+	iter := s.session.Query("SELECT id FROM "+s.keyspace+".secrets_by_did WHERE did = ?", did).Iter()
+	ids := []string{}
+	var id string
+	for iter.Scan(&id) {
+		ids = append(ids, id)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *Cassandra) RetireSecret(secretid string) error {
+	if s.GetPlainSecret(secretid) == nil {
+		return ErrSecretNotFound
+	}
+	return s.session.Query("UPDATE "+config.CurrentConfiguration.Database.Keyspace+".secrets SET retired = ? WHERE id = ?", time.Now(), secretid).Exec()
+}
+
+func secretMaterial(key string, material string) didcomm.SecretMaterial {
+	if material == materialJwk {
+		return didcomm.SecretMaterialJwk{PrivateKeyJwk: key}
+	}
+	return didcomm.SecretMaterialMultibase{PrivateKeyMultibase: key}
 }
 
 func newCassandraSession() (*gocql.Session, error) {
@@ -1,6 +1,9 @@
 package secretsresolver
 
 import (
+	"sync"
+	"time"
+
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 )
@@ -12,18 +15,19 @@ import (
 const DID = "did:peer:2.Ez6LSc19SfftNpBDVqcd8NQtef2vinvR3W8s1wVeoYzwy5yiw.Vz6Mkno2XmnAxWb7YbyDJw9hmqWcTuAwQKbtaiw9tjqRjDvMz.SeyJ0IjoiZG0iLCJzIjp7InVyaSI6Imh0dHA6Ly9sb2NhbGhvc3Q6OTA5MC9tZXNzYWdlL3JlY2VpdmUiLCJhIjpbImRpZGNvbW0vdjIiXSwiciI6W119fQ"
 
 type Demo struct {
-	secrets map[string]didcomm.Secret
+	lock    sync.RWMutex
+	secrets map[string]StoredSecret
 }
 
 func NewDemo() *Demo {
 
-	secrets := make(map[string]didcomm.Secret)
+	secrets := make(map[string]StoredSecret)
 
 	// store demo secrets
 	vSecret := createDemoVerificationSecrets()
-	secrets[vSecret.Id] = vSecret
+	secrets[vSecret.Id] = StoredSecret{Secret: vSecret, Added: time.Now()}
 	eSecret := createDemoEncryptionSecrets()
-	secrets[eSecret.Id] = eSecret
+	secrets[eSecret.Id] = StoredSecret{Secret: eSecret, Added: time.Now()}
 
 	return &Demo{
 		secrets: secrets,
@@ -31,15 +35,17 @@ func NewDemo() *Demo {
 }
 
 func (d *Demo) GetPlainSecret(secretid string) *didcomm.Secret {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
 	if secret, ok := d.secrets[secretid]; ok {
-		return &secret
+		return &secret.Secret
 	}
 	return nil
 }
 
 func (d *Demo) GetSecret(secretId string, cb *didcomm.OnGetSecretResult) didcomm.ErrorCode {
-	if secret, ok := d.secrets[secretId]; ok {
-		err := cb.Success(&secret)
+	if secret := d.GetPlainSecret(secretId); secret != nil {
+		err := cb.Success(secret)
 		if err != nil {
 			config.Logger.Error("Unable to use success  channel while getting secret", "msg", err)
 			return didcomm.ErrorCodeError
@@ -59,7 +65,7 @@ func (d *Demo) GetSecret(secretId string, cb *didcomm.OnGetSecretResult) didcomm
 func (d *Demo) FindSecrets(secretIds []string, cb *didcomm.OnFindSecretsResult) didcomm.ErrorCode {
 	var secrets []string
 	for _, id := range secretIds {
-		if secret, ok := d.secrets[id]; ok {
+		if secret := d.GetPlainSecret(id); secret != nil {
 			secrets = append(secrets, secret.Id)
 		}
 	}
@@ -72,7 +78,33 @@ func (d *Demo) FindSecrets(secretIds []string, cb *didcomm.OnFindSecretsResult)
 }
 
 func (d *Demo) StoreSecret(secret didcomm.Secret) error {
-	d.secrets[secret.Id] = secret
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.secrets[secret.Id] = StoredSecret{Secret: secret, Added: time.Now()}
+	return nil
+}
+
+func (d *Demo) ListSecrets(did string) ([]StoredSecret, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	secrets := []StoredSecret{}
+	for _, secret := range d.secrets {
+		if did == "" || SecretDid(secret.Id) == did {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+func (d *Demo) RetireSecret(secretid string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	secret, ok := d.secrets[secretid]
+	if !ok {
+		return ErrSecretNotFound
+	}
+	secret.Retired = time.Now()
+	d.secrets[secretid] = secret
 	return nil
 }
 
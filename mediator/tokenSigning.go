@@ -0,0 +1,117 @@
+package mediator
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+
+	"github.com/golang-jwt/jwt"
+	multibase "github.com/multiformats/go-multibase"
+)
+
+// SigningMethodES256K signs tokens with secp256k1 keys, see RFC 8812
+var SigningMethodES256K = &signingMethodES256K{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodES256K.Alg(), func() jwt.SigningMethod {
+		return SigningMethodES256K
+	})
+}
+
+type signingMethodES256K struct{}
+
+func (m *signingMethodES256K) Alg() string {
+	return "ES256K"
+}
+
+func (m *signingMethodES256K) Sign(signingString string, key interface{}) (string, error) {
+	private, ok := key.(*secp256k1.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	hash := sha256.Sum256([]byte(signingString))
+	// the compact signature is the recovery code followed by r || s
+	signature := secp256k1ecdsa.SignCompact(private, hash[:], true)
+	return jwt.EncodeSegment(signature[1:]), nil
+}
+
+func (m *signingMethodES256K) Verify(signingString string, signature string, key interface{}) error {
+	public, ok := key.(*secp256k1.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if len(sig) != 64 {
+		return jwt.ErrSignatureInvalid
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+		return jwt.ErrSignatureInvalid
+	}
+	hash := sha256.Sum256([]byte(signingString))
+	if !secp256k1ecdsa.NewSignature(&r, &s).Verify(hash[:], public) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+// tokenKeys returns the signing method and the keys of an authentication secret of the mediator
+func tokenKeys(secret didcomm.Secret) (method jwt.SigningMethod, private interface{}, public interface{}, err error) {
+	switch material := secret.SecretMaterial.(type) {
+	case didcomm.SecretMaterialMultibase:
+		_, by, err := multibase.Decode(material.PrivateKeyMultibase)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(by) != 2+ed25519.PrivateKeySize {
+			return nil, nil, nil, errors.New("secret is no Ed25519 key")
+		}
+		key := ed25519.PrivateKey(by[2:])
+		return jwt.SigningMethodEdDSA, key, key.Public(), nil
+	case didcomm.SecretMaterialJwk:
+		var jwk ecJwk
+		if err := json.Unmarshal([]byte(material.PrivateKeyJwk), &jwk); err != nil {
+			return nil, nil, nil, err
+		}
+		d, err := base64.RawURLEncoding.DecodeString(jwk.D)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch jwk.Crv {
+		case crvP256:
+			ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			point := ecdhKey.PublicKey().Bytes()
+			key := &ecdsa.PrivateKey{
+				PublicKey: ecdsa.PublicKey{
+					Curve: elliptic.P256(),
+					X:     new(big.Int).SetBytes(point[1:33]),
+					Y:     new(big.Int).SetBytes(point[33:]),
+				},
+				D: new(big.Int).SetBytes(d),
+			}
+			return jwt.SigningMethodES256, key, &key.PublicKey, nil
+		case crvSecp256k1:
+			key := secp256k1.PrivKeyFromBytes(d)
+			return SigningMethodES256K, key, key.PubKey(), nil
+		}
+		return nil, nil, nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported secret %s", secret.Id)
+}
@@ -1,17 +1,32 @@
 package mediator
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/callback"
 )
 
 func (m *Mediator) UnpackMessage(body string) (didcomm.Message, error) {
-	// var message didcomm.Message = didcomm.Message{}
+	message, _, err := m.UnpackMessageWithMetadata(body)
+	return message, err
+}
+
+// UnpackMessageWithMetadata unpacks a message and returns how it was protected, e.g. who signed it
+func (m *Mediator) UnpackMessageWithMetadata(body string) (message didcomm.Message, metadata didcomm.UnpackMetadata, err error) {
+	defer func(start time.Time) { metrics.Observe(metrics.PackDuration, start, err, "unpack") }(time.Now())
 	options := didcomm.UnpackOptions{
 		ExpectDecryptByAllKeys:  true,
 		UnwrapReWrappingForward: true,
 	}
-	msgCh := make(chan didcomm.Message, 1)
+	msgCh := make(chan callback.UnpackSuccessPair, 1)
 	errCh := make(chan callback.UnpackErrorPair, 1)
 	unpackCB := callback.NewUnpackResultCallback(msgCh, errCh)
 
@@ -21,8 +36,102 @@ func (m *Mediator) UnpackMessage(body string) (didcomm.Message, error) {
 	select {
 	case e := <-errCh:
 		m.Logger.Error("Error unpacking message:", "msg", e.Msg)
-		return didcomm.Message{}, e.Err
-	case message := <-msgCh:
-		return message, nil
+		return didcomm.Message{}, didcomm.UnpackMetadata{}, e.Err
+	case suc := <-msgCh:
+		return suc.Message, suc.Metadata, nil
+	}
+}
+
+// UnpackFromPrior verifies the signature of a from_prior JWT and returns its claims and the key id of the signer
+func (m *Mediator) UnpackFromPrior(fromPriorJwt string) (didcomm.FromPrior, string, error) {
+	sucCh := make(chan callback.UnpackFromPriorSuccessPair, 1)
+	errCh := make(chan callback.UnpackFromPriorErrorPair, 1)
+	cb := callback.NewUnpackFromPriorResultCallback(sucCh, errCh)
+
+	dc := m.Messages
+	go dc.UnpackFromPrior(fromPriorJwt, cb)
+	select {
+	case e := <-errCh:
+		m.Logger.Error("Error unpacking from_prior:", "msg", e.Msg)
+		return didcomm.FromPrior{}, "", e.Err
+	case suc := <-sucCh:
+		return suc.FromPrior, suc.Kid, nil
+	}
+}
+
+// AwaitResolutions links the resolutions of the DIDs of the envelope to the span of ctx until the returned function
+// is called, the didcomm library resolves them without context
+func (m *Mediator) AwaitResolutions(ctx context.Context, envelope string) func() {
+	return tracing.Await(ctx, EnvelopeDids(envelope)...)
+}
+
+// EnvelopeDids returns the DIDs which the didcomm library resolves to unpack the envelope as far as they are
+// readable without unpacking it: the key ids of a JWE or JWS, the sender and recipients of a plaintext message
+// or the issuer of a JWT like a from_prior
+func EnvelopeDids(envelope string) []string {
+	if parts := strings.Split(envelope, "."); len(parts) == 3 && !strings.HasPrefix(strings.TrimSpace(envelope), "{") {
+		var claims struct {
+			Iss string `json:"iss"`
+		}
+		decodeSegment(parts[1], &claims)
+		return dids([]string{protectedHeader(parts[0]).Kid, claims.Iss})
+	}
+
+	var jwm struct {
+		Protected  string `json:"protected"`
+		Recipients []struct {
+			Header struct {
+				Kid string `json:"kid"`
+			} `json:"header"`
+		} `json:"recipients"`
+		Signatures []struct {
+			Protected string `json:"protected"`
+			Header    struct {
+				Kid string `json:"kid"`
+			} `json:"header"`
+		} `json:"signatures"`
+		From string   `json:"from"`
+		To   []string `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(envelope), &jwm); err != nil {
+		return nil
+	}
+	kids := []string{protectedHeader(jwm.Protected).Skid}
+	for _, recipient := range jwm.Recipients {
+		kids = append(kids, recipient.Header.Kid)
+	}
+	for _, signature := range jwm.Signatures {
+		kids = append(kids, signature.Header.Kid, protectedHeader(signature.Protected).Kid)
+	}
+	return dids(append(append(kids, jwm.From), jwm.To...))
+}
+
+type jwsHeader struct {
+	Kid  string `json:"kid"`
+	Skid string `json:"skid"`
+}
+
+func protectedHeader(segment string) (header jwsHeader) {
+	decodeSegment(segment, &header)
+	return header
+}
+
+// decodeSegment decodes a base64url encoded JSON segment, v stays empty if it is invalid
+func decodeSegment(segment string, v any) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err == nil {
+		json.Unmarshal(data, v)
+	}
+}
+
+// dids returns the distinct DIDs of the key ids
+func dids(kids []string) []string {
+	result := []string{}
+	for _, kid := range kids {
+		did, _, _ := strings.Cut(kid, "#")
+		if strings.HasPrefix(did, "did:") && !slices.Contains(result, did) {
+			result = append(result, did)
+		}
 	}
+	return result
 }
@@ -0,0 +1,26 @@
+package mediator
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelopeDids(t *testing.T) {
+	segment := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+
+	jwe := `{"protected":"` + segment(`{"skid":"did:peer:2.sender#key-2"}`) + `","recipients":[{"header":{"kid":"did:peer:2.mediator#key-1"}},{"header":{"kid":"did:peer:2.mediator#key-3"}}]}`
+	assert.Equal(t, []string{"did:peer:2.sender", "did:peer:2.mediator"}, EnvelopeDids(jwe))
+
+	jws := `{"payload":"e30","signatures":[{"protected":"` + segment(`{"kid":"did:key:z6Mk#z6Mk"}`) + `","header":{"kid":"did:key:z6Mk#z6Mk"}}]}`
+	assert.Equal(t, []string{"did:key:z6Mk"}, EnvelopeDids(jws))
+
+	plaintext := `{"id":"1","from":"did:peer:2.sender","to":["did:web:example.com"],"body":{"content":"a.b.c"}}`
+	assert.Equal(t, []string{"did:peer:2.sender", "did:web:example.com"}, EnvelopeDids(plaintext))
+
+	fromPrior := segment(`{"kid":"did:peer:2.prior#key-1"}`) + "." + segment(`{"iss":"did:peer:2.prior","sub":"did:peer:2.sender"}`) + ".c2ln"
+	assert.Equal(t, []string{"did:peer:2.prior"}, EnvelopeDids(fromPrior))
+
+	assert.Empty(t, EnvelopeDids("not a message"))
+}
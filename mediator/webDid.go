@@ -0,0 +1,176 @@
+package mediator
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+
+	multibase "github.com/multiformats/go-multibase"
+)
+
+// the secrets of a did:web mediator are stored with these fragments followed by a number,
+// generating keys increments the number
+const (
+	webDidKeyAgreementFragment   = "#key-agreement-"
+	webDidAuthenticationFragment = "#authentication-"
+)
+
+// CreateWebDidKeys generates the keys of a did:web mediator and stores them in the secrets resolver.
+// A did:web which was used before keeps its active keys, otherwise the keys get the next free number,
+// so keys which were published or retired before are never overwritten.
+func CreateWebDidKeys(did string, secretResolver secretsresolver.Adapter) error {
+	if _, err := webDidUrl(did); err != nil {
+		return err
+	}
+	secrets, err := secretResolver.ListSecrets(did)
+	if err != nil {
+		return err
+	}
+	index := 0
+	active := map[string]bool{}
+	for _, secret := range secrets {
+		index = max(index, webDidKeyIndex(secret.Id))
+		if !secret.IsRetired() && webDidKeyIndex(secret.Id) > 0 {
+			active[keyPurpose(secret.Id, secret.Type)] = true
+		}
+	}
+	if active[KEY_PURPOSE_KEY_AGREEMENT] && active[KEY_PURPOSE_AUTHENTICATION] {
+		return nil
+	}
+	_, err = createWebDidKeys(did, index+1, secretResolver)
+	return err
+}
+
+func createWebDidKeys(did string, index int, secretResolver secretsresolver.Adapter) ([]didcomm.Secret, error) {
+	encryption, signing, err := generateKeyPairs(config.CurrentConfiguration.DidComm.KeySuite)
+	if err != nil {
+		return nil, err
+	}
+	encryption.secret.Id = fmt.Sprintf("%s%s%d", did, webDidKeyAgreementFragment, index)
+	signing.secret.Id = fmt.Sprintf("%s%s%d", did, webDidAuthenticationFragment, index)
+	secrets := []didcomm.Secret{encryption.secret, signing.secret}
+	for _, secret := range secrets {
+		if err = secretResolver.StoreSecret(secret); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+// webDidKeyIndex returns the number of a key of a did:web mediator, 0 for other keys
+func webDidKeyIndex(id string) int {
+	_, fragment, _ := strings.Cut(id, "#")
+	for _, prefix := range []string{webDidKeyAgreementFragment[1:], webDidAuthenticationFragment[1:]} {
+		if index, found := strings.CutPrefix(fragment, prefix); found {
+			if i, err := strconv.Atoi(index); err == nil {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// WebDidDocument builds the document of a did:web mediator from its active secrets and services,
+// the newest keys are listed first
+func WebDidDocument(did string, services []didcomm.Service, secretResolver secretsresolver.Adapter) (*DidDocument, error) {
+	secrets, err := secretResolver.ListSecrets(did)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return webDidKeyIndex(secrets[i].Id) > webDidKeyIndex(secrets[j].Id)
+	})
+	doc := newDidDocument(did)
+	for _, secret := range secrets {
+		if secret.IsRetired() || webDidKeyIndex(secret.Id) == 0 {
+			continue
+		}
+		publicKeyMultibase, err := publicKeyFromSecret(secret.Secret)
+		if err != nil {
+			return nil, err
+		}
+		if err = addMultibaseKey(doc, secret.Id, publicKeyMultibase); err != nil {
+			return nil, err
+		}
+		if strings.Contains(secret.Id, webDidKeyAgreementFragment) {
+			doc.KeyAgreement = append(doc.KeyAgreement, secret.Id)
+		} else {
+			doc.Authentication = append(doc.Authentication, secret.Id)
+			doc.AssertionMethod = append(doc.AssertionMethod, secret.Id)
+		}
+	}
+	if len(doc.KeyAgreement) == 0 || len(doc.Authentication) == 0 {
+		return nil, fmt.Errorf("no active keys found for %s", did)
+	}
+
+	for _, s := range services {
+		serviceKind, ok := s.ServiceEndpoint.(didcomm.ServiceKindDidCommMessaging)
+		if !ok {
+			continue
+		}
+		accept := []string{}
+		if serviceKind.Value.Accept != nil {
+			accept = *serviceKind.Value.Accept
+		}
+		doc.Service = append(doc.Service, Service{
+			ID:   s.Id,
+			Type: "DIDCommMessaging",
+			ServiceEndpoint: ServiceEndpoint{
+				Uri:         serviceKind.Value.Uri,
+				Accept:      accept,
+				RoutingKeys: serviceKind.Value.RoutingKeys,
+			},
+		})
+	}
+	contextualize(doc, did)
+	return doc, nil
+}
+
+// publicKeyFromSecret derives the public multikey of a secret generated by NumAlgo2 or CreateWebDidKeys
+func publicKeyFromSecret(secret didcomm.Secret) (string, error) {
+	if jwk, ok := secret.SecretMaterial.(didcomm.SecretMaterialJwk); ok {
+		return jwkMultikey(jwk.PrivateKeyJwk)
+	}
+	material, ok := secret.SecretMaterial.(didcomm.SecretMaterialMultibase)
+	if !ok {
+		return "", errors.New("secret is neither multibase nor JWK encoded")
+	}
+	_, private, err := multibase.Decode(material.PrivateKeyMultibase)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case secret.Type == didcomm.SecretTypeX25519KeyAgreementKey2020 && len(private) == 2+32:
+		key, err := ecdh.X25519().NewPrivateKey(private[2:])
+		if err != nil {
+			return "", err
+		}
+		return encodeMultikey(multicodecX25519Pub, key.PublicKey().Bytes())
+	case secret.Type == didcomm.SecretTypeEd25519VerificationKey2020 && len(private) == 2+ed25519.PrivateKeySize:
+		public := ed25519.PrivateKey(private[2:]).Public().(ed25519.PublicKey)
+		return encodeMultikey(multicodecEd25519Pub, public)
+	}
+	return "", fmt.Errorf("unsupported secret %s", secret.Id)
+}
+
+// WebDidDocumentPath is the http path at which the document of the did:web has to be served
+func WebDidDocumentPath(did string) (string, error) {
+	documentUrl, err := webDidUrl(did)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(documentUrl)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Path, nil
+}
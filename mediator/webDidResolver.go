@@ -0,0 +1,167 @@
+package mediator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
+)
+
+// https://w3c-ccg.github.io/did-method-web/
+
+// maxWebDidDocumentSize limits the size of a fetched did.json
+const maxWebDidDocumentSize = 1 << 20
+
+var ErrInvalidWebDid = errors.New("invalid did:web")
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// WebDidResolver resolves did:web by fetching the did.json of the domain
+type WebDidResolver struct {
+	client *http.Client
+	// dial connects to the hosts of the DIDs, it refuses internal addresses
+	dial        func(ctx context.Context, network string, address string) (net.Conn, error)
+	guardOnce   sync.Once
+	guardClient *http.Client
+}
+
+// NewWebDidResolver creates a resolver using the given client, the outbound client is used if client is nil
+func NewWebDidResolver(client *http.Client) *WebDidResolver {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			return checkWebDidAddress(address)
+		},
+	}
+	return &WebDidResolver{
+		client: client,
+		dial:   dialer.DialContext,
+	}
+}
+
+// guardedClient returns the client with a transport which dials the did:web hosts through the address check.
+// The DIDs come from unauthenticated senders, so the documents are never fetched from internal addresses.
+func (w *WebDidResolver) guardedClient() *http.Client {
+	w.guardOnce.Do(func() {
+		client := w.client
+		if client == nil {
+			client = transport.Client()
+		}
+		base, ok := client.Transport.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		guarded := base.Clone()
+		// a proxy would resolve the host itself and bypass the check
+		guarded.Proxy = nil
+		guarded.DialContext = w.dial
+		guardClient := *client
+		guardClient.Transport = guarded
+		w.guardClient = &guardClient
+	})
+	return w.guardClient
+}
+
+func (w *WebDidResolver) Resolve(did string, cb *didcomm.OnDidResolverResult) didcomm.ErrorCode {
+	didDoc, err := w.ResolveDid(did)
+	return resolveCallback(didDoc, err, cb)
+}
+
+func (w *WebDidResolver) ResolveDid(did string) (*didcomm.DidDoc, error) {
+	didDocJSON, err := w.ResolveDidAsJson(did)
+	if err != nil {
+		return nil, err
+	}
+	return didDocJsonToDidDoc(*didDocJSON), nil
+}
+
+func (w *WebDidResolver) ResolveDidAsJson(did string) (*DidDocumentJSON, error) {
+	documentUrl, err := webDidUrl(did)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.guardedClient().Get(documentUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: fetching %s returned status %d", ErrInvalidWebDid, documentUrl, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebDidDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxWebDidDocumentSize {
+		return nil, fmt.Errorf("%w: document of %s is too large", ErrInvalidWebDid, did)
+	}
+
+	var doc DidDocument
+	if err = json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("%w: document can not be decoded: %w", ErrInvalidWebDid, err)
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("%w: document id %s does not match", ErrInvalidWebDid, doc.ID)
+	}
+	contextualize(&doc, did)
+	return &DidDocumentJSON{
+		DidDocument:               doc,
+		DidResolutionMetadataJSON: DidResolutionMetadataJSON{ContentType: "application/did+ld+json"},
+	}, nil
+}
+
+// webDidUrl transforms the DID into the https url of its document
+func webDidUrl(did string) (string, error) {
+	identifier := strings.TrimPrefix(did, "did:web:")
+	if identifier == did || identifier == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidWebDid, did)
+	}
+	segments := strings.Split(identifier, ":")
+	for i, segment := range segments {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil || decoded == "" || decoded == ".." || strings.ContainsAny(decoded, "/?#") {
+			return "", fmt.Errorf("%w: %s", ErrInvalidWebDid, did)
+		}
+		segments[i] = decoded
+	}
+	host := segments[0]
+	parsed, err := url.Parse("https://" + host)
+	if err != nil || parsed.Host != host {
+		return "", fmt.Errorf("%w: %s", ErrInvalidWebDid, did)
+	}
+	// internal hosts are refused, the addresses of names are checked when they are dialed
+	hostname := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if _, err := netip.ParseAddr(hostname); err == nil || hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		return "", fmt.Errorf("%w: host of %s is not a public domain", ErrInvalidWebDid, did)
+	}
+	if len(segments) == 1 {
+		return "https://" + host + "/.well-known/did.json", nil
+	}
+	return "https://" + host + "/" + strings.Join(segments[1:], "/") + "/did.json", nil
+}
+
+// checkWebDidAddress refuses to dial loopback, private, link-local and other non public addresses
+func checkWebDidAddress(address string) error {
+	hostPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected address %s", ErrInvalidWebDid, address)
+	}
+	ip := hostPort.Addr().Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: address %s is not public", ErrInvalidWebDid, ip)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package mediator
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDidUrl(t *testing.T) {
+	u, err := webDidUrl("did:web:example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/.well-known/did.json", u)
+
+	u, err = webDidUrl("did:web:example.com%3A3000:user:alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com:3000/user/alice/did.json", u)
+
+	_, err = webDidUrl("did:web:example.com:..")
+	assert.ErrorIs(t, err, ErrInvalidWebDid)
+
+	for _, did := range []string{"did:web:127.0.0.1", "did:web:169.254.169.254", "did:web:%5B%3A%3A1%5D%3A8080", "did:web:localhost", "did:web:api.localhost."} {
+		_, err = webDidUrl(did)
+		assert.ErrorIs(t, err, ErrInvalidWebDid, did)
+	}
+}
+
+func TestCheckWebDidAddress(t *testing.T) {
+	assert.Nil(t, checkWebDidAddress("93.184.215.14:443"))
+	assert.Nil(t, checkWebDidAddress("[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443"))
+	for _, address := range []string{"127.0.0.1:443", "10.1.2.3:443", "192.168.0.1:443", "169.254.169.254:80", "100.64.0.1:443", "0.0.0.0:443", "[::1]:443", "[fe80::1]:443", "[fd00::1]:443", "[::ffff:127.0.0.1]:443"} {
+		assert.ErrorIs(t, checkWebDidAddress(address), ErrInvalidWebDid, address)
+	}
+}
+
+func TestWebDidResolver(t *testing.T) {
+	var did string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did.json" && r.URL.Path != "/other/did.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"@context":"https://www.w3.org/ns/did/v1","id":"` + did + `",` +
+			`"verificationMethod":[{"id":"#key-1","type":"JsonWebKey2020","publicKeyJwk":{"kty":"OKP","crv":"X25519","x":"avH0O2Y4tqLAq8y9zpianr8ajii5m4F_mICrzNlatXs"}}],` +
+			`"keyAgreement":["#key-1"],` +
+			`"service":[{"id":"#web","type":"LinkedDomains","serviceEndpoint":"https://example.com"},` +
+			`{"id":"#didcomm","type":"DIDCommMessaging","serviceEndpoint":[{"uri":"https://example.com/didcomm","accept":["didcomm/v2"]}]}]}`))
+	}))
+	defer server.Close()
+	// the test certificate is valid for example.com, which is dialed at the test server
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	did = "did:web:example.com%3A" + port
+	resolver := NewWebDidResolver(server.Client())
+	resolver.dial = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	doc, err := resolver.ResolveDidAsJson(did)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []string{did + "#key-1"}, doc.KeyAgreement)
+	assert.Equal(t, did, doc.VerificationMethod[0].Controller)
+	assert.Equal(t, "https://example.com/didcomm", doc.Service[1].ServiceEndpoint.Uri)
+
+	_, err = resolver.ResolveDidAsJson(did + ":unknown")
+	assert.ErrorIs(t, err, ErrInvalidWebDid)
+
+	// the served document belongs to another DID
+	_, err = resolver.ResolveDidAsJson(did + ":other")
+	assert.ErrorIs(t, err, ErrInvalidWebDid)
+}
+
+func TestWebDidResolverRefusesInternalAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+	resolver := NewWebDidResolver(server.Client())
+
+	// names are checked with their resolved address when they are dialed
+	_, err := resolver.guardedClient().Get(server.URL)
+	assert.ErrorIs(t, err, ErrInvalidWebDid)
+	assert.False(t, requested)
+}
+
+func TestJsonLdContext(t *testing.T) {
+	var doc DidDocument
+	assert.Nil(t, json.Unmarshal([]byte(`{"@context":"https://www.w3.org/ns/did/v1","id":"did:web:example.com"}`), &doc))
+	assert.Equal(t, JsonLdContext{"https://www.w3.org/ns/did/v1"}, doc.Context)
+
+	doc = DidDocument{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"@context":["https://www.w3.org/ns/did/v1",{"@vocab":"https://example.com#"}]}`), &doc))
+	assert.Equal(t, JsonLdContext{"https://www.w3.org/ns/did/v1"}, doc.Context)
+
+	// type errors after the context are reported
+	err := json.Unmarshal([]byte(`{"@context":"https://www.w3.org/ns/did/v1","keyAgreement":"#key-1"}`), &doc)
+	var typeErr *json.UnmarshalTypeError
+	if assert.ErrorAs(t, err, &typeErr) {
+		assert.Equal(t, "keyAgreement", typeErr.Field)
+	}
+}
@@ -0,0 +1,86 @@
+package mediator
+
+import (
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	secretsresolver "github.com/eclipse-xfsc/didcomm-v2-connector/mediator/secretsResolver"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyFromSecret(t *testing.T) {
+	for _, keySuite := range []string{config.KEY_SUITE_ED25519, config.KEY_SUITE_P256, config.KEY_SUITE_P384, config.KEY_SUITE_SECP256K1} {
+		encryption, signing, err := generateKeyPairs(keySuite)
+		assert.Nil(t, err)
+		for _, pair := range []keyPair{encryption, signing} {
+			public, err := publicKeyFromSecret(pair.secret)
+			assert.Nil(t, err)
+			assert.Equal(t, pair.public, public, keySuite)
+		}
+	}
+}
+
+func TestWebDidDocument(t *testing.T) {
+	did := "did:web:mediator.example.com"
+	secrets := secretsresolver.NewDemo()
+	assert.Nil(t, CreateWebDidKeys(did, secrets))
+
+	services := []didcomm.Service{{
+		Id: "#service-1",
+		ServiceEndpoint: didcomm.ServiceKindDidCommMessaging{Value: didcomm.DidCommMessagingService{
+			Uri:         "https://mediator.example.com/message/receive",
+			Accept:      &[]string{"didcomm/v2"},
+			RoutingKeys: []string{},
+		}},
+	}}
+	doc, err := WebDidDocument(did, services, secrets)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{did + "#key-agreement-1"}, doc.KeyAgreement)
+	assert.Equal(t, []string{did + "#authentication-1"}, doc.Authentication)
+	assert.Equal(t, did+"#service-1", doc.Service[0].ID)
+
+	resolver := NewDidResolver()
+	resolver.AddLocalDocument(*doc)
+	didDoc, err := resolver.ResolveDid(did)
+	assert.Nil(t, err)
+	assert.Equal(t, doc.KeyAgreement, didDoc.KeyAgreement)
+	assert.Len(t, didDoc.Service, 1)
+
+	_, err = WebDidDocument("did:web:other.example.com", services, secrets)
+	assert.NotNil(t, err)
+
+	path, err := WebDidDocumentPath("did:web:mediator.example.com:tenant")
+	assert.Nil(t, err)
+	assert.Equal(t, "/tenant/did.json", path)
+}
+
+func TestCreateWebDidKeysKeepsFormerKeys(t *testing.T) {
+	did := "did:web:mediator.example.com"
+	secrets := secretsresolver.NewDemo()
+	assert.Nil(t, CreateWebDidKeys(did, secrets))
+	first, err := secrets.ListSecrets(did)
+	assert.Nil(t, err)
+
+	// the did:web is configured again after another DID was used
+	assert.Nil(t, CreateWebDidKeys(did, secrets))
+	again, err := secrets.ListSecrets(did)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, first, again)
+
+	// retired keys are not overwritten, the new keys get the next number
+	for _, secret := range first {
+		assert.Nil(t, secrets.RetireSecret(secret.Id))
+	}
+	assert.Nil(t, CreateWebDidKeys(did, secrets))
+	doc, err := WebDidDocument(did, nil, secrets)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{did + "#key-agreement-2"}, doc.KeyAgreement)
+	assert.Equal(t, []string{did + "#authentication-2"}, doc.Authentication)
+	all, err := secrets.ListSecrets(did)
+	assert.Nil(t, err)
+	for _, secret := range all {
+		assert.Equal(t, webDidKeyIndex(secret.Id) == 1, secret.IsRetired(), secret.Id)
+	}
+}
@@ -1,10 +1,59 @@
 package messaging
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// cloud event extensions with the DIDComm thread of a device message
+	EXTENSION_THID  = "thid"
+	EXTENSION_PTHID = "pthid"
+	// cloud event extensions with the DIDComm message type, the recipient DID and the group of the connection
+	EXTENSION_TYPE          = "didcommtype"
+	EXTENSION_RECIPIENT_DID = "recipientdid"
+	EXTENSION_GROUP         = "group"
+	// cloud event extension of dead events with the outbox id for a replay
+	EXTENSION_OUTBOX_ID = "outboxid"
+	// cloud event extension of delivery status events with the id of the cloud event they report on
+	EXTENSION_EVENT_ID = "eventid"
+)
+
+// type of the delivery status events and their states
+const (
+	EVENT_TYPE_DELIVERY_STATUS = "didcomm.delivery.status"
+
+	// parked for the pickup of the device
+	DELIVERY_STATUS_QUEUED = "queued"
+	// pushed to the endpoint of the device or picked up by the device
+	DELIVERY_STATUS_DELIVERED = "delivered"
+	// the device confirmed the message with messages-received
+	DELIVERY_STATUS_ACKNOWLEDGED = "acknowledged"
+	// the message expired before it was forwarded or picked up
+	DELIVERY_STATUS_EXPIRED = "expired"
+	DELIVERY_STATUS_FAILED  = "failed"
+)
 
 type ConnectorMessage struct {
 	Did     string          `json:"did"`
 	Payload json.RawMessage `json:"payload"`
+	// thread of the payload, the thid or id of a plaintext payload is used if it is not given
+	Thid  string `json:"thid,omitempty"`
+	Pthid string `json:"pthid,omitempty"`
+	// topic or subject for the replies of the device on the thread
+	ReplyTo string `json:"replyTo,omitempty"`
+	// unix time after which the message is not delivered anymore, 0 if it does not expire
+	ExpiresTime uint64 `json:"expiresTime,omitempty"`
+}
+
+// DeliveryStatus is published to the status topic when a connector message reaches a state of its delivery
+type DeliveryStatus struct {
+	// id of the cloud event with the connector message
+	EventId      string    `json:"eventId"`
+	Status       string    `json:"status"`
+	RecipientDid string    `json:"recipientDid"`
+	Reason       string    `json:"reason,omitempty"`
+	Time         time.Time `json:"time"`
 }
 
 type InvitationNotify struct {
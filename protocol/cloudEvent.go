@@ -1,32 +1,55 @@
 package protocol
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
-	"sync"
-	"text/template"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/cloudevents/sdk-go/v2/event"
 	cloudeventprovider "github.com/eclipse-xfsc/cloud-event-provider"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/cloudevent"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/kafka"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/mapping"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func SendMessage(message map[string]interface{}, mediatee *database.Mediatee) error {
+// SendMessage stores a message for the recipient DID in the outbox, it is published to the topic of the mediatee
+// or to the reply topic of the thread
+func SendMessage(ctx context.Context, outbox *outbox.Outbox, message map[string]interface{}, mediatee *database.Mediatee, recipientDid string, thread database.Thread) error {
 
 	switch config.CurrentConfiguration.CloudForwarding.Protocol {
 	case config.HTTP:
-		return sendCloudEvent(message, mediatee, mediatee.Topic)
+		return sendCloudEvent(ctx, outbox, message, mediatee, replyTopic(thread, mediatee.Topic), recipientDid, thread)
 	case config.NATS:
-		return sendCloudEvent(message, mediatee, mediatee.Topic)
+		return sendCloudEvent(ctx, outbox, message, mediatee, replyTopic(thread, mediatee.Topic), recipientDid, thread)
+	case config.KAFKA:
+		return sendCloudEvent(ctx, outbox, message, mediatee, replyTopic(thread, mediatee.Topic), recipientDid, thread)
+	case config.MQTT:
+		if thread.ReplyTo != "" {
+			return sendCloudEvent(ctx, outbox, message, mediatee, thread.ReplyTo, recipientDid, thread)
+		}
+		topic, err := mqttTopic(mediatee.Topic, mediatee)
+		if err != nil {
+			return deadCloudEvent(ctx, outbox, message, mediatee, mediatee.Topic, recipientDid, thread, err)
+		}
+		return sendCloudEvent(ctx, outbox, message, mediatee, topic, recipientDid, thread)
 	case config.HYBRID:
 		// implement hybrid mode if cloud event provider supports it
 		return errors.New("hybrid mode not supported: message will not be sent")
@@ -35,137 +58,423 @@ func SendMessage(message map[string]interface{}, mediatee *database.Mediatee) er
 	}
 }
 
-func ReceiveMessage(mediator *mediator.Mediator) {
+// ErrNotReceiving is returned by CheckReceiving while no cloud events are consumed
+var ErrNotReceiving = errors.New("not subscribed to cloud events")
+
+// consumer of ReceiveMessage, nil before the start
+var receiving atomic.Pointer[cloudevent.Consumer]
+
+// CheckReceiving fails if ReceiveMessage has not subscribed to the inbound cloud events or lost the subscription
+func CheckReceiving() error {
+	consumer := receiving.Load()
+	if consumer == nil || !(*consumer).Connected() {
+		return ErrNotReceiving
+	}
+	return nil
+}
+
+// ReceiveMessage forwards the inbound cloud events to the devices until the context is done
+func ReceiveMessage(ctx context.Context, mediator *mediator.Mediator) {
 
 	config.Logger.Info("Start messaging", "context")
 
-	topic := config.CurrentConfiguration.CloudForwarding.Nats.Topic
+	topic := messagingTopic()
+	consumer := cloudevent.NewConsumer(topic)
+	receiving.Store(&consumer)
+	defer receiving.Store(nil)
+	routing := NewRouting(mediator)
 
-	client, err := cloudeventprovider.New(cloudeventprovider.Config{
-		Protocol: cloudeventprovider.ProtocolTypeNats,
-		Settings: cloudeventprovider.NatsConfig{
-			Url:        config.CurrentConfiguration.CloudForwarding.Nats.Url,
-			QueueGroup: config.CurrentConfiguration.CloudForwarding.Nats.QueueGroup,
-		},
-	}, cloudeventprovider.Sub, topic)
+	go func() {
+		<-ctx.Done()
+		consumer.Close()
+	}()
+
+	config.Logger.Info("Receiving cloud events", "topic", topic)
+
+	err := consumer.Consume(func(event event.Event, last bool) error {
+		return forwardCloudEvent(routing, event, last)
+	})
 	if err != nil {
-		config.Logger.Error("unable to connect to cloud event provider", "msg", err)
+		config.Logger.Error("Error in subscription of cloud event", "msg", err)
 	}
+}
 
-	defer client.Close()
-	routing := NewRouting(mediator)
-	// Use a WaitGroup to wait for a message to arrive
-	wg := sync.WaitGroup{}
-	wg.Add(1)
+// forwardCloudEvent forwards the connector message of the event to its device. Events which are no connector
+// messages or can never be forwarded fail with cloudevent.ErrInvalidEvent. last is unset if the event is delivered
+// again when the forward fails.
+func forwardCloudEvent(routing *Routing, event event.Event, last bool) error {
 
-	config.Logger.Info("Receiving cloud events", "topic", topic)
+	config.Logger.Info("Received cloud event", "context", event.Context)
+	config.Logger.Info("Data", "context", string(event.DataEncoded))
 
-	err = client.Sub(func(event event.Event) {
+	var incomingMessage json.RawMessage
+	err := json.Unmarshal(event.DataEncoded, &incomingMessage)
+	if err != nil {
+		return fmt.Errorf("%w: %w", cloudevent.ErrInvalidEvent, err)
+	}
 
-		config.Logger.Info("Received cloud event", "context", event.Context)
-		config.Logger.Info("Data", "context", string(event.DataEncoded))
+	var content messaging.ConnectorMessage
 
-		var incomingMessage json.RawMessage
-		err := json.Unmarshal(event.DataEncoded, &incomingMessage)
-		if err != nil {
-			config.Logger.Error("error while unmarshalling received nats message")
-			return
-		}
+	err = json.Unmarshal(incomingMessage, &content)
 
-		var content messaging.ConnectorMessage
+	if err != nil {
+		return fmt.Errorf("%w: %w", cloudevent.ErrInvalidEvent, err)
+	}
 
-		err = json.Unmarshal(incomingMessage, &content)
+	// the trace of the cloud service is continued
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), event), "forwardCloudEvent",
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attribute.String("cloudevents.event_id", event.ID())))
+	// the forward carries the id of the event, the delivery status is sent with it
+	err = forwardConnectorMessage(ctx, routing, event.ID(), content, incomingMessage, last)
+	tracing.End(span, err)
+	return err
+}
 
-		if err != nil {
-			config.Logger.Error("error while unmarshalling received nats message")
-			return
-		}
+// forwardConnectorMessage forwards the connector message to the device of its DID, raw is attached to the forward
+func forwardConnectorMessage(ctx context.Context, routing *Routing, id string, content messaging.ConnectorMessage, raw json.RawMessage, last bool) error {
+	rememberThread(ctx, routing.mediator, content)
 
-		attachment := didcomm.Attachment{
-			Data: didcomm.AttachmentDataBase64{
-				Value: didcomm.Base64AttachmentData{
-					Base64: base64.StdEncoding.EncodeToString(incomingMessage),
-				},
+	attachment := didcomm.Attachment{
+		Data: didcomm.AttachmentDataBase64{
+			Value: didcomm.Base64AttachmentData{
+				Base64: base64.StdEncoding.EncodeToString(raw),
 			},
-		}
+		},
+	}
 
-		var body = make(map[string]interface{})
+	var body = make(map[string]interface{})
 
-		body["next"] = content.Did
+	body["next"] = content.Did
 
-		bodyJson, err := json.Marshal(body)
-		if err != nil {
-			panic(err)
-		}
+	bodyJson, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
 
-		message := didcomm.Message{
-			Id:          uuid.NewString(),
-			Type:        PIURI_ROUTING_FORWARD,
-			To:          &[]string{mediator.Did},
-			Attachments: &[]didcomm.Attachment{attachment},
-			Body:        string(bodyJson),
-		}
+	message := didcomm.Message{
+		Id:          id,
+		Type:        PIURI_ROUTING_FORWARD,
+		To:          &[]string{routing.mediator.Did},
+		Attachments: &[]didcomm.Attachment{attachment},
+		Body:        string(bodyJson),
+	}
+	if message.Id == "" {
+		message.Id = uuid.NewString()
+	}
+	if content.ExpiresTime != 0 {
+		message.ExpiresTime = &content.ExpiresTime
+	}
+
+	_, err = routing.handleForward(ctx, message, false, last)
+	return err
+}
+
+// PublishOutbox publishes the cloud events of the outbox with the publishers of the mediator, failed events are retried
+func PublishOutbox(mediator *mediator.Mediator) {
+	config.Logger.Info("Publishing cloud events of the outbox")
+	mediator.Outbox.Run(context.Background(), func(topic string, outboxEvent database.OutboxEvent) error {
+		return publishCloudEvent(mediator.Publishers, topic, outboxEvent)
+	})
+}
+
+// sendCloudEvent creates the payload of the cloud event and stores it in the outbox
+func sendCloudEvent(ctx context.Context, outbox *outbox.Outbox, message any, mediatee *database.Mediatee, topic string, recipientDid string, thread database.Thread) error {
+	if topic == "" {
+		topic = "default-http"
+	}
+
+	config.Logger.Info(fmt.Sprintf("message to send as cloud event: %s", message))
+
+	payload, err := cloudEventPayload(message, mediatee)
+	if err != nil {
+		return deadCloudEvent(ctx, outbox, message, mediatee, topic, recipientDid, thread, err)
+	}
 
-		routing.handleForward(message, false)
+	outboxEvent := newOutboxEvent(ctx, message, mediatee, topic, recipientDid, thread)
+	outboxEvent.Payload = string(payload)
+	var event database.OutboxEvent
+	err = tracing.Database(ctx, "AddOutboxEvent", func() (err error) {
+		event, err = outbox.Add(outboxEvent)
+		return err
 	})
 	if err != nil {
-		config.Logger.Error("Error in subscription of cloud event", "msg", err)
+		config.Logger.Error("failed to store cloud event", "msg", err)
+		return err
 	}
 
-	// Wait for a message to come in
-	wg.Wait()
+	config.Logger.Info("stored cloud event", "id", event.Id, "topic", topic)
+	return nil
 }
 
-func sendCloudEvent(message any, mediatee *database.Mediatee, topic string) (err error) {
-	if topic == "" {
-		topic = "default-http"
+// deadCloudEvent keeps a message whose cloud event can not be created as dead event in the outbox
+func deadCloudEvent(ctx context.Context, outbox *outbox.Outbox, message any, mediatee *database.Mediatee, topic string, recipientDid string, thread database.Thread, cause error) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return errors.Join(cause, err)
 	}
-	client, err := cloudeventprovider.New(cloudeventprovider.Config{
-		Protocol: cloudeventprovider.ProtocolTypeNats,
-		Settings: cloudeventprovider.NatsConfig{
-			Url:        config.CurrentConfiguration.CloudForwarding.Nats.Url,
-			QueueGroup: config.CurrentConfiguration.CloudForwarding.Nats.QueueGroup,
-		},
-	}, cloudeventprovider.Pub, topic)
+	outboxEvent := newOutboxEvent(ctx, message, mediatee, topic, recipientDid, thread)
+	outboxEvent.Payload = string(raw)
+	_, err = outbox.AddDead(outboxEvent, cause)
+	return err
+}
+
+// newOutboxEvent takes the attributes of the cloud event from the message and the mediatee, and the trace from ctx
+func newOutboxEvent(ctx context.Context, message any, mediatee *database.Mediatee, topic string, recipientDid string, thread database.Thread) database.OutboxEvent {
+	outboxEvent := database.OutboxEvent{
+		RemoteDid:    mediatee.RemoteDid,
+		RecipientDid: recipientDid,
+		Topic:        topic,
+		EventType:    mediatee.EventType,
+		Thid:         thread.Thid,
+		Pthid:        thread.Pthid,
+		Group:        mediatee.Group,
+		DataSchema:   mediatee.DataSchema,
+	}
+	outboxEvent.TraceParent, outboxEvent.TraceState = tracing.TraceContext(ctx)
+	// only plaintext DIDComm messages show their headers
+	if content, ok := message.(map[string]interface{}); ok {
+		outboxEvent.MessageId, _ = content["id"].(string)
+		outboxEvent.MessageType, _ = content["type"].(string)
+		if createdTime, ok := content["created_time"].(float64); ok {
+			outboxEvent.Created = time.Unix(int64(createdTime), 0).UTC()
+		}
+	}
+	return outboxEvent
+}
+
+// cloudEventPayload maps the message with the properties of the mediatee
+func cloudEventPayload(message any, mediatee *database.Mediatee) ([]byte, error) {
+	propertiesMapping, err := mapping.Parse(mediatee.Properties)
 	if err != nil {
-		config.Logger.Error("Can not create cloudevent client", "msg", err)
-		return
+		return nil, err
+	}
+	values, err := propertiesMapping.Render(message)
+	if err != nil {
+		return nil, err
 	}
-	defer client.Close()
+	values["routingKey"] = mediatee.RoutingKey
+	values["remoteDid"] = mediatee.RemoteDid
+	return json.Marshal(values)
+}
 
-	sourceUrl, err := url.JoinPath(config.CurrentConfiguration.CloudForwarding.Nats.Url)
+// publishCloudEvent publishes an event of the outbox. The id of the DIDComm message is the id of the cloud event,
+// the outbox id if the message has none.
+func publishCloudEvent(publishers *cloudevent.Pool, topic string, outboxEvent database.OutboxEvent) (err error) {
+	// the event continues the trace of the message it was stored with
+	ctx, span := tracing.Start(tracing.WithTraceContext(context.Background(), outboxEvent.TraceParent, outboxEvent.TraceState), "publishCloudEvent",
+		trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attribute.String("messaging.destination.name", topic)))
+	defer func() { tracing.End(span, err) }()
 
-	config.Logger.Info(fmt.Sprintf("message to send as cloud event: %s", message))
+	event, err := newCloudEvent(outboxEvent)
+	if err != nil {
+		return err
+	}
+	tracing.Inject(ctx, &event)
 
-	mediatee.Properties["routingKey"] = mediatee.RoutingKey
-	mediatee.Properties["remoteDid"] = mediatee.RemoteDid
+	err = publishers.Publish(topic, event)
+	metrics.CloudPublished.WithLabelValues(config.CurrentConfiguration.CloudForwarding.Protocol, metrics.Result(err)).Inc()
+	if err != nil {
+		return err
+	}
 
-	jsonData, err := json.Marshal(mediatee.Properties)
+	config.Logger.Info("published cloud event", "id", outboxEvent.Id, "topic", topic)
+	return nil
+}
 
+// newCloudEvent sets the attributes and extensions of the cloud event, consumers can route and filter on them
+func newCloudEvent(outboxEvent database.OutboxEvent) (event.Event, error) {
+	sourceUrl, err := url.JoinPath(cloudevent.Source())
 	if err != nil {
-		config.Logger.Error("cant marshal properties", "msg", err)
-		return
+		return event.Event{}, err
 	}
 
-	tmpl, err := template.New("template").Parse(string(jsonData))
-	var result bytes.Buffer
-	err = tmpl.Execute(&result, message)
+	cloudEvent, err := cloudeventprovider.NewEvent(sourceUrl, outboxEvent.EventType, []byte(outboxEvent.Payload))
 	if err != nil {
-		panic(err)
+		return cloudEvent, err
+	}
+	cloudEvent.SetID(outboxEvent.Id)
+	if outboxEvent.MessageId != "" {
+		cloudEvent.SetID(outboxEvent.MessageId)
+	}
+	cloudEvent.SetTime(outboxEvent.Added)
+	if !outboxEvent.Created.IsZero() {
+		cloudEvent.SetTime(outboxEvent.Created)
+	}
+	cloudEvent.SetSubject(outboxEvent.RemoteDid)
+	if outboxEvent.DataSchema != "" {
+		cloudEvent.SetDataSchema(outboxEvent.DataSchema)
+	}
+
+	// Kafka keys the message with the partition key, all messages of a recipient stay in order
+	cloudEvent.SetExtension(kafka.PARTITION_KEY, outboxEvent.RecipientDid)
+	cloudEvent.SetExtension(messaging.EXTENSION_RECIPIENT_DID, outboxEvent.RecipientDid)
+	optionalExtensions := map[string]string{
+		messaging.EXTENSION_TYPE:     outboxEvent.MessageType,
+		messaging.EXTENSION_THID:     outboxEvent.Thid,
+		messaging.EXTENSION_PTHID:    outboxEvent.Pthid,
+		messaging.EXTENSION_GROUP:    outboxEvent.Group,
+		messaging.EXTENSION_EVENT_ID: outboxEvent.EventId,
 	}
+	for name, value := range optionalExtensions {
+		if value != "" {
+			cloudEvent.SetExtension(name, value)
+		}
+	}
+	if outboxEvent.Status == database.OUTBOX_STATUS_DEAD {
+		cloudEvent.SetExtension("deadletterreason", outboxEvent.LastError)
+		cloudEvent.SetExtension("originaltopic", outboxEvent.Topic)
+		cloudEvent.SetExtension(messaging.EXTENSION_OUTBOX_ID, outboxEvent.Id)
+	}
+	return cloudEvent, cloudEvent.Validate()
+}
 
-	event, err := cloudeventprovider.NewEvent(sourceUrl, mediatee.EventType, result.Bytes())
+// mqttTopic renders the topic template with the topic and the group of the connection
+func mqttTopic(baseTopic string, mediatee *database.Mediatee) (string, error) {
+	tmpl, err := config.MqttTopicTemplate()
 	if err != nil {
-		config.Logger.Error("failed to create cloud event", "msg", err)
-		return
+		return "", err
+	}
+	var topic strings.Builder
+	err = tmpl.Execute(&topic, struct {
+		Topic     string
+		Group     string
+		RemoteDid string
+	}{baseTopic, mediatee.Group, mediatee.RemoteDid})
+	if err != nil {
+		return "", err
+	}
+	// wildcards are only allowed in subscriptions
+	if strings.ContainsAny(topic.String(), "+#") {
+		return "", fmt.Errorf("mqtt topic %s contains wildcards", topic.String())
 	}
+	return topic.String(), nil
+}
+
+// sendInvitationNotify publishes the invitation of a connection to the invitation topic, for MQTT it is rendered
+// with the topic template like the topics of the device messages
+func sendInvitationNotify(ctx context.Context, outbox *outbox.Outbox, inv messaging.InvitationNotify, mediatee *database.Mediatee) error {
+	topic := messagingTopic() + "-invitation"
+	if config.IsForwardTypeMqtt() {
+		rendered, err := mqttTopic(topic, mediatee)
+		if err != nil {
+			return deadCloudEvent(ctx, outbox, inv, mediatee, topic, mediatee.RemoteDid, database.Thread{}, err)
+		}
+		topic = rendered
+	}
+	return sendCloudEvent(ctx, outbox, inv, mediatee, topic, mediatee.RemoteDid, database.Thread{})
+}
 
-	if err = client.Pub(event); err != nil {
-		config.Logger.Error("failed to send cloud event", "msg", err)
+// messagingTopic is the topic of the ConnectorMessage events of the configured protocol
+func messagingTopic() string {
+	switch {
+	case config.IsForwardTypeKafka():
+		return config.CurrentConfiguration.CloudForwarding.Kafka.Topic
+	case config.IsForwardTypeMqtt():
+		return config.CurrentConfiguration.CloudForwarding.Mqtt.Topic
+	}
+	return config.CurrentConfiguration.CloudForwarding.Nats.Topic
+}
+
+// rememberThread stores the thread of a cloud request with a reply topic, so that the replies of the device are published there
+func rememberThread(ctx context.Context, mediator *mediator.Mediator, content messaging.ConnectorMessage) {
+	ttl := time.Duration(config.CurrentConfiguration.CloudForwarding.Threads.Ttl) * time.Second
+	thread := requestThread(content)
+	if ttl <= 0 || thread.Thid == "" || thread.ReplyTo == "" {
+		return
+	}
+	if err := checkReplyTo(thread.ReplyTo); err != nil {
+		config.Logger.Warn("Reply topic is ignored", "thid", thread.Thid, "err", err)
 		return
 	}
+	err := tracing.Database(ctx, "AddThread", func() error {
+		return mediator.Database.AddThread(thread, ttl)
+	})
+	if err != nil {
+		config.Logger.Error("Unable to store thread", "thid", thread.Thid, "err", err)
+	}
+}
 
-	config.Logger.Info("published cloud event", "topic", topic)
+// requestThread is the thread of a cloud request, given by the connector message or by a plaintext payload
+func requestThread(content messaging.ConnectorMessage) database.Thread {
+	thread := database.Thread{RecipientDid: content.Did, Thid: content.Thid, Pthid: content.Pthid, ReplyTo: content.ReplyTo}
+	if thread.Thid != "" {
+		return thread
+	}
+	var payload struct {
+		Id    string `json:"id"`
+		Thid  string `json:"thid"`
+		Pthid string `json:"pthid"`
+	}
+	// encrypted payloads carry no thread
+	if err := json.Unmarshal(content.Payload, &payload); err != nil {
+		return thread
+	}
+	// the id of the first message of a thread is the thread id
+	thread.Thid = payload.Thid
+	if thread.Thid == "" {
+		thread.Thid = payload.Id
+	}
+	if thread.Pthid == "" {
+		thread.Pthid = payload.Pthid
+	}
+	return thread
+}
 
-	return
+// replyThread is the thread of a device message with the reply topic of the cloud request which started it
+func replyThread(ctx context.Context, db database.Adapter, recipientDid string, content map[string]interface{}, forward didcomm.Message) (database.Thread, error) {
+	thread := database.Thread{RecipientDid: recipientDid}
+	thread.Thid, _ = content["thid"].(string)
+	thread.Pthid, _ = content["pthid"].(string)
+	// an encrypted message only shows the thread of the forward which carried it
+	if thread.Thid == "" && forward.Thid != nil {
+		thread.Thid = *forward.Thid
+	}
+	if thread.Pthid == "" && forward.Pthid != nil {
+		thread.Pthid = *forward.Pthid
+	}
+	// a child thread is answered like its parent
+	for _, thid := range []string{thread.Thid, thread.Pthid} {
+		if thid == "" {
+			continue
+		}
+		request, err := tracing.Query(ctx, "GetThread", func() (*database.Thread, error) {
+			return db.GetThread(recipientDid, thid)
+		})
+		if err != nil {
+			return thread, err
+		}
+		if request != nil {
+			thread.ReplyTo = request.ReplyTo
+			break
+		}
+	}
+	return thread, nil
+}
+
+func replyTopic(thread database.Thread, topic string) string {
+	if thread.ReplyTo != "" {
+		return thread.ReplyTo
+	}
+	return topic
+}
+
+var kafkaTopicPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// checkReplyTo rejects reply topics which can not be published to
+func checkReplyTo(replyTo string) error {
+	switch {
+	case config.IsForwardTypeKafka():
+		if !kafkaTopicPattern.MatchString(replyTo) {
+			return fmt.Errorf("kafka topic %s is invalid", replyTo)
+		}
+	case config.IsForwardTypeMqtt():
+		if strings.ContainsAny(replyTo, "+#") {
+			return fmt.Errorf("mqtt topic %s contains wildcards", replyTo)
+		}
+	default:
+		if strings.ContainsAny(replyTo, "*> \t\r\n") {
+			return fmt.Errorf("nats subject %s contains wildcards or whitespace", replyTo)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/cloudevent"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestThread(t *testing.T) {
+	thread := requestThread(messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`{"id":"1","pthid":"0"}`), ReplyTo: "replies"})
+	assert.Equal(t, database.Thread{RecipientDid: "did:peer:2.a", Thid: "1", Pthid: "0", ReplyTo: "replies"}, thread)
+
+	thread = requestThread(messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`{"id":"2","thid":"1"}`)})
+	assert.Equal(t, "1", thread.Thid)
+
+	thread = requestThread(messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`"eyJhbGciOi..."`), Thid: "3"})
+	assert.Equal(t, "3", thread.Thid)
+
+	thread = requestThread(messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`"eyJhbGciOi..."`)})
+	assert.Empty(t, thread.Thid)
+}
+
+func TestReplyThread(t *testing.T) {
+	config.Logger = slog.Default()
+	config.CurrentConfiguration.CloudForwarding.Protocol = config.NATS
+	config.CurrentConfiguration.CloudForwarding.Threads.Ttl = 60
+	defer func() { config.CurrentConfiguration.CloudForwarding.Threads.Ttl = 0 }()
+	med := &mediator.Mediator{Database: database.NewDemo()}
+
+	rememberThread(context.Background(), med, messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`{"id":"1"}`), ReplyTo: "replies.service"})
+	rememberThread(context.Background(), med, messaging.ConnectorMessage{Did: "did:peer:2.a", Payload: json.RawMessage(`{"id":"2"}`), ReplyTo: "replies.*"})
+
+	thread, err := replyThread(context.Background(), med.Database, "did:peer:2.a", map[string]interface{}{"id": "r1", "thid": "1"}, didcomm.Message{})
+	assert.Nil(t, err)
+	assert.Equal(t, "replies.service", thread.ReplyTo)
+	assert.Equal(t, "1", thread.Thid)
+
+	// a child thread and the thread of the forward are answered to the reply topic as well
+	thread, err = replyThread(context.Background(), med.Database, "did:peer:2.a", map[string]interface{}{"id": "r2", "thid": "c1", "pthid": "1"}, didcomm.Message{})
+	assert.Nil(t, err)
+	assert.Equal(t, "replies.service", thread.ReplyTo)
+	thid := "1"
+	thread, err = replyThread(context.Background(), med.Database, "did:peer:2.a", map[string]interface{}{"protected": "..."}, didcomm.Message{Thid: &thid})
+	assert.Nil(t, err)
+	assert.Equal(t, "replies.service", thread.ReplyTo)
+
+	// wildcard reply topics and other recipients are not correlated
+	thread, err = replyThread(context.Background(), med.Database, "did:peer:2.a", map[string]interface{}{"thid": "2"}, didcomm.Message{})
+	assert.Nil(t, err)
+	assert.Empty(t, thread.ReplyTo)
+	thread, err = replyThread(context.Background(), med.Database, "did:peer:2.b", map[string]interface{}{"thid": "1"}, didcomm.Message{})
+	assert.Nil(t, err)
+	assert.Empty(t, thread.ReplyTo)
+	assert.Equal(t, "1", thread.Thid)
+}
+
+func TestNewCloudEvent(t *testing.T) {
+	config.CurrentConfiguration.CloudForwarding.Protocol = config.NATS
+	config.CurrentConfiguration.CloudForwarding.Nats.Url = "nats://localhost:4222"
+	mediatee := &database.Mediatee{RemoteDid: "did:peer:2.device", EventType: "device.message", Group: "fleet", DataSchema: "https://example.com/schemas/temperature.json"}
+	message := map[string]interface{}{"id": "m1", "type": "https://example.com/temperature/1.0/reading", "created_time": float64(1700000000)}
+
+	outboxEvent := newOutboxEvent(context.Background(), message, mediatee, "devices", "did:peer:2.recipient", database.Thread{Thid: "t1"})
+	outboxEvent.Id = "o1"
+	outboxEvent.Payload = `{"temperature":21.5}`
+	cloudEvent, err := newCloudEvent(outboxEvent)
+	assert.Nil(t, err)
+	assert.Equal(t, "m1", cloudEvent.ID())
+	assert.Equal(t, "did:peer:2.device", cloudEvent.Subject())
+	assert.Equal(t, int64(1700000000), cloudEvent.Time().Unix())
+	assert.Equal(t, "https://example.com/schemas/temperature.json", cloudEvent.DataSchema())
+	assert.Equal(t, "https://example.com/temperature/1.0/reading", cloudEvent.Extensions()[messaging.EXTENSION_TYPE])
+	assert.Equal(t, "t1", cloudEvent.Extensions()[messaging.EXTENSION_THID])
+	assert.Equal(t, "did:peer:2.recipient", cloudEvent.Extensions()[messaging.EXTENSION_RECIPIENT_DID])
+	assert.Equal(t, "fleet", cloudEvent.Extensions()[messaging.EXTENSION_GROUP])
+	assert.NotContains(t, cloudEvent.Extensions(), messaging.EXTENSION_PTHID)
+
+	// events without a DIDComm message keep the outbox id
+	outboxEvent = newOutboxEvent(context.Background(), messaging.InvitationNotify{InvitationId: "i1"}, mediatee, "devices-invitation", "did:peer:2.device", database.Thread{})
+	outboxEvent.Id = "o2"
+	outboxEvent.Payload = `{}`
+	cloudEvent, err = newCloudEvent(outboxEvent)
+	assert.Nil(t, err)
+	assert.Equal(t, "o2", cloudEvent.ID())
+	assert.NotContains(t, cloudEvent.Extensions(), messaging.EXTENSION_TYPE)
+}
+
+func TestForwardCloudEvent_Invalid(t *testing.T) {
+	config.Logger = slog.Default()
+	routing := NewRouting(&mediator.Mediator{Database: database.NewDemo()})
+
+	invalid := cloudevents.NewEvent()
+	invalid.DataEncoded = []byte(`{"did":`)
+	assert.ErrorIs(t, forwardCloudEvent(routing, invalid, true), cloudevent.ErrInvalidEvent)
+
+	// messages to unknown recipients are dropped and acknowledged
+	unknown := cloudevents.NewEvent()
+	unknown.DataEncoded = []byte(`{"did":"did:peer:2.unknown","payload":{}}`)
+	assert.Nil(t, forwardCloudEvent(routing, unknown, true))
+}
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
 	"slices"
 
@@ -356,7 +357,7 @@ func (h *CoordinateMediation) handleMediationRequest(message didcomm.Message, be
 		From: &h.mediator.Did,
 	}
 
-	err = h.mediator.ConnectionManager.StoreConnection(invitation.Protocol, *message.From, invitation.Topic, invitation.Properties, invitation.EventType, []string{routingKey}, invitation.Group)
+	err = h.mediator.ConnectionManager.StoreConnection(invitation.Protocol, *message.From, invitation.Topic, invitation.Properties, invitation.EventType, []string{routingKey}, invitation.Group, invitation.DataSchema)
 
 	if err != nil {
 		config.Logger.Error("error finalizing mediatee", err)
@@ -394,7 +395,7 @@ func (h *CoordinateMediation) handleMediationRequest(message didcomm.Message, be
 		Did:          mediatee.RoutingKey,
 	}
 
-	err = sendCloudEvent(inv, mediatee, config.CurrentConfiguration.CloudForwarding.Nats.Topic+"-invitation")
+	err = sendInvitationNotify(context.Background(), h.mediator.Outbox, inv, mediatee)
 
 	return response, err
 }
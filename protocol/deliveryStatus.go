@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
+)
+
+func deliveryStatusEnabled() bool {
+	return config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic != ""
+}
+
+// sendDeliveryStatus stores the delivery status of a forwarded cloud event in the outbox. Messages which were not
+// forwarded from a cloud event have no event id and no status.
+func sendDeliveryStatus(outbox *outbox.Outbox, eventId string, recipientDid string, status string, reason error) {
+	if !deliveryStatusEnabled() || eventId == "" {
+		return
+	}
+	deliveryStatus := messaging.DeliveryStatus{
+		EventId:      eventId,
+		Status:       status,
+		RecipientDid: recipientDid,
+		Time:         time.Now().UTC(),
+	}
+	if reason != nil {
+		deliveryStatus.Reason = reason.Error()
+	}
+	payload, err := json.Marshal(deliveryStatus)
+	if err != nil {
+		config.Logger.Error("Unable to create delivery status", "eventId", eventId, "status", status, "err", err)
+		return
+	}
+	_, err = outbox.Add(database.OutboxEvent{
+		RecipientDid: recipientDid,
+		Topic:        config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic,
+		EventType:    messaging.EVENT_TYPE_DELIVERY_STATUS,
+		Payload:      string(payload),
+		EventId:      eventId,
+	})
+	if err != nil {
+		config.Logger.Error("Unable to store delivery status", "eventId", eventId, "status", status, "err", err)
+	}
+}
+
+// forwardedMessages returns the stored messages which were forwarded from cloud events by their id
+func forwardedMessages(ctx context.Context, db database.Adapter, messageIds []string) map[string]database.Message {
+	messages := map[string]database.Message{}
+	if !deliveryStatusEnabled() {
+		return messages
+	}
+	for _, id := range messageIds {
+		message, err := tracing.Query(ctx, "GetMessage", func() (*database.Message, error) {
+			return db.GetMessage(id)
+		})
+		if err != nil || message == nil {
+			config.Logger.Warn("Unable to read message for its delivery status", "id", id, "err", err)
+			continue
+		}
+		if message.EventId != "" {
+			messages[id] = *message
+		}
+	}
+	return messages
+}
@@ -0,0 +1,132 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/cloudevent"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func deliveryStatuses(t *testing.T, o *outbox.Outbox) map[string]string {
+	events, err := o.Events(database.OUTBOX_STATUS_PENDING)
+	assert.Nil(t, err)
+	statuses := map[string]string{}
+	for _, event := range events {
+		assert.Equal(t, "delivery-status", event.Topic)
+		assert.Equal(t, messaging.EVENT_TYPE_DELIVERY_STATUS, event.EventType)
+		var status messaging.DeliveryStatus
+		assert.Nil(t, json.Unmarshal([]byte(event.Payload), &status))
+		assert.Equal(t, status.EventId, event.EventId)
+		statuses[status.EventId] = status.Status
+		assert.Nil(t, o.Delete(event.Id))
+	}
+	return statuses
+}
+
+func TestDeliveryStatus(t *testing.T) {
+	config.Logger = slog.Default()
+	config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic = "delivery-status"
+	defer func() { config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic = "" }()
+	db := database.NewDemo()
+	med := &mediator.Mediator{Database: db, Outbox: outbox.NewOutbox(db)}
+	remoteDid := "did:peer:2.remote"
+	recipientDid := "did:peer:2.recipient"
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: remoteDid, RecipientDids: []string{recipientDid}}))
+
+	attachment := func() didcomm.Attachment {
+		return didcomm.Attachment{Data: didcomm.AttachmentDataBase64{Value: didcomm.Base64AttachmentData{Base64: "e30="}}}
+	}
+	assert.Nil(t, db.AddMessage(recipientDid, attachment(), "e1", time.Time{}))
+	assert.Nil(t, db.AddMessage(recipientDid, attachment(), "e2", time.Now().Add(-time.Minute)))
+	assert.Nil(t, db.AddMessage(recipientDid, attachment(), "", time.Time{}))
+
+	pickup := NewMessagePickup(med)
+	response, err := pickup.handleDeliveryRequest(context.Background(), didcomm.Message{From: &remoteDid, Body: fmt.Sprintf(`{"recipient_did":%q,"limit":10}`, recipientDid)})
+	assert.Nil(t, err)
+	assert.Len(t, *response.Attachments, 2)
+	// messages without a cloud event have no status
+	assert.Equal(t, map[string]string{"e1": messaging.DELIVERY_STATUS_DELIVERED, "e2": messaging.DELIVERY_STATUS_EXPIRED}, deliveryStatuses(t, med.Outbox))
+	// a pickup of the same messages before they are acknowledged is not reported again
+	response, err = pickup.handleDeliveryRequest(context.Background(), didcomm.Message{From: &remoteDid, Body: fmt.Sprintf(`{"recipient_did":%q,"limit":10}`, recipientDid)})
+	assert.Nil(t, err)
+	assert.Len(t, *response.Attachments, 2)
+	assert.Empty(t, deliveryStatuses(t, med.Outbox))
+
+	ids := []string{}
+	for _, delivered := range *response.Attachments {
+		ids = append(ids, *delivered.Id)
+	}
+	idList, _ := json.Marshal(ids)
+	_, err = pickup.handleMessagesReceived(context.Background(), didcomm.Message{From: &remoteDid, Body: fmt.Sprintf(`{"message_id_list":%s}`, idList)})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"e1": messaging.DELIVERY_STATUS_ACKNOWLEDGED}, deliveryStatuses(t, med.Outbox))
+
+	// forwarded cloud events report their failure or expiry
+	routing := NewRouting(med)
+	unknown := cloudevents.NewEvent()
+	unknown.SetID("e3")
+	unknown.DataEncoded = []byte(`{"did":"did:peer:2.unknown","payload":{}}`)
+	assert.Nil(t, forwardCloudEvent(routing, unknown, true))
+	expired := cloudevents.NewEvent()
+	expired.SetID("e4")
+	expired.DataEncoded = []byte(fmt.Sprintf(`{"did":%q,"payload":{},"expiresTime":1}`, recipientDid))
+	// an expired event is not delivered again
+	assert.ErrorIs(t, forwardCloudEvent(routing, expired, false), cloudevent.ErrInvalidEvent)
+	assert.Equal(t, map[string]string{"e3": messaging.DELIVERY_STATUS_FAILED, "e4": messaging.DELIVERY_STATUS_EXPIRED}, deliveryStatuses(t, med.Outbox))
+}
+
+// unavailableDatabase fails to check mediatees while it is down
+type unavailableDatabase struct {
+	database.Adapter
+	down bool
+}
+
+func (d *unavailableDatabase) IsMediated(did string) (bool, error) {
+	if d.down {
+		return false, errors.New("database unavailable")
+	}
+	return d.Adapter.IsMediated(did)
+}
+
+func TestDeliveryStatus_Redelivery(t *testing.T) {
+	config.Logger = slog.Default()
+	config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic = "delivery-status"
+	defer func() { config.CurrentConfiguration.CloudForwarding.DeliveryStatus.Topic = "" }()
+	db := database.NewDemo()
+	unavailable := &unavailableDatabase{Adapter: db, down: true}
+	med := &mediator.Mediator{Database: unavailable, Outbox: outbox.NewOutbox(db)}
+	remoteDid := "did:peer:2.remote"
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: remoteDid}))
+	routing := NewRouting(med)
+
+	event := cloudevents.NewEvent()
+	event.SetID("e5")
+	event.DataEncoded = []byte(fmt.Sprintf(`{"did":%q,"payload":{}}`, remoteDid))
+	// failed deliveries which are delivered again are not reported
+	assert.NotNil(t, forwardCloudEvent(routing, event, false))
+	assert.NotNil(t, forwardCloudEvent(routing, event, false))
+	assert.Empty(t, deliveryStatuses(t, med.Outbox))
+	unavailable.down = false
+	assert.Nil(t, forwardCloudEvent(routing, event, false))
+	assert.Equal(t, map[string]string{"e5": messaging.DELIVERY_STATUS_QUEUED}, deliveryStatuses(t, med.Outbox))
+
+	// the last delivery reports the failure
+	event.SetID("e6")
+	unavailable.down = true
+	assert.NotNil(t, forwardCloudEvent(routing, event, true))
+	assert.Equal(t, map[string]string{"e6": messaging.DELIVERY_STATUS_FAILED}, deliveryStatuses(t, med.Outbox))
+}
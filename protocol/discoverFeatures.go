@@ -0,0 +1,125 @@
+package protocol
+
+// https://identity.foundation/didcomm-messaging/spec/#discover-features-protocol-20
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+
+	"github.com/google/uuid"
+)
+
+const PIURI_DISCOVER_FEATURES = "https://didcomm.org/discover-features/2.0/"
+const PIURI_DISCOVER_FEATURES_QUERIES = "https://didcomm.org/discover-features/2.0/queries"
+const PIURI_DISCOVER_FEATURES_DISCLOSE = "https://didcomm.org/discover-features/2.0/disclose"
+
+const (
+	FEATURE_TYPE_PROTOCOL = "protocol"
+	FEATURE_TYPE_LIMIT    = "limit"
+)
+
+type Query struct {
+	FeatureType string `json:"feature-type"`
+	Match       string `json:"match"`
+}
+
+type Disclosure struct {
+	FeatureType string   `json:"feature-type"`
+	Id          string   `json:"id"`
+	Roles       []string `json:"roles,omitempty"`
+	Value       any      `json:"value,omitempty"`
+}
+
+type DiscoverFeatures struct {
+	mediator *mediator.Mediator
+}
+
+func NewDiscoverFeatures(mediator *mediator.Mediator) *DiscoverFeatures {
+	return &DiscoverFeatures{
+		mediator: mediator,
+	}
+}
+
+func (df *DiscoverFeatures) Handle(message didcomm.Message) (response didcomm.Message, err error) {
+	switch message.Type {
+	case PIURI_DISCOVER_FEATURES_QUERIES:
+		response, err = df.handleQueries(message)
+	default:
+		err = intErr.ErrUnknownMessageType
+		response = PR_UNKNOWN_MESSAGE_TYPE
+	}
+	return
+}
+
+func (df *DiscoverFeatures) handleQueries(message didcomm.Message) (response didcomm.Message, err error) {
+	type requestBody struct {
+		Queries []Query `json:"queries"`
+	}
+	body, err := extractBody[requestBody](message)
+	if err != nil {
+		return PR_INVALID_REQUEST, err
+	}
+
+	type responseBody struct {
+		Disclosures []Disclosure `json:"disclosures"`
+	}
+	disclosures := disclose(body.Queries, features())
+	responseBodyJson, err := json.Marshal(responseBody{Disclosures: disclosures})
+	if err != nil {
+		return PR_INTERNAL_SERVER_ERROR, err
+	}
+
+	response = didcomm.Message{
+		Id:   uuid.NewString(),
+		Type: PIURI_DISCOVER_FEATURES_DISCLOSE,
+		From: &df.mediator.Did,
+		Thid: &message.Id,
+		Body: string(responseBodyJson),
+	}
+	return response, nil
+}
+
+// features lists the supported protocols and the configured message limits
+func features() []Disclosure {
+	limits := config.CurrentConfiguration.DidComm.Limits
+	return []Disclosure{
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/coordinate-mediation/3.0", Roles: []string{"mediator"}},
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/routing/2.0", Roles: []string{"mediator"}},
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/messagepickup/3.0", Roles: []string{"mediator"}},
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/trust-ping/2.0", Roles: []string{"receiver"}},
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/discover-features/2.0", Roles: []string{"responder"}},
+		{FeatureType: FEATURE_TYPE_PROTOCOL, Id: "https://didcomm.org/out-of-band/2.0", Roles: []string{"sender"}},
+		{FeatureType: FEATURE_TYPE_LIMIT, Id: "maxEnvelopeSize", Value: limits.MaxEnvelopeSize},
+		{FeatureType: FEATURE_TYPE_LIMIT, Id: "maxAttachments", Value: limits.MaxAttachments},
+		{FeatureType: FEATURE_TYPE_LIMIT, Id: "maxAttachmentSize", Value: limits.MaxAttachmentSize},
+	}
+}
+
+func disclose(queries []Query, features []Disclosure) []Disclosure {
+	disclosures := []Disclosure{}
+	for _, feature := range features {
+		for _, query := range queries {
+			if query.FeatureType == feature.FeatureType && matchFeature(query.Match, feature.Id) {
+				disclosures = append(disclosures, feature)
+				break
+			}
+		}
+	}
+	return disclosures
+}
+
+// matchFeature matches an id against a pattern where * is a wildcard for any sequence of characters
+func matchFeature(pattern string, id string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, err := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", id)
+	return err == nil && matched
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchFeature(t *testing.T) {
+	assert.True(t, matchFeature("https://didcomm.org/trust-ping/2.0", "https://didcomm.org/trust-ping/2.0"))
+	assert.True(t, matchFeature("https://didcomm.org/*", "https://didcomm.org/routing/2.0"))
+	assert.True(t, matchFeature("https://didcomm.org/messagepickup/3.*", "https://didcomm.org/messagepickup/3.0"))
+	assert.False(t, matchFeature("https://didcomm.org/messagepickup/2.*", "https://didcomm.org/messagepickup/3.0"))
+	assert.False(t, matchFeature("https://didcomm.org/routing/2.0", "https://didcomm.org/routing/2.0.1"))
+}
+
+func TestDisclose_FiltersByFeatureType(t *testing.T) {
+	config.CurrentConfiguration.DidComm.Limits.MaxEnvelopeSize = 1024
+
+	disclosures := disclose([]Query{{FeatureType: FEATURE_TYPE_LIMIT, Match: "maxEnvelope*"}}, features())
+
+	assert.Equal(t, []Disclosure{{FeatureType: FEATURE_TYPE_LIMIT, Id: "maxEnvelopeSize", Value: int64(1024)}}, disclosures)
+}
+
+func TestCheckAttachmentLimits(t *testing.T) {
+	config.CurrentConfiguration.DidComm.Limits.MaxAttachments = 1
+	config.CurrentConfiguration.DidComm.Limits.MaxAttachmentSize = 4
+
+	small := didcomm.Attachment{Data: didcomm.AttachmentDataBase64{Value: didcomm.Base64AttachmentData{Base64: "dGVzdA=="}}}
+	large := didcomm.Attachment{Data: didcomm.AttachmentDataJson{Value: didcomm.JsonAttachmentData{Json: `{"a":"b"}`}}}
+
+	_, err := checkAttachmentLimits(&[]didcomm.Attachment{small})
+	assert.Nil(t, err)
+
+	_, err = checkAttachmentLimits(&[]didcomm.Attachment{large})
+	assert.ErrorIs(t, err, intErr.ErrAttachmentTooLarge)
+
+	_, err = checkAttachmentLimits(&[]didcomm.Attachment{small, small})
+	assert.ErrorIs(t, err, intErr.ErrTooManyAttachments)
+}
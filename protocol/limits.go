@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"math"
+	"strings"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+)
+
+func checkEnvelopeSize(size int64) error {
+	limit := config.CurrentConfiguration.DidComm.Limits.MaxEnvelopeSize
+	if limit > 0 && size > limit {
+		return intErr.ErrMessageTooLarge
+	}
+	return nil
+}
+
+// checkAttachmentLimits validates count and size of the attachments without decoding them
+func checkAttachmentLimits(attachments *[]didcomm.Attachment) (ProblemReport, error) {
+	if attachments == nil {
+		return didcomm.Message{}, nil
+	}
+	limits := config.CurrentConfiguration.DidComm.Limits
+	if limits.MaxAttachments > 0 && len(*attachments) > limits.MaxAttachments {
+		return PR_TOO_MANY_ATTACHMENTS, intErr.ErrTooManyAttachments
+	}
+	if limits.MaxAttachmentSize > 0 {
+		for _, attachment := range *attachments {
+			if attachmentSize(attachment) > limits.MaxAttachmentSize {
+				return PR_ATTACHMENT_TOO_LARGE, intErr.ErrAttachmentTooLarge
+			}
+		}
+	}
+	return didcomm.Message{}, nil
+}
+
+// attachmentSize returns the decoded size of inline data or the declared byte count of linked data
+func attachmentSize(attachment didcomm.Attachment) int64 {
+	var size int64
+	switch data := attachment.Data.(type) {
+	case didcomm.AttachmentDataBase64:
+		size = int64(base64.RawStdEncoding.DecodedLen(len(strings.TrimRight(data.Value.Base64, "="))))
+	case didcomm.AttachmentDataJson:
+		size = int64(len(data.Value.Json))
+	}
+	if attachment.ByteCount != nil && *attachment.ByteCount > uint64(size) {
+		if *attachment.ByteCount > math.MaxInt64 {
+			return math.MaxInt64
+		}
+		size = int64(*attachment.ByteCount)
+	}
+	return size
+}
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -8,30 +9,47 @@ import (
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/constants"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string) (packMsg string, err error) {
+// HandleMessage unpacks the message and handles it with its protocol, the returned message is the packed response
+func HandleMessage(ctx context.Context, bodyString string, mediator *mediator.Mediator, bearer string) (packMsg string, err error) {
+	ctx, span := tracing.Start(ctx, "HandleMessage")
+	defer func() { tracing.End(span, err) }()
 
 	messageExpired := false
 	messageWrongCreationTime := false
 
+	// check envelope size before unpacking
+	if err = checkEnvelopeSize(int64(len(bodyString))); err != nil {
+		config.Logger.Warn("Received message exceeds envelope size limit", "size", len(bodyString))
+		pr, err := PackProblemReport(PR_MESSAGE_TOO_LARGE, mediator)
+		if err != nil {
+			return "", err
+		}
+		return pr, intErr.ErrMessageTooLarge
+	}
+
 	// unpack message
-	msg, err := mediator.UnpackMessage(bodyString)
+	unpackCtx, unpackSpan := tracing.Start(ctx, "UnpackMessage")
+	// the didcomm library resolves the DIDs of the envelope without context, the resolutions are linked to the span
+	resolved := mediator.AwaitResolutions(unpackCtx, bodyString)
+	msg, metadata, err := mediator.UnpackMessageWithMetadata(bodyString)
+	resolved()
+	tracing.End(unpackSpan, err)
 	if err != nil {
 		config.Logger.Error("Error unpacking message", "err", err)
-		internal_error := PR_MESSAGE_NOT_UNPACKABLE
-		internal_error.To = &[]string{""}
-		internal_error.From = &mediator.Did
-		timeNow := uint64(time.Now().UTC().Unix())
-		internal_error.CreatedTime = &timeNow
-		pr, err := mediator.PackPlainMessage(internal_error)
+		pr, err := PackProblemReport(PR_MESSAGE_NOT_UNPACKABLE, mediator)
 		if err != nil {
 			return "", err
 		}
 		return pr, intErr.ErrUnpackingMessage
 	}
+	countInboundMessage(msg.Type)
+	span.SetAttributes(attribute.String("didcomm.message.id", msg.Id), attribute.String("didcomm.message.type", msg.Type))
 
 	// check optional field created_time and expiration_time
 	if msg.CreatedTime != nil {
@@ -50,14 +68,53 @@ func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string
 		}
 	}
 
-	// check if did is blocked
-	isBlocked, err := mediator.Database.IsBlocked(*msg.From)
+	// the rotation is only verified here, the mediation is moved after the message passed all checks
+	fromPrior, rotationPr, rotationErr := checkRotation(ctx, msg, metadata, mediator)
+	if rotationErr != nil {
+		config.Logger.Warn("Received invalid DID rotation", "id", msg.Id, "err", rotationErr)
+	}
+
+	// check if did is blocked, a rotation does not escape the block of the prior DID
+	var isBlocked bool
+	err = tracing.Database(ctx, "IsBlocked", func() (err error) {
+		isBlocked, err = mediator.Database.IsBlocked(*msg.From)
+		if err == nil && !isBlocked && fromPrior != nil {
+			isBlocked, err = mediator.Database.IsBlocked(fromPrior.Iss)
+		}
+		return err
+	})
 	if err != nil {
 		errMsg := "unable to check if DID is blocked"
 		config.Logger.Error(errMsg, "err", err)
 		return "", errors.New(errMsg)
 	}
 
+	// check message age and if the message was already received
+	replayPr, replayErr := checkReplay(ctx, msg, mediator)
+	if replayErr != nil {
+		if !errors.Is(replayErr, intErr.ErrMessageTooOld) && !errors.Is(replayErr, intErr.ErrDuplicateMessage) {
+			errMsg := "unable to check message for replay"
+			config.Logger.Error(errMsg, "err", replayErr)
+			return "", errors.New(errMsg)
+		}
+		config.Logger.Warn("Received replayed or outdated message", "id", msg.Id, "err", replayErr)
+	}
+	// the message id is only kept if the message is handled, a failed message can be retried by the sender
+	unhandled := false
+	if replayErr == nil {
+		defer func() {
+			if err != nil || unhandled {
+				releaseReplay(ctx, msg, mediator)
+			}
+		}()
+	}
+
+	// check attachment count and size
+	attachmentPr, attachmentErr := checkAttachmentLimits(msg.Attachments)
+	if attachmentErr != nil {
+		config.Logger.Warn("Received message exceeds attachment limits", "err", attachmentErr)
+	}
+
 	var responseMsg didcomm.Message = didcomm.Message{}
 
 	if isBlocked {
@@ -67,6 +124,20 @@ func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string
 		responseMsg = PR_EXPIRED_MESSAGE
 	} else if messageWrongCreationTime {
 		responseMsg = PR_MESSAGE_WRONG_CREATION_TIME
+	} else if rotationErr != nil {
+		responseMsg = rotationPr
+	} else if replayErr != nil {
+		responseMsg = replayPr
+	} else if attachmentErr != nil {
+		responseMsg = attachmentPr
+	} else if rotationPr, rotationErr = rotateMediatee(ctx, fromPrior, mediator); rotationErr != nil {
+		if !errors.Is(rotationErr, intErr.ErrInvalidRotation) {
+			errMsg := "unable to rotate DID"
+			config.Logger.Error(errMsg, "err", rotationErr)
+			return "", errors.New(errMsg)
+		}
+		config.Logger.Warn("Received invalid DID rotation", "id", msg.Id, "err", rotationErr)
+		responseMsg = rotationPr
 	} else if strings.HasPrefix(msg.Type, constants.PIURI_COORDINATE_MEDIATION) {
 
 		coordinateMediation := NewCoordinateMediation(mediator)
@@ -91,7 +162,7 @@ func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string
 		}
 	} else if strings.HasPrefix(msg.Type, PIURI_ROUTING) {
 		routing := NewRouting(mediator)
-		responseMsg, err = routing.Handle(msg)
+		responseMsg, err = routing.Handle(ctx, msg)
 		if err != nil {
 			errMsg := "unable to handle routing"
 			config.Logger.Error(errMsg, "err", err)
@@ -103,23 +174,31 @@ func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string
 
 	} else if strings.HasPrefix(msg.Type, PIURI_MESSAGEPICKUP) {
 		messagePickup := NewMessagePickup(mediator)
-		responseMsg, err = messagePickup.Handle(msg)
+		responseMsg, err = messagePickup.Handle(ctx, msg)
 		if err != nil {
 			errMsg := "unable to handle message pickup"
 			config.Logger.Error(errMsg, "err", err)
 			return "", errors.New(errMsg)
 		}
 
+	} else if strings.HasPrefix(msg.Type, PIURI_DISCOVER_FEATURES) {
+		discoverFeatures := NewDiscoverFeatures(mediator)
+		responseMsg, err = discoverFeatures.Handle(msg)
+		if err != nil && !errors.Is(err, intErr.ErrUnknownMessageType) {
+			config.Logger.Warn("unable to handle discover features", "err", err)
+		}
+
 	} else {
 		config.Logger.Warn("Message type not handled yet.")
 		responseMsg = PR_UNKNOWN_MESSAGE_TYPE
 	}
 
 	// pack response
-	packMsg, err = packMessage(mediator.Did, *msg.From, responseMsg, mediator)
+	packMsg, err = packMessage(ctx, mediator.Did, *msg.From, responseMsg, mediator)
 	if err != nil {
+		unhandled = true
 		internal_error := PR_INTERNAL_SERVER_ERROR
-		pr, err := packMessage(mediator.Did, *msg.From, internal_error, mediator)
+		pr, err := packMessage(ctx, mediator.Did, *msg.From, internal_error, mediator)
 		if err != nil {
 			return "", err
 		}
@@ -129,7 +208,19 @@ func HandleMessage(bodyString string, mediator *mediator.Mediator, bearer string
 	return
 }
 
-func packMessage(from string, to string, responseMsg didcomm.Message, mediator *mediator.Mediator) (packedMsg string, err error) {
+// PackProblemReport packs a problem report for a sender which is unknown, e.g. because the message could not be unpacked
+func PackProblemReport(pr ProblemReport, mediator *mediator.Mediator) (string, error) {
+	pr.To = &[]string{""}
+	pr.From = &mediator.Did
+	timeNow := uint64(time.Now().UTC().Unix())
+	pr.CreatedTime = &timeNow
+	countProblemReport(pr)
+	return mediator.PackPlainMessage(pr)
+}
+
+func packMessage(ctx context.Context, from string, to string, responseMsg didcomm.Message, mediator *mediator.Mediator) (packedMsg string, err error) {
+	defer tracing.Await(ctx, from, to)()
+	countProblemReport(responseMsg)
 	responseMsg.To = &[]string{to}
 	responseMsg.From = &from
 	timeNow := uint64(time.Now().UTC().Unix())
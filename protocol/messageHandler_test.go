@@ -1,6 +1,7 @@
 package protocol_test
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"github.com/google/uuid"
@@ -8,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/protocol"
 
 	"github.com/stretchr/testify/assert"
@@ -41,7 +45,7 @@ func TestHandleMessage(t *testing.T) {
 
 	bodyString, _ := json.Marshal(data)
 
-	packedMsg, err := protocol.HandleMessage(string(bodyString), med, "")
+	packedMsg, err := protocol.HandleMessage(context.Background(), string(bodyString), med, "")
 	assert.Nil(t, err)
 	print(packedMsg)
 
@@ -65,7 +69,7 @@ func TestHandleMessage_MessageExpired(t *testing.T) {
 		"\"attachments\": []" +
 		"}"
 
-	packedMsg, err := protocol.HandleMessage(bodyString, med, "")
+	packedMsg, err := protocol.HandleMessage(context.Background(), bodyString, med, "")
 
 	// Check if the result matches the expected outcome
 	prType := "https://didcomm.org/report-problem/2.0/problem-report"
@@ -95,7 +99,7 @@ func TestHandleMessage_MessageCreatedWrong(t *testing.T) {
 		"\"attachments\": []" +
 		"}"
 
-	packedMsg, err := protocol.HandleMessage(bodyString, med, "")
+	packedMsg, err := protocol.HandleMessage(context.Background(), bodyString, med, "")
 
 	// Check if the result matches the expected outcome
 	prType := "https://didcomm.org/report-problem/2.0/problem-report"
@@ -105,3 +109,95 @@ func TestHandleMessage_MessageCreatedWrong(t *testing.T) {
 	assert.Contains(t, packedMsg, prType)
 	assert.Contains(t, packedMsg, prComment)
 }
+
+type packFromPriorCallback struct {
+	jwtCh chan string
+	errCh chan string
+}
+
+func (c *packFromPriorCallback) Success(fromPriorJwt string, kid string) {
+	c.jwtCh <- fromPriorJwt
+}
+
+func (c *packFromPriorCallback) Error(err *didcomm.ErrorKind, msg string) {
+	c.errCh <- msg
+}
+
+// newRotation creates a mediated prior DID and a new DID and returns the from_prior which rotates between them
+func newRotation(t *testing.T) (prior string, next string, fromPrior string) {
+	prior, err := mediator.NumAlgo2([]didcomm.Service{}, med.SecretsResolver, med.DidResolver)
+	assert.Nil(t, err)
+	next, err = mediator.NumAlgo2([]didcomm.Service{}, med.SecretsResolver, med.DidResolver)
+	assert.Nil(t, err)
+	assert.Nil(t, med.Database.AddMediatee(database.Mediatee{RemoteDid: prior}))
+
+	doc, err := med.DidResolver.ResolveDid(prior)
+	assert.Nil(t, err)
+	kid := doc.Authentication[0]
+	cb := &packFromPriorCallback{jwtCh: make(chan string, 1), errCh: make(chan string, 1)}
+	go med.Messages.PackFromPrior(didcomm.FromPrior{Iss: prior, Sub: next}, &kid, cb)
+	select {
+	case fromPrior = <-cb.jwtCh:
+	case msg := <-cb.errCh:
+		t.Fatal(msg)
+	}
+	return prior, next, fromPrior
+}
+
+func signedPing(t *testing.T, id string, from string, fromPrior *string, expires *uint64) string {
+	msg := didcomm.Message{
+		Id:          id,
+		Type:        protocol.PIURI_TRUST_PING,
+		Body:        `{"response_requested":true}`,
+		From:        &from,
+		To:          &[]string{med.Did},
+		ExpiresTime: expires,
+		FromPrior:   fromPrior,
+	}
+	packed, err := med.PackSignedMessage(msg, from)
+	assert.Nil(t, err)
+	return packed
+}
+
+func assertNotRotated(t *testing.T, prior string, next string) {
+	isMediated, err := med.Database.IsMediated(prior)
+	assert.Nil(t, err)
+	assert.True(t, isMediated)
+	isMediated, err = med.Database.IsMediated(next)
+	assert.Nil(t, err)
+	assert.False(t, isMediated)
+}
+
+func TestHandleMessage_ExpiredRotation(t *testing.T) {
+	prior, next, fromPrior := newRotation(t)
+	expired := uint64(time.Now().Add(-time.Minute).Unix())
+
+	packedMsg, err := protocol.HandleMessage(context.Background(), signedPing(t, uuid.NewString(), next, &fromPrior, &expired), med, "")
+	assert.Nil(t, err)
+	assert.Contains(t, packedMsg, "Message has expired")
+	assertNotRotated(t, prior, next)
+
+	// the same rotation is done with a valid message
+	packedMsg, err = protocol.HandleMessage(context.Background(), signedPing(t, uuid.NewString(), next, &fromPrior, nil), med, "")
+	assert.Nil(t, err)
+	assert.NotContains(t, packedMsg, "problem-report")
+	isMediated, err := med.Database.IsMediated(next)
+	assert.Nil(t, err)
+	assert.True(t, isMediated)
+}
+
+func TestHandleMessage_ReplayedRotation(t *testing.T) {
+	config.CurrentConfiguration.DidComm.Replay.Retention = 60
+	defer func() { config.CurrentConfiguration.DidComm.Replay.Retention = 0 }()
+	prior, next, fromPrior := newRotation(t)
+	id := uuid.NewString()
+
+	packedMsg, err := protocol.HandleMessage(context.Background(), signedPing(t, id, next, nil, nil), med, "")
+	assert.Nil(t, err)
+	assert.NotContains(t, packedMsg, "problem-report")
+
+	packedMsg, err = protocol.HandleMessage(context.Background(), signedPing(t, id, next, &fromPrior, nil), med, "")
+	assert.Nil(t, err)
+	assert.Contains(t, packedMsg, "Message was already received")
+	assertNotRotated(t, prior, next)
+}
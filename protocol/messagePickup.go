@@ -1,14 +1,19 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
 )
 
 type MessagePickup struct {
@@ -34,7 +39,7 @@ const PIURI_MESSAGEPICKUP_DELIVERY_REQUEST = "https://didcomm.org/messagepickup/
 const PIURI_MESSAGEPICKUP_MESSAGES_RECEIVED = "https://didcomm.org/messagepickup/3.0/messages-received"
 const PIURI_MESSAGEPICKUP_LIVE_DELIVERY_CHANGE = "https://didcomm.org/messagepickup/3.0/live-delivery-change"
 
-func (mp *MessagePickup) Handle(message didcomm.Message) (response didcomm.Message, err error) {
+func (mp *MessagePickup) Handle(ctx context.Context, message didcomm.Message) (response didcomm.Message, err error) {
 
 	// Extra headers return "string" with quotation marks if it is a string
 	// and 99 if it is an int without quotation marks
@@ -50,11 +55,11 @@ func (mp *MessagePickup) Handle(message didcomm.Message) (response didcomm.Messa
 	}
 	switch message.Type {
 	case PIURI_MESSAGEPICKUP_STATUS_REQUEST:
-		response, err = mp.handleStatusRequest(message)
+		response, err = mp.handleStatusRequest(ctx, message)
 	case PIURI_MESSAGEPICKUP_DELIVERY_REQUEST:
-		response, err = mp.handleDeliveryRequest(message)
+		response, err = mp.handleDeliveryRequest(ctx, message)
 	case PIURI_MESSAGEPICKUP_MESSAGES_RECEIVED:
-		response, err = mp.handleMessagesReceived(message)
+		response, err = mp.handleMessagesReceived(ctx, message)
 	case PIURI_MESSAGEPICKUP_LIVE_DELIVERY_CHANGE:
 		response, err = mp.handleLiveDeliveryChange(message)
 	default:
@@ -64,7 +69,7 @@ func (mp *MessagePickup) Handle(message didcomm.Message) (response didcomm.Messa
 	return response, err
 }
 
-func (mp *MessagePickup) handleStatusRequest(message didcomm.Message) (response didcomm.Message, err error) {
+func (mp *MessagePickup) handleStatusRequest(ctx context.Context, message didcomm.Message) (response didcomm.Message, err error) {
 	type requestBody struct {
 		RecipientDid string `json:"recipient_did"`
 	}
@@ -78,7 +83,9 @@ func (mp *MessagePickup) handleStatusRequest(message didcomm.Message) (response
 	recipientDid := body.RecipientDid
 	remoteDid := *message.From
 
-	match, err := mp.mediator.Database.RecipientAndRemoteDidBelongTogether(recipientDid, remoteDid)
+	match, err := tracing.Query(ctx, "RecipientAndRemoteDidBelongTogether", func() (bool, error) {
+		return mp.mediator.Database.RecipientAndRemoteDidBelongTogether(recipientDid, remoteDid)
+	})
 	if err != nil {
 		return PR_INTERNAL_SERVER_ERROR, err
 	}
@@ -86,7 +93,13 @@ func (mp *MessagePickup) handleStatusRequest(message didcomm.Message) (response
 		return PR_RECIPIENT_REMOTE_DID_MISMATCH, errors.New("recipient did and remote did do not belong together")
 	}
 
-	count, err := mp.mediator.Database.GetMessagesCountForRecipient(recipientDid)
+	if err = mp.deleteExpiredMessages(ctx, recipientDid); err != nil {
+		return PR_INTERNAL_SERVER_ERROR, err
+	}
+
+	count, err := tracing.Query(ctx, "GetMessagesCountForRecipient", func() (int, error) {
+		return mp.mediator.Database.GetMessagesCountForRecipient(recipientDid)
+	})
 	if err != nil {
 		return PR_INTERNAL_SERVER_ERROR, err
 	}
@@ -110,7 +123,7 @@ func (mp *MessagePickup) handleStatusRequest(message didcomm.Message) (response
 	return response, nil
 }
 
-func (mp *MessagePickup) handleDeliveryRequest(message didcomm.Message) (response didcomm.Message, err error) {
+func (mp *MessagePickup) handleDeliveryRequest(ctx context.Context, message didcomm.Message) (response didcomm.Message, err error) {
 	type requestBody struct {
 		RecipientDid string `json:"recipient_did"`
 		Limit        int    `json:"limit"`
@@ -128,7 +141,9 @@ func (mp *MessagePickup) handleDeliveryRequest(message didcomm.Message) (respons
 
 	recipientDid := body.RecipientDid
 	remoteDid := *message.From
-	match, err := mp.mediator.Database.RecipientAndRemoteDidBelongTogether(recipientDid, remoteDid)
+	match, err := tracing.Query(ctx, "RecipientAndRemoteDidBelongTogether", func() (bool, error) {
+		return mp.mediator.Database.RecipientAndRemoteDidBelongTogether(recipientDid, remoteDid)
+	})
 	if err != nil {
 		return PR_INTERNAL_SERVER_ERROR, err
 	}
@@ -136,11 +151,37 @@ func (mp *MessagePickup) handleDeliveryRequest(message didcomm.Message) (respons
 		return PR_RECIPIENT_REMOTE_DID_MISMATCH, errors.New("recipient did and remote did do not belong together")
 	}
 
-	attachments, err := mp.mediator.Database.GetMessagesForRecipient(recipientDid, body.Limit)
+	if err = mp.deleteExpiredMessages(ctx, recipientDid); err != nil {
+		return PR_INTERNAL_SERVER_ERROR, err
+	}
+
+	attachments, err := tracing.Query(ctx, "GetMessagesForRecipient", func() ([]didcomm.Attachment, error) {
+		return mp.mediator.Database.GetMessagesForRecipient(recipientDid, body.Limit)
+	})
 	if err != nil {
 		return PR_INTERNAL_SERVER_ERROR, err
 	}
 
+	ids := []string{}
+	for _, attachment := range attachments {
+		if attachment.Id != nil {
+			ids = append(ids, *attachment.Id)
+		}
+	}
+	for id, delivered := range forwardedMessages(ctx, mp.mediator.Database, ids) {
+		// a message which is picked up again before it is acknowledged was already reported
+		isFirst, err := tracing.Query(ctx, "MarkMessageDelivered", func() (bool, error) {
+			return mp.mediator.Database.MarkMessageDelivered(id, recipientDid)
+		})
+		if err != nil {
+			config.Logger.Error("Unable to mark message as delivered", "id", id, "err", err)
+			continue
+		}
+		if isFirst {
+			sendDeliveryStatus(mp.mediator.Outbox, delivered.EventId, recipientDid, messaging.DELIVERY_STATUS_DELIVERED, nil)
+		}
+	}
+
 	rb := responseBody{
 		RecipientDid: recipientDid,
 	}
@@ -160,7 +201,7 @@ func (mp *MessagePickup) handleDeliveryRequest(message didcomm.Message) (respons
 	return response, nil
 }
 
-func (mp *MessagePickup) handleMessagesReceived(message didcomm.Message) (response didcomm.Message, err error) {
+func (mp *MessagePickup) handleMessagesReceived(ctx context.Context, message didcomm.Message) (response didcomm.Message, err error) {
 	type requestBody struct {
 		MessageIdList []string `json:"message_id_list"`
 	}
@@ -176,7 +217,9 @@ func (mp *MessagePickup) handleMessagesReceived(message didcomm.Message) (respon
 	}
 
 	for _, id := range body.MessageIdList {
-		match, err := mp.mediator.Database.RemoteDidBelongsToMessage(*message.From, id)
+		match, err := tracing.Query(ctx, "RemoteDidBelongsToMessage", func() (bool, error) {
+			return mp.mediator.Database.RemoteDidBelongsToMessage(*message.From, id)
+		})
 		if err != nil {
 			return PR_INTERNAL_SERVER_ERROR, err
 		}
@@ -185,10 +228,16 @@ func (mp *MessagePickup) handleMessagesReceived(message didcomm.Message) (respon
 			return PR_REMOTE_DID_MESSAGE_MISMATCH, errors.New("remote did does not belong to message")
 		}
 	}
-	count, err := mp.mediator.Database.DeleteMessagesByIds(body.MessageIdList)
+	received := forwardedMessages(ctx, mp.mediator.Database, body.MessageIdList)
+	count, err := tracing.Query(ctx, "DeleteMessagesByIds", func() (int, error) {
+		return mp.mediator.Database.DeleteMessagesByIds(body.MessageIdList)
+	})
 	if err != nil {
 		return PR_INTERNAL_SERVER_ERROR, err
 	}
+	for _, message := range received {
+		sendDeliveryStatus(mp.mediator.Outbox, message.EventId, message.RecipientDid, messaging.DELIVERY_STATUS_ACKNOWLEDGED, nil)
+	}
 
 	rb := deletedBody{
 		DeleteCount: count,
@@ -208,6 +257,20 @@ func (mp *MessagePickup) handleMessagesReceived(message didcomm.Message) (respon
 	return response, nil
 }
 
+// deleteExpiredMessages drops the messages of the recipient which expired before they were picked up
+func (mp *MessagePickup) deleteExpiredMessages(ctx context.Context, recipientDid string) error {
+	expired, err := tracing.Query(ctx, "DeleteExpiredMessages", func() ([]database.Message, error) {
+		return mp.mediator.Database.DeleteExpiredMessages(recipientDid)
+	})
+	if err != nil {
+		return err
+	}
+	for _, message := range expired {
+		sendDeliveryStatus(mp.mediator.Outbox, message.EventId, recipientDid, messaging.DELIVERY_STATUS_EXPIRED, errors.New("message expired before it was picked up"))
+	}
+	return nil
+}
+
 func (mp *MessagePickup) handleLiveDeliveryChange(message didcomm.Message) (response didcomm.Message, err error) {
 	type responseBody struct {
 		Code    string `json:"code"`
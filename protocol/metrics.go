@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/metrics"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/constants"
+)
+
+// message types which are counted with their own labels, all others are counted as unknown
+var knownMessageTypes = map[string]string{
+	constants.PIURI_COORDINATE_MEDIATION_REQUEST: "coordinate-mediation",
+	constants.PIURI_COORDINATE_MEDIATION_UPDATE:  "coordinate-mediation",
+	constants.PIURI_COORDINATE_MEDIATION_QUERY:   "coordinate-mediation",
+	PIURI_TRUST_PING:                         "trust-ping",
+	PIURI_TRUST_PING_RESPONSE:                "trust-ping",
+	PIURI_ROUTING_FORWARD:                    "routing",
+	PIURI_MESSAGEPICKUP_STATUS_REQUEST:       "messagepickup",
+	PIURI_MESSAGEPICKUP_DELIVERY_REQUEST:     "messagepickup",
+	PIURI_MESSAGEPICKUP_MESSAGES_RECEIVED:    "messagepickup",
+	PIURI_MESSAGEPICKUP_LIVE_DELIVERY_CHANGE: "messagepickup",
+	PIURI_DISCOVER_FEATURES_QUERIES:          "discover-features",
+	PIURI_DISCOVER_FEATURES_DISCLOSE:         "discover-features",
+	PIURI_REPORT_PROBLEM:                     "report-problem",
+}
+
+func countInboundMessage(messageType string) {
+	protocol, ok := knownMessageTypes[messageType]
+	if !ok {
+		metrics.InboundMessages.WithLabelValues("unknown", "unknown").Inc()
+		return
+	}
+	metrics.InboundMessages.WithLabelValues(protocol, messageType[strings.LastIndex(messageType, "/")+1:]).Inc()
+}
+
+// countProblemReport counts the message by its code if it is a problem report
+func countProblemReport(message didcomm.Message) {
+	if message.Type != PIURI_REPORT_PROBLEM {
+		return
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal([]byte(message.Body), &body); err != nil || body.Code == "" {
+		body.Code = "unknown"
+	}
+	metrics.ProblemReports.WithLabelValues(body.Code).Inc()
+}
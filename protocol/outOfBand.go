@@ -2,8 +2,11 @@ package protocol
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
 
 	"github.com/google/uuid"
@@ -48,6 +51,13 @@ func (o *OutOfBand) Handle(label string, bearer string) (response string, err er
 		Body: string(bodyJson),
 		From: &o.mediator.Did,
 	}
+	if config.CurrentConfiguration.DidComm.SignInvitations {
+		kid, err := o.mediator.AuthenticationKid()
+		if err != nil {
+			return "", err
+		}
+		return o.mediator.PackSignedMessage(message, kid)
+	}
 	packMsg, err := o.mediator.PackPlainMessage(message)
 	if err != nil {
 		return "", err
@@ -56,3 +66,22 @@ func (o *OutOfBand) Handle(label string, bearer string) (response string, err er
 	// return packMsg64, nil
 	return packMsg, nil
 }
+
+// VerifyInvitation checks that a signed invitation was signed by a key of its sender.
+// The signature itself is verified while unpacking.
+func VerifyInvitation(message didcomm.Message, metadata didcomm.UnpackMetadata) error {
+	if message.From == nil {
+		return intErr.ErrInvitationSignerMismatch
+	}
+	if !metadata.NonRepudiation || metadata.SignFrom == nil {
+		if config.CurrentConfiguration.DidComm.RequireSignedInvitations {
+			return intErr.ErrInvitationNotSigned
+		}
+		return nil
+	}
+	signer, _, _ := strings.Cut(*metadata.SignFrom, "#")
+	if signer != *message.From {
+		return intErr.ErrInvitationSignerMismatch
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyInvitation(t *testing.T) {
+	from := "did:peer:2.Ez6LSexample"
+	message := didcomm.Message{From: &from}
+	ownKid := from + "#6MkExample"
+	otherKid := "did:peer:2.Ez6LSother#6MkOther"
+
+	config.CurrentConfiguration.DidComm.RequireSignedInvitations = false
+	assert.Nil(t, VerifyInvitation(message, didcomm.UnpackMetadata{}))
+	assert.Nil(t, VerifyInvitation(message, didcomm.UnpackMetadata{NonRepudiation: true, SignFrom: &ownKid}))
+	assert.ErrorIs(t, VerifyInvitation(message, didcomm.UnpackMetadata{NonRepudiation: true, SignFrom: &otherKid}), intErr.ErrInvitationSignerMismatch)
+
+	config.CurrentConfiguration.DidComm.RequireSignedInvitations = true
+	defer func() { config.CurrentConfiguration.DidComm.RequireSignedInvitations = false }()
+	assert.ErrorIs(t, VerifyInvitation(message, didcomm.UnpackMetadata{}), intErr.ErrInvitationNotSigned)
+	assert.Nil(t, VerifyInvitation(message, didcomm.UnpackMetadata{NonRepudiation: true, SignFrom: &ownKid}))
+}
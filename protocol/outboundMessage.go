@@ -0,0 +1,144 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrConnectionNotFound     = errors.New("connection not found")
+	ErrConnectionBlocked      = errors.New("connection is blocked")
+	ErrInvalidOutboundMessage = errors.New("invalid message")
+)
+
+// PlaintextMessage is a DIDComm plaintext message without attachments
+type PlaintextMessage struct {
+	Id          string          `json:"id,omitempty"`
+	Type        string          `json:"type"`
+	Body        json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+	Thid        string          `json:"thid,omitempty"`
+	Pthid       string          `json:"pthid,omitempty"`
+	ExpiresTime uint64          `json:"expires_time,omitempty"`
+}
+
+// OutboundMessage is sent by a cloud service to the device of a connection
+type OutboundMessage struct {
+	// recipient DID of the connection the message is packed for, the first recipient DID if empty
+	RecipientDid string `json:"recipientDid,omitempty"`
+	// full plaintext message, a new message of the type and body is created if it is not given
+	Message *PlaintextMessage `json:"message,omitempty"`
+	Type    string            `json:"type,omitempty" example:"https://didcomm.org/basicmessage/2.0/message"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+	// topic or subject for the replies of the device on the thread of the message
+	ReplyTo string `json:"replyTo,omitempty"`
+}
+
+// SendToConnection packs the message for a recipient DID of the connection and forwards it to the device.
+// The returned id is the id of the DIDComm message and of its delivery status events.
+func SendToConnection(ctx context.Context, mediator *mediator.Mediator, remoteDid string, outbound OutboundMessage) (string, error) {
+	isMediated, err := tracing.Query(ctx, "IsMediated", func() (bool, error) {
+		return mediator.Database.IsMediated(remoteDid)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !isMediated {
+		return "", ErrConnectionNotFound
+	}
+	isBlocked, err := tracing.Query(ctx, "IsBlocked", func() (bool, error) {
+		return mediator.Database.IsBlocked(remoteDid)
+	})
+	if err != nil {
+		return "", err
+	}
+	if isBlocked {
+		return "", ErrConnectionBlocked
+	}
+
+	recipientDids, err := tracing.Query(ctx, "GetRecipientDids", func() ([]string, error) {
+		return mediator.Database.GetRecipientDids(remoteDid)
+	})
+	if err != nil {
+		return "", err
+	}
+	recipientDid := outbound.RecipientDid
+	if recipientDid == "" {
+		if len(recipientDids) == 0 {
+			return "", fmt.Errorf("%w: the connection has no recipient DID", ErrInvalidOutboundMessage)
+		}
+		recipientDid = recipientDids[0]
+	} else if !slices.Contains(recipientDids, recipientDid) {
+		return "", fmt.Errorf("%w: the recipient DID does not belong to the connection", ErrInvalidOutboundMessage)
+	}
+
+	plaintext := outbound.Message
+	if plaintext == nil {
+		plaintext = &PlaintextMessage{Type: outbound.Type, Body: outbound.Body}
+	}
+	message, err := plaintext.didcommMessage()
+	if err != nil {
+		return "", err
+	}
+
+	packed, err := packMessage(ctx, mediator.Did, recipientDid, message, mediator)
+	if err != nil {
+		return "", err
+	}
+	content := messaging.ConnectorMessage{
+		Did:         recipientDid,
+		Payload:     json.RawMessage(packed),
+		Thid:        plaintext.Thid,
+		Pthid:       plaintext.Pthid,
+		ReplyTo:     outbound.ReplyTo,
+		ExpiresTime: plaintext.ExpiresTime,
+	}
+	if content.Thid == "" {
+		content.Thid = message.Id
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return message.Id, forwardConnectorMessage(ctx, NewRouting(mediator), message.Id, content, raw, true)
+}
+
+func (m PlaintextMessage) didcommMessage() (didcomm.Message, error) {
+	if m.Type == "" {
+		return didcomm.Message{}, fmt.Errorf("%w: the message has no type", ErrInvalidOutboundMessage)
+	}
+	if len(m.Body) == 0 {
+		m.Body = json.RawMessage("{}")
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(m.Body, &body); err != nil {
+		return didcomm.Message{}, fmt.Errorf("%w: the body must be a JSON object: %w", ErrInvalidOutboundMessage, err)
+	}
+	if m.Id == "" {
+		m.Id = uuid.NewString()
+	}
+	message := didcomm.Message{
+		Id:   m.Id,
+		Typ:  "application/didcomm-plain+json",
+		Type: m.Type,
+		Body: string(m.Body),
+	}
+	if m.Thid != "" {
+		message.Thid = &m.Thid
+	}
+	if m.Pthid != "" {
+		message.Pthid = &m.Pthid
+	}
+	if m.ExpiresTime != 0 {
+		message.ExpiresTime = &m.ExpiresTime
+	}
+	return message, nil
+}
@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/outbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendToConnection_Rejected(t *testing.T) {
+	config.Logger = slog.Default()
+	db := database.NewDemo()
+	med := &mediator.Mediator{Database: db, Outbox: outbox.NewOutbox(db)}
+	remoteDid := "did:peer:2.remote"
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: remoteDid, RecipientDids: []string{"did:peer:2.recipient"}}))
+	blockedDid := "did:peer:2.blocked"
+	assert.Nil(t, db.AddMediatee(database.Mediatee{RemoteDid: blockedDid, RecipientDids: []string{"did:peer:2.other"}}))
+	assert.Nil(t, db.BlockMediatee(blockedDid))
+
+	message := OutboundMessage{Type: "https://didcomm.org/basicmessage/2.0/message", Body: json.RawMessage(`{"content":"hello"}`)}
+	_, err := SendToConnection(context.Background(), med, "did:peer:2.unknown", message)
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+	_, err = SendToConnection(context.Background(), med, blockedDid, message)
+	assert.ErrorIs(t, err, ErrConnectionBlocked)
+
+	foreign := message
+	foreign.RecipientDid = "did:peer:2.other"
+	_, err = SendToConnection(context.Background(), med, remoteDid, foreign)
+	assert.ErrorIs(t, err, ErrInvalidOutboundMessage)
+	_, err = SendToConnection(context.Background(), med, remoteDid, OutboundMessage{Body: message.Body})
+	assert.ErrorIs(t, err, ErrInvalidOutboundMessage)
+	_, err = SendToConnection(context.Background(), med, remoteDid, OutboundMessage{Message: &PlaintextMessage{Type: message.Type, Body: json.RawMessage(`[1]`)}})
+	assert.ErrorIs(t, err, ErrInvalidOutboundMessage)
+}
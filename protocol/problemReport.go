@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 )
 
+const PIURI_REPORT_PROBLEM = "https://didcomm.org/report-problem/2.0/problem-report"
+
 // https://identity.foundation/didcomm-messaging/spec/#problem-codes
 type problemReportCode struct {
 	sorter     string
@@ -50,7 +52,7 @@ func NewProblemReport(sorter string, scope string, descriptor []string, comment
 
 	return didcomm.Message{
 		Id:   uuid.New().String(),
-		Type: "https://didcomm.org/report-problem/2.0/problem-report",
+		Type: PIURI_REPORT_PROBLEM,
 		Body: string(responseBodyJson),
 	}
 }
@@ -12,9 +12,16 @@ var (
 	PR_NEXT_DENIED_MESSAGE           = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_XFER}, "Forwarded message was not accepted by the next recipient")
 	PR_EXPIRED_MESSAGE               = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT_TIME}, "Message has expired")
 	PR_MESSAGE_WRONG_CREATION_TIME   = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT_TIME}, "Message creation time is in the future")
+	PR_MESSAGE_TOO_OLD               = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT_TIME}, "Message is older than the maximum message age")
+	PR_DUPLICATE_MESSAGE             = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_MESSAGE}, "Message was already received")
 	PR_ALREADY_MEDIATED              = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT}, "DID is already mediated")
 	PR_ALREADY_CONNECTED             = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT}, "DID is already connected")
 	PR_INVALID_REQUEST               = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT}, "Invalid request")
 	PR_DID_BLOCKED                   = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT}, "DID is blocked")
 	PR_PROTOCOL_NOT_SUPPORTED        = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_REQUIREMENT}, "Transportation Protocol not supported")
+	PR_MESSAGE_TOO_LARGE             = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_RESOURCE}, "Message exceeds the envelope size limit")
+	PR_TOO_MANY_ATTACHMENTS          = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_RESOURCE}, "Message exceeds the attachment count limit")
+	PR_ATTACHMENT_TOO_LARGE          = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_RESOURCE}, "Attachment exceeds the attachment size limit")
+	PR_INVITATION_NOT_TRUSTED        = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_TRUST}, "Invitation signature can not be verified")
+	PR_INVALID_ROTATION              = NewProblemReport(PR_SORTER_ERROR, PR_SCOPE_MESSAGE, []string{PR_DESCRIPTOR_TRUST}, "DID rotation can not be verified")
 )
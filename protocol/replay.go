@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	"context"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+)
+
+// checkReplay rejects messages which are too old or whose id was already received from the same sender.
+// A problem report is only returned together with ErrMessageTooOld or ErrDuplicateMessage.
+// Messages without created_time can not be checked for their age, they are only rejected as duplicates within the retention.
+func checkReplay(ctx context.Context, msg didcomm.Message, mediator *mediator.Mediator) (pr ProblemReport, err error) {
+	replay := config.CurrentConfiguration.DidComm.Replay
+	maxAge := time.Duration(replay.MaxMessageAge) * time.Second
+	retention := time.Duration(replay.Retention) * time.Second
+
+	if maxAge > 0 && msg.CreatedTime != nil {
+		if time.Unix(int64(*msg.CreatedTime), 0).Add(maxAge).Before(time.Now()) {
+			return PR_MESSAGE_TOO_OLD, intErr.ErrMessageTooOld
+		}
+	}
+
+	if retention <= 0 {
+		return didcomm.Message{}, nil
+	}
+	// ids must be remembered at least as long as messages are accepted
+	if maxAge > retention {
+		retention = maxAge
+	}
+	isNew, err := tracing.Query(ctx, "StoreMessageId", func() (bool, error) {
+		return mediator.Database.StoreMessageId(replaySender(msg), msg.Id, retention)
+	})
+	if err != nil {
+		return didcomm.Message{}, err
+	}
+	if !isNew {
+		return PR_DUPLICATE_MESSAGE, intErr.ErrDuplicateMessage
+	}
+	return didcomm.Message{}, nil
+}
+
+// releaseReplay forgets the id of a message which was stored by checkReplay but not handled, so that the sender can retry it
+func releaseReplay(ctx context.Context, msg didcomm.Message, mediator *mediator.Mediator) {
+	if config.CurrentConfiguration.DidComm.Replay.Retention <= 0 {
+		return
+	}
+	err := tracing.Database(ctx, "DeleteMessageId", func() error {
+		return mediator.Database.DeleteMessageId(replaySender(msg), msg.Id)
+	})
+	if err != nil {
+		config.Logger.Error("Unable to release message id, a retry of the message is rejected as replay", "id", msg.Id, "err", err)
+	}
+}
+
+func replaySender(msg didcomm.Message) string {
+	if msg.From != nil {
+		return *msg.From
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReplay(t *testing.T) {
+	config.CurrentConfiguration.DidComm.Replay.Retention = 60
+	config.CurrentConfiguration.DidComm.Replay.MaxMessageAge = 60
+	defer func() { config.CurrentConfiguration.DidComm.Replay.Retention = 0 }()
+	defer func() { config.CurrentConfiguration.DidComm.Replay.MaxMessageAge = 0 }()
+
+	med := &mediator.Mediator{Database: database.NewDemo()}
+	from := "did:example:sender"
+	other := "did:example:other"
+	now := uint64(time.Now().Unix())
+	old := uint64(time.Now().Add(-time.Hour).Unix())
+
+	_, err := checkReplay(context.Background(), didcomm.Message{Id: "1", From: &from, CreatedTime: &now}, med)
+	assert.Nil(t, err)
+
+	pr, err := checkReplay(context.Background(), didcomm.Message{Id: "1", From: &from, CreatedTime: &now}, med)
+	assert.ErrorIs(t, err, intErr.ErrDuplicateMessage)
+	assert.Equal(t, PR_DUPLICATE_MESSAGE, pr)
+
+	_, err = checkReplay(context.Background(), didcomm.Message{Id: "1", From: &other, CreatedTime: &now}, med)
+	assert.Nil(t, err)
+
+	pr, err = checkReplay(context.Background(), didcomm.Message{Id: "2", From: &from, CreatedTime: &old}, med)
+	assert.ErrorIs(t, err, intErr.ErrMessageTooOld)
+	assert.Equal(t, PR_MESSAGE_TOO_OLD, pr)
+}
+
+func TestReleaseReplay(t *testing.T) {
+	config.CurrentConfiguration.DidComm.Replay.Retention = 60
+	defer func() { config.CurrentConfiguration.DidComm.Replay.Retention = 0 }()
+
+	med := &mediator.Mediator{Database: database.NewDemo()}
+	from := "did:example:sender"
+	msg := didcomm.Message{Id: "1", From: &from}
+
+	_, err := checkReplay(context.Background(), msg, med)
+	assert.Nil(t, err)
+	// a message which failed can be retried
+	releaseReplay(context.Background(), msg, med)
+	_, err = checkReplay(context.Background(), msg, med)
+	assert.Nil(t, err)
+	_, err = checkReplay(context.Background(), msg, med)
+	assert.ErrorIs(t, err, intErr.ErrDuplicateMessage)
+}
@@ -0,0 +1,88 @@
+package protocol
+
+// https://identity.foundation/didcomm-messaging/spec/#did-rotation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+)
+
+// checkRotation verifies the from_prior of the message, it returns the claims of a valid rotation and nil
+// if the message contains no from_prior. A problem report is only returned together with ErrInvalidRotation.
+// The mediation is not moved until rotateMediatee is called with the claims.
+func checkRotation(ctx context.Context, msg didcomm.Message, metadata didcomm.UnpackMetadata, mediator *mediator.Mediator) (fromPrior *didcomm.FromPrior, pr ProblemReport, err error) {
+	if msg.FromPrior == nil {
+		return nil, didcomm.Message{}, nil
+	}
+	// the signature of the prior DID is checked while unpacking the JWT
+	resolved := mediator.AwaitResolutions(ctx, *msg.FromPrior)
+	claims, kid, err := mediator.UnpackFromPrior(*msg.FromPrior)
+	resolved()
+	if err != nil {
+		return nil, PR_INVALID_ROTATION, fmt.Errorf("%w: %s", intErr.ErrInvalidRotation, err.Error())
+	}
+	if err = validateRotation(msg, metadata, claims, kid, time.Now()); err != nil {
+		return nil, PR_INVALID_ROTATION, err
+	}
+	return &claims, didcomm.Message{}, nil
+}
+
+// rotateMediatee moves the mediation of the prior DID to the sender of a message which passed all checks,
+// it does nothing without from_prior. A problem report is only returned together with ErrInvalidRotation.
+func rotateMediatee(ctx context.Context, fromPrior *didcomm.FromPrior, mediator *mediator.Mediator) (pr ProblemReport, err error) {
+	if fromPrior == nil {
+		return didcomm.Message{}, nil
+	}
+	// wallets send from_prior until they receive a message for the new DID, so the rotation may be done already
+	isMediated, err := tracing.Query(ctx, "IsMediated", func() (bool, error) {
+		return mediator.Database.IsMediated(fromPrior.Iss)
+	})
+	if err != nil || !isMediated {
+		return didcomm.Message{}, err
+	}
+	isNewMediated, err := tracing.Query(ctx, "IsMediated", func() (bool, error) {
+		return mediator.Database.IsMediated(fromPrior.Sub)
+	})
+	if err != nil {
+		return didcomm.Message{}, err
+	}
+	if isNewMediated {
+		return PR_INVALID_ROTATION, fmt.Errorf("%w: %s is already mediated", intErr.ErrInvalidRotation, fromPrior.Sub)
+	}
+	err = tracing.Database(ctx, "RotateMediatee", func() error {
+		return mediator.Database.RotateMediatee(fromPrior.Iss, fromPrior.Sub)
+	})
+	if err != nil {
+		return didcomm.Message{}, err
+	}
+	config.Logger.Info("Rotated mediatee DID", "prior", fromPrior.Iss, "did", fromPrior.Sub)
+	return didcomm.Message{}, nil
+}
+
+// validateRotation checks that the verified from_prior rotates to the authenticated sender of the message
+func validateRotation(msg didcomm.Message, metadata didcomm.UnpackMetadata, fromPrior didcomm.FromPrior, kid string, now time.Time) error {
+	if msg.From == nil || fromPrior.Sub != *msg.From {
+		return fmt.Errorf("%w: subject is not the sender", intErr.ErrInvalidRotation)
+	}
+	if !metadata.Authenticated && !metadata.NonRepudiation {
+		return fmt.Errorf("%w: sender is not authenticated", intErr.ErrInvalidRotation)
+	}
+	if fromPrior.Iss == "" || fromPrior.Iss == fromPrior.Sub || strings.Split(kid, "#")[0] != fromPrior.Iss {
+		return fmt.Errorf("%w: not signed by the prior DID", intErr.ErrInvalidRotation)
+	}
+	if fromPrior.Exp != nil && uint64(now.Unix()) >= *fromPrior.Exp {
+		return fmt.Errorf("%w: expired", intErr.ErrInvalidRotation)
+	}
+	if fromPrior.Nbf != nil && uint64(now.Unix()) < *fromPrior.Nbf {
+		return fmt.Errorf("%w: not yet valid", intErr.ErrInvalidRotation)
+	}
+	return nil
+}
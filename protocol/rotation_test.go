@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRotation(t *testing.T) {
+	prior := "did:peer:2.Ez6LSprior"
+	next := "did:peer:2.Ez6LSnext"
+	now := time.Now()
+	exp := uint64(now.Add(time.Hour).Unix())
+	msg := didcomm.Message{From: &next}
+	authenticated := didcomm.UnpackMetadata{Authenticated: true}
+	fromPrior := didcomm.FromPrior{Iss: prior, Sub: next, Exp: &exp}
+
+	assert.Nil(t, validateRotation(msg, authenticated, fromPrior, prior+"#key-1", now))
+
+	err := validateRotation(msg, didcomm.UnpackMetadata{}, fromPrior, prior+"#key-1", now)
+	assert.ErrorIs(t, err, intErr.ErrInvalidRotation)
+
+	err = validateRotation(msg, authenticated, fromPrior, next+"#key-1", now)
+	assert.ErrorIs(t, err, intErr.ErrInvalidRotation)
+
+	other := "did:peer:2.Ez6LSother"
+	err = validateRotation(didcomm.Message{From: &other}, authenticated, fromPrior, prior+"#key-1", now)
+	assert.ErrorIs(t, err, intErr.ErrInvalidRotation)
+
+	err = validateRotation(msg, authenticated, fromPrior, prior+"#key-1", now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, intErr.ErrInvalidRotation)
+}
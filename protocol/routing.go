@@ -2,17 +2,25 @@ package protocol
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/eclipse-xfsc/didcomm-v2-connector/didcomm"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/cloudevent"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/config"
 	intErr "github.com/eclipse-xfsc/didcomm-v2-connector/internal/errors"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/tracing"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/internal/transport"
 	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/mediator/database"
+	"github.com/eclipse-xfsc/didcomm-v2-connector/pkg/messaging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // https://identity.foundation/didcomm-messaging/spec/#routing-protocol-20
@@ -30,11 +38,11 @@ func NewRouting(mediator *mediator.Mediator) *Routing {
 	}
 }
 
-func (rt *Routing) Handle(message didcomm.Message) (response didcomm.Message, err error) {
+func (rt *Routing) Handle(ctx context.Context, message didcomm.Message) (response didcomm.Message, err error) {
 
 	switch message.Type {
 	case PIURI_ROUTING_FORWARD:
-		response, err = rt.handleForward(message, true)
+		response, err = rt.handleForward(ctx, message, true, true)
 	default:
 		err = intErr.ErrUnknownMessageType
 		response = PR_UNKNOWN_MESSAGE_TYPE
@@ -42,28 +50,76 @@ func (rt *Routing) Handle(message didcomm.Message) (response didcomm.Message, er
 	return
 }
 
-func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr ProblemReport, err error) {
-	t := uint64(time.Now().UTC().Unix())
-	if message.ExpiresTime != nil && *message.ExpiresTime < t {
-		return PR_EXPIRED_MESSAGE, errors.New("message has expired")
-	}
+// handleForward parks or forwards the attachment to the next recipient. last is unset for a cloud event which is
+// delivered again if the forward fails, its delivery status is only sent once the forward is done for good.
+func (rt *Routing) handleForward(ctx context.Context, message didcomm.Message, inbound bool, last bool) (pr ProblemReport, err error) {
 	type requestBody struct {
 		Next string `json:"next"`
 	}
 	var body requestBody
+
+	ctx, span := tracing.Start(ctx, "handleForward", trace.WithAttributes(attribute.Bool("didcomm.forward.inbound", inbound)))
+	defer func() {
+		span.SetAttributes(attribute.String("didcomm.forward.next", body.Next))
+		tracing.End(span, err)
+	}()
+
+	// the forward of a cloud event carries the id of the event, its delivery status is sent to the cloud
+	var status string
+	var reason error
+	defer func() {
+		if inbound {
+			return
+		}
+		// a failed status is final, the cloud does not learn about the failures before a redelivery
+		if err != nil && !last && !errors.Is(err, cloudevent.ErrInvalidEvent) {
+			return
+		}
+		if status == "" && err != nil {
+			status = messaging.DELIVERY_STATUS_FAILED
+		}
+		if reason == nil {
+			reason = err
+		}
+		if status != "" {
+			sendDeliveryStatus(rt.mediator.Outbox, message.Id, body.Next, status, reason)
+		}
+	}()
+
 	body, err = extractBody[requestBody](message)
 
 	if err != nil {
-		return PR_COULD_NOT_FORWARD_MESSAGE, err
+		return PR_COULD_NOT_FORWARD_MESSAGE, fmt.Errorf("%w: %w", cloudevent.ErrInvalidEvent, err)
 	}
 
-	if len(*message.Attachments) != 1 {
-		return PR_COULD_NOT_FORWARD_MESSAGE, errors.New("message must have exactly one attachment")
+	t := uint64(time.Now().UTC().Unix())
+	if message.ExpiresTime != nil && *message.ExpiresTime < t {
+		status = messaging.DELIVERY_STATUS_EXPIRED
+		return PR_EXPIRED_MESSAGE, fmt.Errorf("%w: message has expired", cloudevent.ErrInvalidEvent)
+	}
+	var eventId string
+	if !inbound {
+		eventId = message.Id
+	}
+	var expires time.Time
+	if message.ExpiresTime != nil {
+		expires = time.Unix(int64(*message.ExpiresTime), 0)
+	}
+
+	if message.Attachments == nil || len(*message.Attachments) != 1 {
+		return PR_COULD_NOT_FORWARD_MESSAGE, fmt.Errorf("%w: message must have exactly one attachment", cloudevent.ErrInvalidEvent)
+	}
+
+	if pr, err := checkAttachmentLimits(message.Attachments); err != nil {
+		config.Logger.Warn("Forward attachment exceeds limits", "err", err)
+		return pr, fmt.Errorf("%w: %w", cloudevent.ErrInvalidEvent, err)
 	}
 
 	attachment := (*message.Attachments)[0]
 
-	isMediated, err := rt.mediator.Database.IsMediated(body.Next)
+	isMediated, err := tracing.Query(ctx, "IsMediated", func() (bool, error) {
+		return rt.mediator.Database.IsMediated(body.Next)
+	})
 
 	if err != nil {
 		config.Logger.Error("error checking mediatee", "err", err)
@@ -72,16 +128,21 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 
 	if isMediated {
 		config.Logger.Debug("Next is registered as mediator, park message in outbox.")
-		err = rt.mediator.Database.AddMessage(body.Next, attachment)
+		err = tracing.Database(ctx, "AddMessage", func() error {
+			return rt.mediator.Database.AddMessage(body.Next, attachment, eventId, expires)
+		})
 		if err != nil {
 			config.Logger.Error("could not add message to inbox", "err", err)
 			return PR_COULD_NOT_FORWARD_MESSAGE, err
 		}
+		status = messaging.DELIVERY_STATUS_QUEUED
 		return PR_COULD_NOT_FORWARD_MESSAGE, err
 
 	} else {
 
-		isRegistered, err := rt.mediator.Database.IsRecipientDidRegistered(body.Next)
+		isRegistered, err := tracing.Query(ctx, "IsRecipientDidRegistered", func() (bool, error) {
+			return rt.mediator.Database.IsRecipientDidRegistered(body.Next)
+		})
 		if err != nil {
 			return PR_COULD_NOT_FORWARD_MESSAGE, err
 		}
@@ -96,14 +157,16 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 				//In the case of a did here must be later on a decision if a service endpoint should be used or
 				//to use the message box. for now is it the messagebox
 
-				mediatee, err := rt.mediator.Database.GetMediateeByRecipientDid(body.Next)
+				mediatee, err := tracing.Query(ctx, "GetMediateeByRecipientDid", func() (*database.Mediatee, error) {
+					return rt.mediator.Database.GetMediateeByRecipientDid(body.Next)
+				})
 				if err != nil {
 					config.Logger.Error("error getting mediatee", "err", err)
 					return PR_COULD_NOT_FORWARD_MESSAGE, err
 				}
 
 				config.Logger.Debug("Message is outgoing, resolve remote did")
-				didDoc, err := rt.mediator.DidResolver.ResolveDid(mediatee.RemoteDid)
+				didDoc, err := mediator.ResolveDidContext(ctx, rt.mediator.DidResolver, mediatee.RemoteDid)
 
 				if err != nil {
 					return PR_COULD_NOT_FORWARD_MESSAGE, err
@@ -114,11 +177,14 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 				*/
 				if len(didDoc.Service) == 0 {
 					config.Logger.Debug("No direct forwarding possible (no service found in remote did), park message in outbox")
-					err = rt.mediator.Database.AddMessage(body.Next, attachment)
+					err = tracing.Database(ctx, "AddMessage", func() error {
+						return rt.mediator.Database.AddMessage(body.Next, attachment, eventId, expires)
+					})
 					if err != nil {
 						config.Logger.Error("could not add message to inbox", "err", err)
 						return PR_COULD_NOT_FORWARD_MESSAGE, err
 					}
+					status = messaging.DELIVERY_STATUS_QUEUED
 				} else {
 					/*
 						if service endpoint exists which is didcomm compatible, forward message as it is.
@@ -132,7 +198,11 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 							message.From = &rt.mediator.Did
 							message.To = &[]string{mediatee.RemoteDid}
 
-							return rt.ForwardMessage(message, val.Value.Uri)
+							pr, err = rt.ForwardMessage(ctx, message, val.Value.Uri)
+							if err == nil {
+								status = messaging.DELIVERY_STATUS_DELIVERED
+							}
+							return pr, err
 						}
 					}
 
@@ -151,14 +221,14 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 
 				*/
 				config.Logger.Debug("Incoming message, handle it as normal mediation and forward b64 attachment")
-				messageB64 := attachment.Data.(didcomm.AttachmentDataBase64).Value.Base64
-				messageDecoded, err := base64.StdEncoding.DecodeString(messageB64)
-				if err != nil {
-					config.Logger.Error("Error decoding message", "err", err)
-					return PR_COULD_NOT_FORWARD_MESSAGE, err
+				data, ok := attachment.Data.(didcomm.AttachmentDataBase64)
+				if !ok {
+					return PR_COULD_NOT_FORWARD_MESSAGE, errors.New("attachment must be base64 encoded")
 				}
 
-				mediatee, err := rt.mediator.Database.GetMediateeByRecipientDid(body.Next)
+				mediatee, err := tracing.Query(ctx, "GetMediateeByRecipientDid", func() (*database.Mediatee, error) {
+					return rt.mediator.Database.GetMediateeByRecipientDid(body.Next)
+				})
 				if err != nil {
 					config.Logger.Error("error getting mediatee", "err", err)
 					return PR_COULD_NOT_FORWARD_MESSAGE, err
@@ -166,34 +236,46 @@ func (rt *Routing) handleForward(message didcomm.Message, inbound bool) (pr Prob
 
 				var content map[string]interface{}
 
-				err = json.Unmarshal(messageDecoded, &content)
+				// decode the attachment while parsing it instead of buffering the decoded bytes
+				decoder := json.NewDecoder(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data.Value.Base64)))
+				err = decoder.Decode(&content)
+
+				if err != nil {
+					config.Logger.Error("decoding message failed", "err", err)
+					return PR_COULD_NOT_FORWARD_MESSAGE, err
+				}
 
+				thread, err := replyThread(ctx, rt.mediator.Database, body.Next, content, message)
 				if err != nil {
-					config.Logger.Error("marshalling body failed", "err", err)
+					config.Logger.Error("unable to read thread", "err", err)
 					return PR_COULD_NOT_FORWARD_MESSAGE, err
 				}
 
-				err = SendMessage(content, mediatee)
+				err = SendMessage(ctx, rt.mediator.Outbox, content, mediatee, body.Next, thread)
 				if err != nil {
 					config.Logger.Error("unable to send message to cloud", "err", err)
 					return PR_COULD_NOT_FORWARD_MESSAGE, err
 				}
 			}
+		} else if !inbound {
+			// the cloud learns about messages for unknown recipients, they are dropped
+			status = messaging.DELIVERY_STATUS_FAILED
+			reason = errors.New("recipient did is not registered")
 		}
 	}
 	return didcomm.Message{}, err
 }
 
-func (rt *Routing) ForwardMessage(message didcomm.Message, endpoint string) (pr ProblemReport, err error) {
+func (rt *Routing) ForwardMessage(ctx context.Context, message didcomm.Message, endpoint string) (pr ProblemReport, err error) {
 	to := *message.To
-	packMsg, err := packMessage(*message.From, to[0], message, rt.mediator)
+	packMsg, err := packMessage(ctx, *message.From, to[0], message, rt.mediator)
 	if err != nil {
 		config.Logger.Error("Problem Report", "err", err)
 		return didcomm.Message{}, err
 	}
 	r := strings.NewReader(packMsg)
 
-	resp, err := http.Post(endpoint, "application/didcomm-plain+json", r)
+	resp, err := transport.Client().Post(endpoint, "application/didcomm-plain+json", r)
 	if err != nil {
 		return PR_COULD_NOT_FORWARD_MESSAGE, err
 	}
@@ -211,7 +293,7 @@ func (rt *Routing) ForwardAttachmentMessage(message didcomm.Attachment, recipien
 		return PR_COULD_NOT_FORWARD_MESSAGE, err
 	}
 	r := bytes.NewReader(body)
-	resp, err := http.Post(endpoint, "application/didcomm-plain+json", r)
+	resp, err := transport.Client().Post(endpoint, "application/didcomm-plain+json", r)
 	if err != nil {
 		return PR_COULD_NOT_FORWARD_MESSAGE, err
 	}